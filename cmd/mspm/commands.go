@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vatine/mspm/pkg/client"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+type command struct {
+	name string
+	args string
+	run  func(c *client.Client, args []string) error
+}
+
+var commands = []command{
+	{"info", "<package>", runInfo},
+	{"list", "[-prefix <prefix>] [pattern]", runList},
+	{"labelled", "<label>", runLabelled},
+	{"files", "[-hash] <package> [label|version]", runFiles},
+	{"cat", "<package> <label|version> <path>", runCat},
+	{"install", "<package> <label|version>", runInstall},
+	{"activate", "<package> <label|version>", runActivate},
+	{"deactivate", "<package> <label|version>", runDeactivate},
+	{"start", "<package>", runStart},
+	{"stop", "<package>", runStop},
+	{"purge", "<package> [label|version...]", runPurge},
+	{"upload", "[-label <label>]... <package> <file>...", runUpload},
+	{"upload-dir", "[-ignore <pattern>]... [-label <label>]... [-dry-run] <package> <directory>", runUploadDir},
+	{"label", "[-force] [-expect <label>=<version>]... <package> <label|version> <label>...", runLabel},
+	{"promote", "[-force] <label> <package>=<label|version>...", runPromote},
+	{"history", "<package> [label]", runHistory},
+	{"rollback", "[-force] <package> <label>", runRollback},
+	{"delete", "[-force] <package> [version]", runDelete},
+	{"retention", "[package]", runRetention},
+	{"audit", "[-from <time>] [-to <time>] [-limit <n>] [package]", runAudit},
+	{"options", "-latest=<true|false> <package>", runOptions},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Print package information, one version per line.
+func printPackageInformation(info *pb.PackageInformation) {
+	labels := append([]string{}, info.GetLabel()...)
+	sort.Strings(labels)
+	fmt.Printf("%s %s %s\n", info.GetPackageName(), info.GetVersion(), strings.Join(labels, ","))
+}
+
+func runInfo(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	infos, err := c.GetPackageInformation(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		return fmt.Errorf("no package named %s", args[0])
+	}
+	for _, info := range infos {
+		printPackageInformation(info)
+	}
+	return nil
+}
+
+func runList(c *client.Client, args []string) error {
+	var prefix string
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&prefix, "prefix", "", "Only list packages whose names start with this.")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		return errUsage
+	}
+
+	summaries, err := c.ListPackages(context.Background(), prefix, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		var labels []string
+		for label := range summary.GetLabels() {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		fmt.Printf("%s %d %s\n", summary.GetPackageName(), summary.GetVersions(), strings.Join(labels, ","))
+	}
+	return nil
+}
+
+func runLabelled(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	summaries, err := c.PackagesWithLabel(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		fmt.Printf("%s %s\n", summary.GetPackageName(), summary.GetLabels()[args[0]])
+	}
+	return nil
+}
+
+// Print the files in a version of a package, one per line, the way
+// ls -l would. The designator defaults to "latest".
+func runFiles(c *client.Client, args []string) error {
+	var showHash bool
+	fs := flag.NewFlagSet("files", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&showHash, "hash", false, "Show the SHA-512 of each file.")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 || fs.NArg() > 2 {
+		return errUsage
+	}
+	designator := "latest"
+	if fs.NArg() == 2 {
+		designator = fs.Arg(1)
+	}
+
+	list, err := c.ListFiles(context.Background(), fs.Arg(0), designator)
+	if err != nil {
+		return err
+	}
+	for _, f := range list.GetFiles() {
+		mode := os.FileMode(f.GetMode() & 0777)
+		if f.GetType() == "dir" {
+			mode |= os.ModeDir
+		}
+		group := f.GetGroup()
+		if group == "" {
+			group = "-"
+		}
+		line := fmt.Sprintf("%s %s %s %d", mode, f.GetOwner(), group, f.GetSize())
+		if showHash {
+			hash := f.GetHash()
+			if hash == "" {
+				hash = "-"
+			}
+			line = line + " " + hash
+		}
+		fmt.Printf("%s %s\n", line, f.GetName())
+	}
+	return nil
+}
+
+func runCat(c *client.Client, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	return c.Cat(context.Background(), args[0], args[1], args[2], os.Stdout)
+}
+
+func runInstall(c *client.Client, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return c.Install(args[0], args[1])
+}
+
+func runActivate(c *client.Client, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return c.Activate(args[0], args[1])
+}
+
+func runDeactivate(c *client.Client, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	return c.Deactivate(args[0], args[1])
+}
+
+func runStart(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	return c.Start(args[0])
+}
+
+func runStop(c *client.Client, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	return c.Stop(args[0])
+}
+
+func runPurge(c *client.Client, args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	return c.Purge(args[0], args[1:]...)
+}
+
+// A flag that can be given multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runUpload(c *client.Client, args []string) error {
+	var labels stringList
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(&labels, "label", "Label to set on the uploaded version (may be repeated).")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 2 {
+		return errUsage
+	}
+
+	files, err := client.UploadFilesFromPaths(".", fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	info, err := c.Upload(context.Background(), fs.Arg(0), files, labels...)
+	if err != nil {
+		return err
+	}
+	printPackageInformation(info)
+	return nil
+}
+
+func runUploadDir(c *client.Client, args []string) error {
+	var labels, ignore stringList
+	var dryRun bool
+	fs := flag.NewFlagSet("upload-dir", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(&labels, "label", "Label to set on the uploaded version (may be repeated).")
+	fs.Var(&ignore, "ignore", "Skip files matching this pattern (may be repeated).")
+	fs.BoolVar(&dryRun, "dry-run", false, "Only list the files and the version they would get.")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return errUsage
+	}
+
+	if !dryRun {
+		info, err := c.UploadDirectory(context.Background(), fs.Arg(0), fs.Arg(1), ignore, labels...)
+		if err != nil {
+			return err
+		}
+		printPackageInformation(info)
+		return nil
+	}
+
+	files, err := client.UploadFilesFromDirectory(fs.Arg(1), ignore)
+	if err != nil {
+		return err
+	}
+	version, err := client.PredictVersion(files)
+	if err != nil {
+		return err
+	}
+	for _, uf := range files {
+		f := uf.File
+		fmt.Printf("%04o %s:%s %s\n", f.GetMode(), f.GetOwner(), f.GetGroup(), f.GetName())
+	}
+	fmt.Printf("%s %s\n", fs.Arg(0), version)
+	return nil
+}
+
+func runLabel(c *client.Client, args []string) error {
+	var force bool
+	var expect stringList
+	fs := flag.NewFlagSet("label", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&force, "force", false, "Move protected labels.")
+	fs.Var(&expect, "expect", "Only set labels if <label> is on <version> (empty for none). May be repeated.")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 3 {
+		return errUsage
+	}
+
+	var expected map[string]string
+	for _, e := range expect {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 {
+			return errUsage
+		}
+		if expected == nil {
+			expected = make(map[string]string)
+		}
+		expected[parts[0]] = parts[1]
+	}
+
+	info, err := c.CompareAndSetLabels(context.Background(), fs.Arg(0), fs.Arg(1), expected, force, fs.Args()[2:]...)
+	if err != nil {
+		return err
+	}
+	printPackageInformation(info)
+	return nil
+}
+
+func runPromote(c *client.Client, args []string) error {
+	var force bool
+	fs := flag.NewFlagSet("promote", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&force, "force", false, "Move protected labels.")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 2 {
+		return errUsage
+	}
+
+	label := fs.Arg(0)
+	var changes []*pb.LabelChange
+	for _, arg := range fs.Args()[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return errUsage
+		}
+		changes = append(changes, &pb.LabelChange{
+			PackageName: parts[0],
+			Designator:  parts[1],
+			Label:       label,
+			Force:       force,
+		})
+	}
+
+	infos, err := c.ChangeLabels(context.Background(), changes)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		printPackageInformation(info)
+	}
+	return nil
+}
+
+func runHistory(c *client.Client, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	label := ""
+	if len(args) == 2 {
+		label = args[1]
+	}
+
+	events, err := c.LabelHistory(context.Background(), args[0], label)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		old := e.GetOldVersion()
+		if old == "" {
+			old = "-"
+		}
+		identity := e.GetIdentity()
+		if identity == "" {
+			identity = "-"
+		}
+		when := time.Unix(e.GetTime(), 0).UTC().Format(time.RFC3339)
+		fmt.Printf("%s %s %s %s -> %s\n", when, identity, e.GetLabel(), old, e.GetNewVersion())
+	}
+	return nil
+}
+
+func runRollback(c *client.Client, args []string) error {
+	var force bool
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&force, "force", false, "Roll back protected labels.")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return errUsage
+	}
+
+	info, err := c.RollbackLabel(context.Background(), fs.Arg(0), fs.Arg(1), force)
+	if err != nil {
+		return err
+	}
+	printPackageInformation(info)
+	return nil
+}
+
+func runDelete(c *client.Client, args []string) error {
+	var force bool
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&force, "force", false, "Delete versions carrying protected labels.")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 || fs.NArg() > 2 {
+		return errUsage
+	}
+
+	if fs.NArg() == 2 {
+		info, err := c.DeleteVersion(context.Background(), fs.Arg(0), fs.Arg(1), force)
+		if err != nil {
+			return err
+		}
+		printPackageInformation(info)
+		return nil
+	}
+
+	infos, err := c.DeletePackage(context.Background(), fs.Arg(0), force)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		printPackageInformation(info)
+	}
+	return nil
+}
+
+func runRetention(c *client.Client, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	pkgName := ""
+	if len(args) == 1 {
+		pkgName = args[0]
+	}
+
+	expired, err := c.RetentionReport(context.Background(), pkgName)
+	if err != nil {
+		return err
+	}
+	for _, e := range expired {
+		created := time.Unix(e.GetCreated(), 0).UTC().Format(time.RFC3339)
+		fmt.Printf("%s %s %s\n", e.GetPackageName(), e.GetVersion(), created)
+	}
+	return nil
+}
+
+// Parse a time given on the command line, as RFC 3339 or as a
+// duration back from now (such as "24h").
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func runAudit(c *client.Client, args []string) error {
+	var fromArg, toArg string
+	var limit int
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVar(&fromArg, "from", "", "Start of the time range (RFC 3339, or a duration ago).")
+	fs.StringVar(&toArg, "to", "", "End of the time range (RFC 3339, or a duration ago).")
+	fs.IntVar(&limit, "limit", 0, "Only show the newest n records.")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		return errUsage
+	}
+	from, err := parseTime(fromArg)
+	if err != nil {
+		return errUsage
+	}
+	to, err := parseTime(toArg)
+	if err != nil {
+		return errUsage
+	}
+
+	entries, err := c.QueryAuditLog(context.Background(), fs.Arg(0), from, to, limit)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		when := time.Unix(e.GetTime(), 0).UTC().Format(time.RFC3339)
+		fmt.Printf("%s %s %s %s %s %s [%s] %s: %s\n", when, e.GetIdentity(), e.GetPeer(), e.GetMethod(),
+			strings.Join(e.GetPackageName(), ","), e.GetVersion(), strings.Join(e.GetLabel(), ","), e.GetSummary(), e.GetResult())
+	}
+	return nil
+}
+
+func runOptions(c *client.Client, args []string) error {
+	var latest bool
+	fs := flag.NewFlagSet("options", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&latest, "latest", true, "Move \"latest\" to new uploads.")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	opts, err := c.SetPackageOptions(context.Background(), fs.Arg(0), !latest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s latest=%v\n", opts.GetPackageName(), !opts.GetDisableLatest())
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+// The MSPM command-line client.
+//
+// Exit codes:
+//   0  success
+//   1  the operation failed
+//   2  usage error
+//   3  configuration error, or the server could not be reached
+//   4  a label was not where it was expected to be
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatine/mspm/pkg/client"
+)
+
+const (
+	exitOK          = 0
+	exitFailed      = 1
+	exitUsage       = 2
+	exitUnavailable = 3
+	exitConflict    = 4
+)
+
+const defaultConfig = "/etc/mspm/config.json"
+
+// Settings that can come from the config file. Flags given on the
+// command line take precedence.
+type config struct {
+	Backend   string `json:"backend"`
+	Directory string `json:"directory"`
+	TLS       bool   `json:"tls"`
+	CAFile    string `json:"ca_file"`
+	CertFile  string `json:"cert_file"`
+	KeyFile   string `json:"key_file"`
+	TokenFile string `json:"token_file"`
+}
+
+// Returned by commands that were called with the wrong arguments.
+var errUsage = errors.New("usage error")
+
+// Read a config file. A missing file is only an error if it was
+// explicitly asked for.
+func readConfig(path string, explicit bool) (config, error) {
+	var rv config
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return rv, nil
+	}
+	if err != nil {
+		return rv, err
+	}
+
+	err = json.Unmarshal(data, &rv)
+	return rv, err
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <command> [args]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %s %s\n", cmd.name, cmd.args)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// Map an error to an exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case status.Code(err) == codes.Unavailable:
+		return exitUnavailable
+	case status.Code(err) == codes.Aborted:
+		return exitConflict
+	}
+	return exitFailed
+}
+
+func main() {
+	var debug bool
+	var configPath string
+	var backend, directory string
+	var useTLS, insecureToken bool
+	var caFile, certFile, keyFile, tokenFile string
+
+	flag.BoolVar(&debug, "debug", false, "Enable debug logging.")
+	flag.StringVar(&configPath, "config", defaultConfig, "Path to the config file.")
+	flag.StringVar(&backend, "backend", "localhost:10240", "Host:Port of the MSPM server.")
+	flag.StringVar(&directory, "dir", "/var/mspm/packages", "Directory packages are installed in.")
+	flag.BoolVar(&useTLS, "tls", false, "Connect to the server using TLS.")
+	flag.StringVar(&caFile, "ca", "", "CA certificate(s) to verify the server with, instead of the system ones.")
+	flag.StringVar(&certFile, "cert", "", "Client certificate to present to the server (implies -tls).")
+	flag.StringVar(&keyFile, "key", "", "Private key for the client certificate.")
+	flag.StringVar(&tokenFile, "token-file", "", "File holding a bearer token to authenticate with (needs -tls).")
+	flag.BoolVar(&insecureToken, "insecure-token", false, "Send the token without TLS. For testing only.")
+	flag.Usage = usage
+
+	flag.Parse()
+	log.SetLevel(log.WarnLevel)
+	if debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	cfg, err := readConfig(configPath, explicit["config"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reading config %s: %v\n", configPath, err)
+		os.Exit(exitUnavailable)
+	}
+	if cfg.Backend != "" && !explicit["backend"] {
+		backend = cfg.Backend
+	}
+	if cfg.Directory != "" && !explicit["dir"] {
+		directory = cfg.Directory
+	}
+	if cfg.TLS && !explicit["tls"] {
+		useTLS = true
+	}
+	if cfg.CAFile != "" && !explicit["ca"] {
+		caFile = cfg.CAFile
+	}
+	if cfg.CertFile != "" && !explicit["cert"] {
+		certFile = cfg.CertFile
+	}
+	if cfg.KeyFile != "" && !explicit["key"] {
+		keyFile = cfg.KeyFile
+	}
+	if cfg.TokenFile != "" && !explicit["token-file"] {
+		tokenFile = cfg.TokenFile
+	}
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	var opts []client.Option
+	if useTLS || caFile != "" {
+		opts = append(opts, client.WithTLS(caFile))
+	}
+	if certFile != "" {
+		opts = append(opts, client.WithClientCertificate(certFile, keyFile))
+	}
+	if tokenFile != "" {
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reading token: %v\n", err)
+			os.Exit(exitUnavailable)
+		}
+		opts = append(opts, client.WithToken(strings.TrimSpace(string(token))))
+	}
+	if insecureToken {
+		opts = append(opts, client.WithInsecureToken())
+	}
+	c, err := client.New(backend, directory, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connecting to %s: %v\n", backend, err)
+		os.Exit(exitUnavailable)
+	}
+
+	err = cmd.run(c, flag.Args()[1:])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "usage: %s %s %s\n", os.Args[0], cmd.name, cmd.args)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+	}
+	c.Close()
+	os.Exit(exitCode(err))
+}
@@ -3,28 +3,187 @@ package main
 // The MSPM main entry-point
 
 import (
+	"crypto/x509"
 	"flag"
+	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/vatine/mspm/pkg/data"
 	pb "github.com/vatine/mspm/pkg/protos"
 	"github.com/vatine/mspm/pkg/server"
 )
 
+// Set up TLS. If clientCA is set, clients must present a certificate
+// signed by it.
+func tlsOption(certFile, keyFile, minVersion, clientCA string) (grpc.ServerOption, *server.CertReloader) {
+	version, err := server.TLSVersion(minVersion)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("parsing minimum TLS version")
+	}
+
+	reloader, err := server.NewCertReloader(certFile, keyFile)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"cert":  certFile,
+			"key":   keyFile,
+		}).Fatal("loading TLS certificate")
+	}
+
+	var clientCAs *x509.CertPool
+	if clientCA != "" {
+		clientCAs, err = server.LoadCertPool(clientCA)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":     err,
+				"client-ca": clientCA,
+			}).Fatal("loading client CA certificates")
+		}
+	}
+
+	return grpc.Creds(credentials.NewTLS(reloader.TLSConfig(version, clientCAs))), reloader
+}
+
+// Load the authorization policy, if there is one.
+func loadPolicy(mspmServer *server.Server, policyFile string) error {
+	if policyFile == "" {
+		return nil
+	}
+
+	policy, err := server.LoadPolicy(policyFile)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"policy": policyFile,
+		}).Error("loading policy")
+		return err
+	}
+	mspmServer.SetPolicy(policy)
+	return nil
+}
+
+// Load the retention policy, if there is one.
+func loadRetention(mspmServer *server.Server, retentionFile string) error {
+	if retentionFile == "" {
+		return nil
+	}
+
+	policy, err := data.LoadRetentionPolicy(retentionFile)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"retention": retentionFile,
+		}).Error("loading retention policy")
+		return err
+	}
+	mspmServer.SetRetentionPolicy(policy)
+	return nil
+}
+
+// Create the blob store package tarballs are kept in. S3 credentials
+// come from the environment, so they do not show up in process
+// listings.
+func blobStore(kind, store string, s3Config data.S3Config) (data.BlobStore, error) {
+	switch kind {
+	case "file":
+		return data.NewFileBlobStore(store), nil
+	case "s3":
+		s3Config.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		s3Config.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if s3Config.AccessKey == "" || s3Config.SecretKey == "" {
+			return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY need to be set")
+		}
+		return data.NewS3BlobStore(s3Config)
+	}
+	return nil, fmt.Errorf("unknown blob store %q, expected file or s3", kind)
+}
+
+// Re-read the TLS certificate, the tokens, the policy and the
+// retention policy whenever we get a SIGHUP. If any of them fails to
+// load, the old one is kept.
+func reloadOnHUP(reloader *server.CertReloader, tokens *server.TokenStore, mspmServer *server.Server, policyFile, retentionFile string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if reloader != nil && reloader.Reload() == nil {
+			log.Info("reloaded TLS certificate")
+		}
+		if tokens != nil {
+			if err := tokens.Reload(); err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+				}).Error("reloading tokens")
+			} else {
+				log.Info("reloaded tokens")
+			}
+		}
+		if policyFile != "" && loadPolicy(mspmServer, policyFile) == nil {
+			log.Info("reloaded policy")
+		}
+		if retentionFile != "" && loadRetention(mspmServer, retentionFile) == nil {
+			log.Info("reloaded retention policy")
+		}
+	}
+}
+
 func main() {
 	var debug bool
 	var ssl bool
+	var recoverStore bool
 	var playground, store string
 	var port string
+	var recoverLabel string
+	var certFile, keyFile, tlsMinVersion, clientCA string
+	var policyFile, tokenFile string
+	var retentionFile string
+	var blobKind string
+	var s3Config data.S3Config
+	var gcInterval time.Duration
+	var protectedLabels string
+	var auditPath string
+	var auditMaxSize int64
+	var auditMaxFiles int
+	var sessionMaxIdle, sessionGCInterval time.Duration
 
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging.")
-	flag.BoolVar(&ssl, "ssl", false, "Serve requests on an SSL port.")
+	flag.BoolVar(&ssl, "ssl", false, "Serve requests using TLS.")
+	flag.StringVar(&certFile, "cert", "/etc/mspm/server.crt", "Path to the TLS certificate (PEM), re-read on SIGHUP.")
+	flag.StringVar(&keyFile, "key", "/etc/mspm/server.key", "Path to the TLS private key (PEM), re-read on SIGHUP.")
+	flag.StringVar(&clientCA, "client-ca", "", "CA certificate(s) (PEM) to verify client certificates with. If set, clients must present a certificate.")
+	flag.StringVar(&policyFile, "policy", "", "Authorization policy (JSON), re-read on SIGHUP. Without one, everything is allowed.")
+	flag.StringVar(&tokenFile, "tokens", "", "Token file (JSON) for bearer-token authentication, re-read on SIGHUP. Tokens are stored as hex SHA-256 hashes (printf %s $TOKEN | sha256sum). If set, every call needs a token.")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "1.2", "Minimum TLS version to accept (1.0, 1.1, 1.2 or 1.3).")
 	flag.StringVar(&playground, "playground", "/var/mspm/tempstore", "Path to temporary storage.")
 	flag.StringVar(&store, "store", "/var/mspm/store", "Path to more permanent storage.")
+	flag.StringVar(&blobKind, "blob-store", "file", "Where to keep package tarballs: file (in the -store directory) or s3. The catalog is always kept in -store.")
+	flag.StringVar(&s3Config.Endpoint, "s3-endpoint", "https://s3.amazonaws.com", "URL of the S3-compatible object store, for -blob-store=s3. Credentials are taken from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.")
+	flag.StringVar(&s3Config.Region, "s3-region", "us-east-1", "Region of the S3 bucket.")
+	flag.StringVar(&s3Config.Bucket, "s3-bucket", "", "S3 bucket to keep package tarballs in.")
+	flag.StringVar(&s3Config.Prefix, "s3-prefix", "", "Prefix for the keys of package tarballs in the S3 bucket.")
 	flag.StringVar(&port, "listen", ":10240", "Host:Port for the gRPC communication.")
+	flag.BoolVar(&recoverStore, "recover", false, "Rebuild the catalog from the tarballs in the blob store before serving.")
+	flag.StringVar(&recoverLabel, "recover-label", "recovered", "Label to set on recovered package versions (empty for none).")
+	flag.StringVar(&protectedLabels, "protected-labels", "", "Comma-separated labels that can only be moved from one version to another by a forced SetLabels (e.g. prod,stable).")
+	flag.StringVar(&auditPath, "audit-log", "", "Path to the audit log (JSON lines). Empty for no audit log.")
+	flag.Int64Var(&auditMaxSize, "audit-max-size", 100*1024*1024, "Rotate the audit log when it grows beyond this many bytes.")
+	flag.IntVar(&auditMaxFiles, "audit-max-files", 10, "How many rotated audit logs to keep (at least 1).")
+	flag.StringVar(&retentionFile, "retention", "", "Retention policy (JSON), re-read on SIGHUP. Versions it does not keep are deleted. Without one, nothing is deleted.")
+	flag.DurationVar(&gcInterval, "gc-interval", time.Hour, "How often to delete versions the retention policy does not keep.")
+	flag.DurationVar(&sessionMaxIdle, "session-max-idle", 24*time.Hour, "How long an upload session may be idle before it is thrown away.")
+	flag.DurationVar(&sessionGCInterval, "session-gc-interval", 10*time.Minute, "How often to look for expired upload sessions.")
 
 	flag.Parse()
 	log.SetLevel(log.InfoLevel)
@@ -33,13 +192,109 @@ func main() {
 	}
 	log.Debug("Debug logging enabled.")
 
+	var opts []grpc.ServerOption
+	var reloader *server.CertReloader
+	if ssl {
+		var opt grpc.ServerOption
+		opt, reloader = tlsOption(certFile, keyFile, tlsMinVersion, clientCA)
+		opts = append(opts, opt)
+	} else if clientCA != "" {
+		log.Fatal("-client-ca requires -ssl")
+	}
+
+	// Authentication has to come before auditing, so the audit log
+	// sees who the caller is. Calls the token interceptors turn away
+	// are recorded by the token store itself.
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	var tokens *server.TokenStore
+	if tokenFile != "" {
+		var err error
+		tokens, err = server.NewTokenStore(tokenFile)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":  err,
+				"tokens": tokenFile,
+			}).Fatal("loading tokens")
+		}
+		unary = append(unary, tokens.UnaryInterceptor())
+		stream = append(stream, tokens.StreamInterceptor())
+	}
+
+	var auditLog *server.AuditLog
+	if auditPath != "" {
+		if auditMaxSize > 0 && auditMaxFiles < 1 {
+			log.Fatal("-audit-max-files must be at least 1 when -audit-max-size is set")
+		}
+		var err error
+		auditLog, err = server.NewAuditLog(auditPath, auditMaxSize, auditMaxFiles)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  auditPath,
+			}).Fatal("opening audit log")
+		}
+		unary = append(unary, auditLog.UnaryInterceptor())
+		stream = append(stream, auditLog.StreamInterceptor())
+		if tokens != nil {
+			tokens.SetAuditLog(auditLog)
+		}
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+
 	log.Debug("Creating gRPC server")
-	s := grpc.NewServer()
+	s := grpc.NewServer(opts...)
 	log.WithFields(log.Fields{
 		"store":      store,
 		"playground": playground,
 	}).Debug("Creating MSPM server")
-	mspmServer := server.NewServer(playground, store)
+	blobs, err := blobStore(blobKind, store, s3Config)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"blob-store": blobKind,
+		}).Fatal("creating blob store")
+	}
+	mspmServer, err := server.NewServerWithBlobStore(playground, store, blobs)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"store":      store,
+			"playground": playground,
+		}).Fatal("creating MSPM server")
+	}
+
+	if recoverStore {
+		log.WithFields(log.Fields{
+			"store": store,
+			"label": recoverLabel,
+		}).Info("Recovering catalog from store")
+		err = mspmServer.Recover(recoverLabel)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"store": store,
+			}).Error("recovering catalog")
+		}
+	}
+
+	if err := loadPolicy(mspmServer, policyFile); err != nil {
+		log.Fatal("no usable policy")
+	}
+	if err := loadRetention(mspmServer, retentionFile); err != nil {
+		log.Fatal("no usable retention policy")
+	}
+	go reloadOnHUP(reloader, tokens, mspmServer, policyFile, retentionFile)
+
+	mspmServer.SetAuditLog(auditLog)
+	if protectedLabels != "" {
+		mspmServer.SetProtectedLabels(strings.Split(protectedLabels, ","))
+	}
+	mspmServer.SetSessionMaxIdle(sessionMaxIdle)
+	go mspmServer.ExpireUploadSessions(sessionGCInterval)
+	if retentionFile != "" {
+		go mspmServer.CollectGarbage(gcInterval)
+	}
 
 	log.Debug("Registering MSPM server")
 	pb.RegisterMspmServer(s, mspmServer)
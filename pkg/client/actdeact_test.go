@@ -17,7 +17,7 @@ import (
 //  1. Help us juggle a temp directory for the testing
 //  2. Give us desired responses to package information responses
 type fakeActDeactServer struct {
-	pb.UnimplementedMspmServer
+	pb.MspmClient
 	tmpDir string
 	pvMap  map[string]map[string][]string
 }
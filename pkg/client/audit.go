@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Search the server audit log for records about a package (any
+// package, if pkgName is empty), made between from and to. Zero times
+// leave that end open. At most limit records are returned, the newest
+// ones, unless limit is 0.
+func (c *Client) QueryAuditLog(ctx context.Context, pkgName string, from, to time.Time, limit int) ([]*pb.AuditEntry, error) {
+	req := pb.AuditQuery{
+		PackageName: pkgName,
+		Limit:       int32(limit),
+	}
+	if !from.IsZero() {
+		req.From = from.Unix()
+	}
+	if !to.IsZero() {
+		req.To = to.Unix()
+	}
+
+	resp, err := c.client.QueryAuditLog(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("QueryAuditLog")
+		return nil, err
+	}
+
+	return resp.GetEntries(), nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Write the contents of a single file in a version of a package,
+// designated by label or version, to w. The size, and the hash if the
+// server knows it, are checked once everything has been written.
+func (c *Client) Cat(ctx context.Context, pkgName, designator, name string, w io.Writer) error {
+	req := pb.GetFileRequest{PackageName: pkgName, Designator: designator, Path: name}
+	stream, err := c.client.GetFile(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"name":       pkgName,
+			"designator": designator,
+			"path":       name,
+		}).Error("Cat - starting download")
+		return err
+	}
+
+	var entry *pb.FileEntry
+	checksum := sha512.New()
+	counter := &countingWriter{w: io.MultiWriter(w, checksum)}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":      err,
+				"name":       pkgName,
+				"designator": designator,
+				"path":       name,
+			}).Error("Cat - receiving")
+			return err
+		}
+		if entry == nil {
+			entry = chunk.GetFile()
+		}
+		if _, err := counter.Write(chunk.GetData()); err != nil {
+			return err
+		}
+	}
+
+	if entry == nil {
+		return fmt.Errorf("no file %s received", name)
+	}
+	if counter.n != entry.GetSize() {
+		return fmt.Errorf("file %s: received %d bytes, expected %d", name, counter.n, entry.GetSize())
+	}
+	if sum := fmt.Sprintf("%x", checksum.Sum(nil)); entry.GetHash() != "" && sum != entry.GetHash() {
+		return fmt.Errorf("file %s: hash mismatch, saw %s, expected %s", name, sum, entry.GetHash())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Sends a canned file.
+type fakeFileStream struct {
+	grpc.ClientStream
+	chunks []*pb.FileChunk
+}
+
+func (f *fakeFileStream) Recv() (*pb.FileChunk, error) {
+	if len(f.chunks) == 0 {
+		return nil, io.EOF
+	}
+	rv := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return rv, nil
+}
+
+type fakeCatServer struct {
+	pb.MspmClient
+	contents string
+	hash     string
+}
+
+func (f *fakeCatServer) GetFile(ctx context.Context, in *pb.GetFileRequest, opts ...grpc.CallOption) (pb.Mspm_GetFileClient, error) {
+	entry := &pb.FileEntry{Name: in.GetPath(), Type: "file", Size: int64(len(f.contents)), Hash: f.hash}
+	return &fakeFileStream{chunks: []*pb.FileChunk{
+		{File: entry, Data: []byte(f.contents[:3])},
+		{Data: []byte(f.contents[3:])},
+	}}, nil
+}
+
+func TestCat(t *testing.T) {
+	contents := "port = 8080\n"
+	fs := &fakeCatServer{contents: contents, hash: fmt.Sprintf("%x", sha512.Sum512([]byte(contents)))}
+	c := Client{client: fs}
+
+	var out bytes.Buffer
+	if err := c.Cat(context.Background(), "foo", "latest", "etc/config", &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != contents {
+		t.Errorf("Saw %q, want %q", out.String(), contents)
+	}
+
+	fs.hash = "0123"
+	if err := c.Cat(context.Background(), "foo", "latest", "etc/config", &bytes.Buffer{}); err == nil {
+		t.Errorf("Expected error for a hash mismatch, saw none")
+	}
+}
@@ -1,8 +1,15 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	pb "github.com/vatine/mspm/pkg/protos"
 )
@@ -13,8 +20,99 @@ type Client struct {
 	mspmDir string
 }
 
-// Create a new client Config, with a hooked-up gRPC client.
-func New(backend, directory string) (*Client, error) {
+// What the Options given to New have asked for.
+type clientOptions struct {
+	tls           *tls.Config
+	token         string
+	insecureToken bool
+	dialOpts      []grpc.DialOption
+}
+
+// An Option changes how New connects to the server.
+type Option func(*clientOptions) error
+
+// Connect using TLS, verifying the server certificate against the CA
+// certificates (PEM) in caFile, or the system CAs if caFile is empty.
+func WithTLS(caFile string) Option {
+	return func(o *clientOptions) error {
+		if o.tls == nil {
+			o.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		if caFile == "" {
+			return nil
+		}
+
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", caFile)
+		}
+		o.tls.RootCAs = pool
+		return nil
+	}
+}
+
+// Present a client certificate to the server, for servers that
+// require mutual TLS. This implies TLS, verified against the system
+// CAs unless WithTLS says otherwise.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(o *clientOptions) error {
+		if o.tls == nil {
+			o.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		o.tls.Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// Attaches a bearer token to every call.
+type tokenCredentials struct {
+	token    string
+	insecure bool
+}
+
+func (tc tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + tc.token}, nil
+}
+
+// Tokens are only sent over TLS, unless WithInsecureToken says
+// otherwise.
+func (tc tokenCredentials) RequireTransportSecurity() bool {
+	return !tc.insecure
+}
+
+// Authenticate to the server with a bearer token. This needs TLS.
+func WithToken(token string) Option {
+	return func(o *clientOptions) error {
+		if token == "" {
+			return fmt.Errorf("empty token")
+		}
+		o.token = token
+		return nil
+	}
+}
+
+// Allow the bearer token to be sent over a plaintext connection. This
+// is for testing only, anyone watching the connection can see the
+// token.
+func WithInsecureToken() Option {
+	return func(o *clientOptions) error {
+		o.insecureToken = true
+		return nil
+	}
+}
+
+// Create a new client Config, with a hooked-up gRPC client. Without
+// options, the connection is not encrypted.
+func New(backend, directory string, opts ...Option) (*Client, error) {
 	log.WithFields(log.Fields{
 		"backend":   backend,
 		"directory": directory,
@@ -22,7 +120,29 @@ func New(backend, directory string) (*Client, error) {
 	var rv Client
 	var err error
 
-	rv.conn, err = grpc.Dial(backend, nil)
+	var co clientOptions
+	for _, opt := range opts {
+		if err := opt(&co); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("applying client option")
+			return nil, err
+		}
+	}
+	if co.token != "" {
+		if co.tls == nil && !co.insecureToken {
+			log.Error("token without TLS")
+			return nil, fmt.Errorf("a token needs TLS, or WithInsecureToken")
+		}
+		creds := tokenCredentials{token: co.token, insecure: co.insecureToken}
+		co.dialOpts = append(co.dialOpts, grpc.WithPerRPCCredentials(creds))
+	}
+	dialOpts := append([]grpc.DialOption{grpc.WithInsecure()}, co.dialOpts...)
+	if co.tls != nil {
+		dialOpts[0] = grpc.WithTransportCredentials(credentials.NewTLS(co.tls))
+	}
+
+	rv.conn, err = grpc.Dial(backend, dialOpts...)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err,
@@ -35,6 +155,7 @@ func New(backend, directory string) (*Client, error) {
 	return &rv, nil
 }
 
+// Close the connection to the server.
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("./tempdir", "config")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var co clientOptions
+	if err := WithTLS("")(&co); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if co.tls == nil || co.tls.RootCAs != nil {
+		t.Errorf("Expected TLS with the system CAs, saw %v", co.tls)
+	}
+
+	bad := filepath.Join(dir, "bad.pem")
+	ioutil.WriteFile(bad, []byte("not a certificate"), 0644)
+	for _, caFile := range []string{bad, filepath.Join(dir, "missing.pem")} {
+		if err := WithTLS(caFile)(&clientOptions{}); err == nil {
+			t.Errorf("Expected an error for CA file %s", caFile)
+		}
+	}
+
+	_, err = New("localhost:0", dir, WithTLS(bad))
+	if err == nil {
+		t.Errorf("Expected New to fail with a bad option")
+	}
+}
+
+func TestWithClientCertificate(t *testing.T) {
+	var co clientOptions
+	err := WithClientCertificate("testdata/missing.crt", "testdata/missing.key")(&co)
+	if err == nil {
+		t.Errorf("Expected an error for a missing certificate")
+	}
+	if co.tls == nil {
+		t.Errorf("Expected a client certificate to imply TLS")
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	var co clientOptions
+	if err := WithToken("")(&co); err == nil {
+		t.Errorf("Expected an error for an empty token")
+	}
+	if err := WithToken("secret")(&co); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if co.token != "secret" {
+		t.Errorf("Expected token to be kept, saw %q", co.token)
+	}
+
+	creds := tokenCredentials{token: "secret"}
+	md, _ := creds.GetRequestMetadata(context.Background())
+	if md["authorization"] != "Bearer secret" {
+		t.Errorf("Unexpected metadata %v", md)
+	}
+	if !creds.RequireTransportSecurity() {
+		t.Errorf("Expected tokens to need TLS")
+	}
+
+	dir, err := ioutil.TempDir("./tempdir", "config")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := New("localhost:0", dir, WithToken("secret")); err == nil {
+		t.Errorf("Expected an error for a token without TLS")
+	}
+	c, err := New("localhost:0", dir, WithToken("secret"), WithInsecureToken())
+	if err != nil {
+		t.Fatalf("Unexpected error with an insecure token: %v", err)
+	}
+	c.Close()
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Delete a version of a package from the server. A version carrying
+// protected labels is only deleted if force is set.
+func (c *Client) DeleteVersion(ctx context.Context, pkgName, version string, force bool) (*pb.PackageInformation, error) {
+	req := pb.DeleteRequest{PackageName: pkgName, Version: version, Force: force}
+
+	resp, err := c.client.DeleteVersion(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pkgName,
+			"version": version,
+		}).Error("DeleteVersion")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Delete a package, with all its versions, from the server. The
+// deleted versions are returned.
+func (c *Client) DeletePackage(ctx context.Context, pkgName string, force bool) ([]*pb.PackageInformation, error) {
+	req := pb.DeleteRequest{PackageName: pkgName, Force: force}
+
+	resp, err := c.client.DeletePackage(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("DeletePackage")
+		return nil, err
+	}
+
+	return resp.GetPackageData(), nil
+}
+
+// Ask the server which versions its retention policy would delete
+// right now, for one package or (if pkgName is empty) all of them.
+func (c *Client) RetentionReport(ctx context.Context, pkgName string) ([]*pb.ExpiredVersion, error) {
+	req := pb.RetentionReportRequest{PackageName: pkgName}
+
+	resp, err := c.client.GetRetentionReport(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("RetentionReport")
+		return nil, err
+	}
+
+	return resp.GetVersions(), nil
+}
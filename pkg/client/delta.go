@@ -0,0 +1,303 @@
+package client
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+var versionPattern = regexp.MustCompile(`^[0-9a-f]{128}$`)
+
+// Find an installed version of a package to base a delta download on.
+// This is the active version if there is one, otherwise the most
+// recently installed. Returns "" if no version is installed.
+func (c *Client) installedVersion(pkgName string) string {
+	isInstalled := func(version string) bool {
+		if !versionPattern.MatchString(version) {
+			return false
+		}
+		fi, err := os.Stat(path.Join(c.mspmDir, fmt.Sprintf("%s-%s", pkgName, version)))
+		return err == nil && fi.IsDir()
+	}
+
+	if target, err := os.Readlink(path.Join(c.mspmDir, pkgName)); err == nil {
+		if _, version, err := splitPackageVersion(target, pkgName); err == nil && isInstalled(version) {
+			return version
+		}
+	}
+
+	versions, err := listAllPossibleVersions(c.mspmDir, pkgName)
+	if err != nil {
+		return ""
+	}
+	var rv string
+	var newest os.FileInfo
+	for _, version := range versions {
+		if !isInstalled(version) {
+			continue
+		}
+		fi, _ := os.Stat(path.Join(c.mspmDir, fmt.Sprintf("%s-%s", pkgName, version)))
+		if newest == nil || fi.ModTime().After(newest.ModTime()) {
+			rv, newest = version, fi
+		}
+	}
+	return rv
+}
+
+// Return where a file in a package ends up under root, checking that
+// it stays under root.
+func packagePath(root, name string) (string, error) {
+	trimmed := strings.TrimSuffix(name, "/")
+	if trimmed == "" || path.Clean(trimmed) != trimmed || strings.HasPrefix(trimmed, "../") || trimmed == ".." || path.IsAbs(trimmed) {
+		return "", fmt.Errorf("invalid entry %s in package", name)
+	}
+	return filepath.Join(root, filepath.FromSlash(trimmed)), nil
+}
+
+// Copy a local file, giving the copy the mode mode.
+func copyFile(source, target string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	if err := out.Chmod(mode); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// Hard-link a file from an installed version into a new one. As the
+// two share the mode, files that need a different mode are copied,
+// as are files that cannot be linked.
+func linkOrCopy(source, target string, mode os.FileMode) error {
+	fi, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", source)
+	}
+	if fi.Mode().Perm() == mode {
+		if err := os.Link(source, target); err == nil {
+			return nil
+		}
+	}
+	return copyFile(source, target, mode)
+}
+
+// Install a version of a package into dest by only downloading what
+// changed since an installed version. Files whose contents are in the
+// installed version are taken from it, the rest are received from
+// the server. The result is verified against the version hash.
+func (c *Client) downloadDelta(pkgName, installed, version, dest string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := pb.DeltaRequest{PackageName: pkgName, InstalledVersion: installed, Designator: version}
+	stream, err := c.client.DownloadDelta(ctx, &req)
+	if err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if seen := first.GetPackageData().GetVersion(); seen != version {
+		return fmt.Errorf("asked for %s version %s, server sent %s", pkgName, version, seen)
+	}
+
+	oldDir := path.Join(c.mspmDir, fmt.Sprintf("%s-%s", pkgName, installed))
+	wanted := make(map[string][]*pb.DeltaFile)
+	var dirs []dirMode
+	linked := 0
+	for _, f := range first.GetFiles() {
+		target, err := packagePath(dest, f.GetName())
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(f.GetMode() & 0777)
+		switch {
+		case strings.HasSuffix(f.GetName(), "/"):
+			// Permissions are set once everything is in
+			// place and verified.
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{target, mode})
+		case f.GetInstalled() != "":
+			source, err := packagePath(oldDir, f.GetInstalled())
+			if err != nil {
+				return err
+			}
+			if err := linkOrCopy(source, target, mode); err != nil {
+				return err
+			}
+			linked++
+		default:
+			wanted[f.GetHash()] = append(wanted[f.GetHash()], f)
+		}
+	}
+
+	received := len(wanted)
+	if err := receiveContents(stream, dest, wanted); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"name":      pkgName,
+		"installed": installed,
+		"version":   version,
+		"linked":    linked,
+		"received":  received,
+		"removed":   len(first.GetRemoved()),
+	}).Debug("Install - assembled delta")
+
+	seen, err := deltaVersion(dest, first.GetFiles())
+	if err != nil {
+		return err
+	}
+	if seen != version {
+		return fmt.Errorf("package %s-%s: contents hash to version %s", pkgName, version, seen)
+	}
+
+	for ix := len(dirs) - 1; ix >= 0; ix-- {
+		if err := os.Chmod(dirs[ix].path, dirs[ix].mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive the contents of changed files in a delta download, writing
+// them to every file in wanted (by hash) that has them. Each hash is
+// checked once all of it has been received.
+func receiveContents(stream pb.Mspm_DownloadDeltaClient, dest string, wanted map[string][]*pb.DeltaFile) error {
+	var current string
+	var out *os.File
+	var sum hash.Hash
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+	}()
+
+	// The contents are written to the first file needing them,
+	// and copied to any others.
+	finish := func() error {
+		err := out.Close()
+		out = nil
+		if err != nil {
+			return err
+		}
+		if seen := fmt.Sprintf("%x", sum.Sum(nil)); seen != current {
+			return fmt.Errorf("contents %s received with hash %s", current, seen)
+		}
+		files := wanted[current]
+		firstPath, _ := packagePath(dest, files[0].GetName())
+		if err := os.Chmod(firstPath, os.FileMode(files[0].GetMode()&0777)); err != nil {
+			return err
+		}
+		for _, f := range files[1:] {
+			target, _ := packagePath(dest, f.GetName())
+			if err := copyFile(firstPath, target, os.FileMode(f.GetMode()&0777)); err != nil {
+				return err
+			}
+		}
+		delete(wanted, current)
+		return nil
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if out == nil || chunk.GetHash() != current {
+			if out != nil {
+				if err := finish(); err != nil {
+					return err
+				}
+			}
+			files, ok := wanted[chunk.GetHash()]
+			if !ok {
+				return fmt.Errorf("unexpected contents %s in delta", chunk.GetHash())
+			}
+			target, err := packagePath(dest, files[0].GetName())
+			if err != nil {
+				return err
+			}
+			out, err = os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return err
+			}
+			current = chunk.GetHash()
+			sum = sha512.New()
+		}
+
+		if _, err := io.MultiWriter(out, sum).Write(chunk.GetData()); err != nil {
+			return err
+		}
+	}
+	if out != nil {
+		if err := finish(); err != nil {
+			return err
+		}
+	}
+
+	if len(wanted) > 0 {
+		return fmt.Errorf("contents for %d file(s) missing from delta", len(wanted))
+	}
+	return nil
+}
+
+// Compute the version hash of an assembled package, with the files
+// in the order the server listed them.
+func deltaVersion(dest string, files []*pb.DeltaFile) (string, error) {
+	vh := data.NewVersionHash()
+	for _, f := range files {
+		if strings.HasSuffix(f.GetName(), "/") {
+			vh.Add(f.GetName(), f.GetOwner(), f.GetMode()&0777, nil)
+			continue
+		}
+		target, err := packagePath(dest, f.GetName())
+		if err != nil {
+			return "", err
+		}
+		err = func() error {
+			in, err := os.Open(target)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			return vh.Add(f.GetName(), f.GetOwner(), f.GetMode()&0777, in)
+		}()
+		if err != nil {
+			return "", err
+		}
+	}
+	return vh.Version(), nil
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Sends a canned delta.
+type fakeDeltaStream struct {
+	grpc.ClientStream
+	chunks []*pb.DeltaChunk
+}
+
+func (f *fakeDeltaStream) Recv() (*pb.DeltaChunk, error) {
+	if len(f.chunks) == 0 {
+		return nil, io.EOF
+	}
+	rv := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return rv, nil
+}
+
+type fakeDeltaServer struct {
+	*fakeInstallServer
+	deltas map[string][]*pb.DeltaChunk
+}
+
+func (f *fakeDeltaServer) DownloadDelta(ctx context.Context, in *pb.DeltaRequest, opts ...grpc.CallOption) (pb.Mspm_DownloadDeltaClient, error) {
+	chunks, ok := f.deltas[in.GetInstalledVersion()+":"+in.GetDesignator()]
+	if !ok {
+		return nil, fmt.Errorf("no such delta")
+	}
+	return &fakeDeltaStream{chunks: append([]*pb.DeltaChunk{}, chunks...)}, nil
+}
+
+// Build the delta from one set of entries to another, the way the
+// server does, with files matched by contents.
+func buildDelta(version string, from, to []testEntry) []*pb.DeltaChunk {
+	byHash := make(map[string]string)
+	for _, e := range from {
+		if e.name[len(e.name)-1] != '/' {
+			byHash[fmt.Sprintf("%x", sha512.Sum512([]byte(e.contents)))] = e.name
+		}
+	}
+
+	first := &pb.DeltaChunk{PackageData: &pb.PackageInformation{PackageName: "foo", Version: version}}
+	rv := []*pb.DeltaChunk{first}
+	for _, e := range to {
+		f := &pb.DeltaFile{Name: e.name, Owner: "root", Mode: e.mode}
+		if e.name[len(e.name)-1] != '/' {
+			f.Size = int64(len(e.contents))
+			f.Hash = fmt.Sprintf("%x", sha512.Sum512([]byte(e.contents)))
+			f.Installed = byHash[f.Hash]
+			if f.Installed == "" {
+				rv = append(rv, &pb.DeltaChunk{Hash: f.Hash, Data: []byte(e.contents)})
+			}
+		}
+		first.Files = append(first.Files, f)
+	}
+	return rv
+}
+
+var deltaEntries = []testEntry{
+	{"bin/", 0555, ""},
+	{"bin/start", 0755, "#!/bin/sh\necho hello again\n"},
+	{"README", 0644, "Read me, please.\n"},
+}
+
+func newFakeDelta(t *testing.T) (*fakeDeltaServer, Client, string, string) {
+	fs := &fakeDeltaServer{
+		fakeInstallServer: newFakeInstall(t),
+		deltas:            make(map[string][]*pb.DeltaChunk),
+	}
+	c := Client{client: fs, mspmDir: fs.tmpDir}
+
+	oldVersion, oldTarball := buildTarball(t, "foo", installEntries)
+	fs.addTarball("foo", oldVersion, oldTarball, "latest")
+	if err := c.Install("foo", oldVersion); err != nil {
+		t.Fatalf("Unexpected error installing old version: %v", err)
+	}
+
+	version, tarball := buildTarball(t, "foo", deltaEntries)
+	fs.pvMap["foo"][version] = []string{"next"}
+	fs.tarballs[version] = tarball
+
+	return fs, c, oldVersion, version
+}
+
+func TestInstallDelta(t *testing.T) {
+	fs, c, oldVersion, version := newFakeDelta(t)
+	defer fs.tearDown()
+	fs.deltas[oldVersion+":"+version] = buildDelta(version, installEntries, deltaEntries)
+	// Make sure the full download is not used.
+	delete(fs.tarballs, version)
+
+	if err := c.Install("foo", "next"); err != nil {
+		t.Fatalf("Unexpected error installing delta: %v", err)
+	}
+
+	oldDir := path.Join(fs.tmpDir, "foo-"+oldVersion)
+	pkgDir := path.Join(fs.tmpDir, "foo-"+version)
+	contents, err := ioutil.ReadFile(path.Join(pkgDir, "bin/start"))
+	if err != nil || string(contents) != deltaEntries[1].contents {
+		t.Errorf("Saw bin/start %q (error %v)", contents, err)
+	}
+	for _, e := range deltaEntries {
+		st, err := os.Stat(path.Join(pkgDir, e.name))
+		if err != nil {
+			t.Errorf("Missing %s: %v", e.name, err)
+			continue
+		}
+		if st.Mode().Perm() != os.FileMode(e.mode) {
+			t.Errorf("%s has mode %v, want %v", e.name, st.Mode().Perm(), os.FileMode(e.mode))
+		}
+	}
+
+	oldReadme, err1 := os.Stat(path.Join(oldDir, "README"))
+	newReadme, err2 := os.Stat(path.Join(pkgDir, "README"))
+	if err1 != nil || err2 != nil || !os.SameFile(oldReadme, newReadme) {
+		t.Errorf("Expected README to be hard-linked from the installed version")
+	}
+}
+
+func TestInstallDeltaFallback(t *testing.T) {
+	fs, c, oldVersion, version := newFakeDelta(t)
+	defer fs.tearDown()
+
+	// Contents that do not match their hash make the delta fail,
+	// and the full download is used instead.
+	delta := buildDelta(version, installEntries, deltaEntries)
+	delta[1].Data = []byte("garbage")
+	fs.deltas[oldVersion+":"+version] = delta
+
+	if err := c.Install("foo", "next"); err != nil {
+		t.Fatalf("Unexpected error installing: %v", err)
+	}
+	contents, err := ioutil.ReadFile(path.Join(fs.tmpDir, "foo-"+version, "bin/start"))
+	if err != nil || string(contents) != deltaEntries[1].contents {
+		t.Errorf("Saw bin/start %q (error %v)", contents, err)
+	}
+	entries, _ := ioutil.ReadDir(fs.tmpDir)
+	if len(entries) != 2 {
+		t.Errorf("Expected only the two installed versions, saw %d entries", len(entries))
+	}
+}
@@ -0,0 +1,311 @@
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Makes the chunks of a package download look like an io.Reader. The
+// first chunk is kept, as that has the package metadata.
+type downloadReader struct {
+	stream pb.Mspm_DownloadPackageClient
+	first  *pb.PackageChunk
+	buf    []byte
+}
+
+func newDownloadReader(stream pb.Mspm_DownloadPackageClient) (*downloadReader, error) {
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &downloadReader{stream: stream, first: first, buf: first.GetData()}, nil
+}
+
+func (d *downloadReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		chunk, err := d.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		d.buf = chunk.GetData()
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// Install a version of a package, designated by label or version,
+// into the mspm directory. The package is downloaded, checked against
+// the checksum the server sends and its version hash, and unpacked
+// into a temporary directory that is then renamed into place, so a
+// <name>-<version> directory is always complete. If another version
+// of the package is installed, only what changed is downloaded.
+// Installing an already installed version does nothing.
+func (c *Client) Install(pkgName, designator string) error {
+	log.WithFields(log.Fields{
+		"package name":  pkgName,
+		"label/version": designator,
+	}).Debug("install entered")
+
+	version, err := c.matchLabelToVersion(pkgName, designator)
+	if err != nil {
+		return err
+	}
+
+	fullName := fmt.Sprintf("%s-%s", pkgName, version)
+	fullPath := path.Join(c.mspmDir, fullName)
+	if _, err := os.Lstat(fullPath); err == nil {
+		log.WithFields(log.Fields{
+			"name":    pkgName,
+			"version": version,
+			"path":    fullPath,
+		}).Info("Install - already installed")
+		return nil
+	}
+
+	tmpDir, err := c.download(pkgName, version)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = os.Chmod(tmpDir, 0755)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpDir, fullPath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"tmpDir":  tmpDir,
+			"path":    fullPath,
+			"version": version,
+		}).Error("Install - renaming into place")
+	}
+	return err
+}
+
+// Download a version of a package into a new temporary directory in
+// the mspm directory, returning the directory. If another version of
+// the package is installed, only what changed is downloaded, falling
+// back to downloading everything if that does not work out.
+func (c *Client) download(pkgName, version string) (string, error) {
+	if installed := c.installedVersion(pkgName); installed != "" {
+		tmpDir, err := c.installDir()
+		if err != nil {
+			return "", err
+		}
+		err = c.downloadDelta(pkgName, installed, version, tmpDir)
+		if err == nil {
+			return tmpDir, nil
+		}
+		os.RemoveAll(tmpDir)
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      pkgName,
+			"installed": installed,
+			"version":   version,
+		}).Warning("Install - delta download failed, downloading everything")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := pb.GetPackageRequest{PackageName: pkgName, Designator: version}
+	stream, err := c.client.DownloadPackage(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pkgName,
+			"version": version,
+		}).Error("Install - starting download")
+		return "", err
+	}
+
+	tmpDir, err := c.installDir()
+	if err != nil {
+		return "", err
+	}
+	err = c.downloadAndExtract(stream, pkgName, version, tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+	return tmpDir, nil
+}
+
+// Create a temporary directory to install into.
+func (c *Client) installDir() (string, error) {
+	tmpDir, err := ioutil.TempDir(c.mspmDir, ".install-")
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"dir":   c.mspmDir,
+		}).Error("Install - creating temporary directory")
+	}
+	return tmpDir, err
+}
+
+// Receive a package download, unpack it into dest and verify it.
+func (c *Client) downloadAndExtract(stream pb.Mspm_DownloadPackageClient, pkgName, version, dest string) error {
+	in, err := newDownloadReader(stream)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pkgName,
+			"version": version,
+		}).Error("Install - receiving download")
+		return err
+	}
+	if seen := in.first.GetPackageData().GetVersion(); seen != version {
+		return fmt.Errorf("asked for %s version %s, server sent %s", pkgName, version, seen)
+	}
+
+	checksum := sha512.New()
+	counter := &countingWriter{w: checksum}
+	seen, dirs, err := extractPackage(io.TeeReader(in, counter), pkgName, version, dest)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pkgName,
+			"version": version,
+		}).Error("Install - extracting package")
+		return err
+	}
+	// Drain anything after the end of the archive, so it is
+	// included in the checksum.
+	_, err = io.Copy(counter, in)
+	if err != nil {
+		return err
+	}
+
+	if counter.n != in.first.GetSize() {
+		return fmt.Errorf("package %s-%s: received %d bytes, expected %d", pkgName, version, counter.n, in.first.GetSize())
+	}
+	if sum := fmt.Sprintf("%x", checksum.Sum(nil)); sum != in.first.GetChecksum() {
+		return fmt.Errorf("package %s-%s: checksum mismatch, saw %s, expected %s", pkgName, version, sum, in.first.GetChecksum())
+	}
+	if seen != version {
+		return fmt.Errorf("package %s-%s: contents hash to version %s", pkgName, version, seen)
+	}
+
+	// Only now that we know we are keeping the contents do we make
+	// directories (possibly) read-only.
+	for ix := len(dirs) - 1; ix >= 0; ix-- {
+		err = os.Chmod(dirs[ix].path, dirs[ix].mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// An io.Writer that counts what passes through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// A directory and the mode it should end up with.
+type dirMode struct {
+	path string
+	mode os.FileMode
+}
+
+// Unpack a package tarball into dest, returning the version hash of
+// the contents. Entries are expected to all be under
+// <pkgName>-<version>/, which is stripped. Directories are created
+// writable, the modes they should have are returned for the caller to
+// apply.
+func extractPackage(r io.Reader, pkgName, version, dest string) (string, []dirMode, error) {
+	unzipper, err := gzip.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer unzipper.Close()
+
+	prefix := fmt.Sprintf("%s-%s/", pkgName, version)
+	vh := data.NewVersionHash()
+	tarball := tar.NewReader(unzipper)
+	var dirs []dirMode
+
+	for {
+		hdr, err := tarball.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !strings.HasPrefix(hdr.Name, prefix) {
+			return "", nil, fmt.Errorf("unexpected entry %s in package", hdr.Name)
+		}
+		name := strings.TrimPrefix(hdr.Name, prefix)
+		target, err := packagePath(dest, name)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid entry %s in package", hdr.Name)
+		}
+		mode := int32(hdr.Mode & 0777)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if !strings.HasSuffix(name, "/") {
+				name = name + "/"
+			}
+			// Permissions are set once everything is
+			// unpacked and verified.
+			err = os.Mkdir(target, 0700)
+			if err != nil {
+				return "", nil, err
+			}
+			dirs = append(dirs, dirMode{target, os.FileMode(mode)})
+			vh.Add(name, hdr.Uname, mode, nil)
+		case tar.TypeReg:
+			err = func() error {
+				out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+				if err != nil {
+					return err
+				}
+				defer out.Close()
+				err = vh.Add(name, hdr.Uname, mode, io.TeeReader(tarball, out))
+				if err != nil {
+					return err
+				}
+				err = out.Chmod(os.FileMode(mode))
+				if err != nil {
+					return err
+				}
+				return out.Close()
+			}()
+			if err != nil {
+				return "", nil, err
+			}
+		default:
+			return "", nil, fmt.Errorf("unsupported entry type for %s in package", hdr.Name)
+		}
+	}
+
+	return vh.Version(), dirs, nil
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+type testEntry struct {
+	name     string
+	mode     int32
+	contents string
+}
+
+// Build a package tarball the way the server does, returning the
+// version and the tarball.
+func buildTarball(t *testing.T, name string, entries []testEntry) (string, []byte) {
+	vh := data.NewVersionHash()
+	for _, e := range entries {
+		vh.Add(e.name, "root", e.mode, bytes.NewBufferString(e.contents))
+	}
+	version := vh.Version()
+
+	var buf bytes.Buffer
+	zipper := gzip.NewWriter(&buf)
+	tarball := tar.NewWriter(zipper)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:  fmt.Sprintf("%s-%s/%s", name, version, e.name),
+			Mode:  int64(e.mode),
+			Uname: "root",
+		}
+		if e.name[len(e.name)-1] == '/' {
+			hdr.Typeflag = tar.TypeDir
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(e.contents))
+		}
+		if err := tarball.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed writing header: %v", err)
+		}
+		tarball.Write([]byte(e.contents))
+	}
+	tarball.Close()
+	zipper.Close()
+
+	return version, buf.Bytes()
+}
+
+// Sends a canned tarball, in small chunks.
+type fakeDownloadStream struct {
+	grpc.ClientStream
+	chunks []*pb.PackageChunk
+}
+
+func (f *fakeDownloadStream) Recv() (*pb.PackageChunk, error) {
+	if len(f.chunks) == 0 {
+		return nil, io.EOF
+	}
+	rv := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return rv, nil
+}
+
+type fakeInstallServer struct {
+	*fakeActDeactServer
+	tarballs map[string][]byte
+	checksum string
+}
+
+func (f *fakeInstallServer) DownloadPackage(ctx context.Context, in *pb.GetPackageRequest, opts ...grpc.CallOption) (pb.Mspm_DownloadPackageClient, error) {
+	tarball, ok := f.tarballs[in.GetDesignator()]
+	if !ok {
+		return nil, fmt.Errorf("no such package")
+	}
+	checksum := f.checksum
+	if checksum == "" {
+		checksum = fmt.Sprintf("%x", sha512.Sum512(tarball))
+	}
+
+	stream := &fakeDownloadStream{}
+	for len(tarball) > 0 || len(stream.chunks) == 0 {
+		n := 100
+		if n > len(tarball) {
+			n = len(tarball)
+		}
+		stream.chunks = append(stream.chunks, &pb.PackageChunk{Data: tarball[:n]})
+		tarball = tarball[n:]
+	}
+	first := stream.chunks[0]
+	first.PackageData = &pb.PackageInformation{PackageName: in.GetPackageName(), Version: in.GetDesignator()}
+	first.Size = int64(len(f.tarballs[in.GetDesignator()]))
+	first.Checksum = checksum
+
+	return stream, nil
+}
+
+func newFakeInstall(t *testing.T) *fakeInstallServer {
+	return &fakeInstallServer{
+		fakeActDeactServer: newFakeActDeact(t),
+		tarballs:           make(map[string][]byte),
+	}
+}
+
+// Register a tarball with the fake server, without installing it.
+func (f *fakeInstallServer) addTarball(name, version string, tarball []byte, labels ...string) {
+	f.pvMap[name] = map[string][]string{version: labels}
+	f.tarballs[version] = tarball
+}
+
+var installEntries = []testEntry{
+	{"bin/", 0555, ""},
+	{"bin/start", 0755, "#!/bin/sh\necho hello\n"},
+	{"README", 0644, "Read me, please.\n"},
+}
+
+func TestInstall(t *testing.T) {
+	fs := newFakeInstall(t)
+	defer fs.tearDown()
+	c := Client{client: fs, mspmDir: fs.tmpDir}
+
+	version, tarball := buildTarball(t, "foo", installEntries)
+	fs.addTarball("foo", version, tarball, "latest")
+
+	if err := c.Install("foo", "latest"); err != nil {
+		t.Fatalf("Unexpected error installing: %v", err)
+	}
+
+	pkgDir := path.Join(fs.tmpDir, "foo-"+version)
+	contents, err := ioutil.ReadFile(path.Join(pkgDir, "bin/start"))
+	if err != nil || string(contents) != installEntries[1].contents {
+		t.Errorf("Saw bin/start %q (error %v)", contents, err)
+	}
+	for _, e := range installEntries {
+		st, err := os.Stat(path.Join(pkgDir, e.name))
+		if err != nil {
+			t.Errorf("Missing %s: %v", e.name, err)
+			continue
+		}
+		if st.Mode().Perm() != os.FileMode(e.mode) {
+			t.Errorf("%s has mode %v, want %v", e.name, st.Mode().Perm(), os.FileMode(e.mode))
+		}
+	}
+
+	// It should now be possible to activate it.
+	if err := c.Activate("foo", "latest"); err != nil {
+		t.Errorf("Unexpected error activating: %v", err)
+	}
+	// And installing again is fine.
+	if err := c.Install("foo", version); err != nil {
+		t.Errorf("Unexpected error re-installing: %v", err)
+	}
+}
+
+func TestInstallBadChecksum(t *testing.T) {
+	fs := newFakeInstall(t)
+	defer fs.tearDown()
+	c := Client{client: fs, mspmDir: fs.tmpDir}
+
+	version, tarball := buildTarball(t, "foo", installEntries)
+	fs.addTarball("foo", version, tarball, "latest")
+	fs.checksum = "0123"
+
+	if err := c.Install("foo", "latest"); err == nil {
+		t.Errorf("Expected error installing, saw none")
+	}
+	if left, _ := ioutil.ReadDir(fs.tmpDir); len(left) != 0 {
+		t.Errorf("Failed install left %d entries behind", len(left))
+	}
+}
+
+func TestInstallBadVersion(t *testing.T) {
+	fs := newFakeInstall(t)
+	defer fs.tearDown()
+	c := Client{client: fs, mspmDir: fs.tmpDir}
+
+	// Claim the contents are a different version.
+	_, tarball := buildTarball(t, "foo", installEntries)
+	version, _ := buildTarball(t, "foo", installEntries[:2])
+	fs.addTarball("foo", version, tarball, "latest")
+
+	if err := c.Install("foo", "latest"); err == nil {
+		t.Errorf("Expected error installing, saw none")
+	}
+	if left, _ := ioutil.ReadDir(fs.tmpDir); len(left) != 0 {
+		t.Errorf("Failed install left %d entries behind", len(left))
+	}
+}
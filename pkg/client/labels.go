@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Set one or more labels on the version of a package designated by
+// designator (a version or a label). Protected labels are only moved
+// from another version if force is set.
+func (c *Client) SetLabels(ctx context.Context, pkgName, designator string, force bool, labels ...string) (*pb.PackageInformation, error) {
+	return c.CompareAndSetLabels(ctx, pkgName, designator, nil, force, labels...)
+}
+
+// Set labels like SetLabels, but only if the labels in expected are
+// currently on the versions given there (an empty version meaning the
+// label must not be on any version). If any of them has moved, no
+// label is changed and an error with code Aborted is returned.
+func (c *Client) CompareAndSetLabels(ctx context.Context, pkgName, designator string, expected map[string]string, force bool, labels ...string) (*pb.PackageInformation, error) {
+	req := pb.SetLabelRequest{
+		PackageName: pkgName,
+		Version:     designator,
+		Label:       labels,
+		Force:       force,
+		Expected:    expected,
+	}
+
+	resp, err := c.client.SetLabels(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"name":       pkgName,
+			"designator": designator,
+			"labels":     labels,
+			"expected":   expected,
+		}).Error("SetLabels")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Change the settings of a package. With disableLatest set, new
+// uploads no longer get the "latest" label.
+func (c *Client) SetPackageOptions(ctx context.Context, pkgName string, disableLatest bool) (*pb.PackageOptions, error) {
+	req := pb.PackageOptions{
+		PackageName:   pkgName,
+		DisableLatest: disableLatest,
+	}
+
+	resp, err := c.client.SetPackageOptions(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("SetPackageOptions")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Apply a batch of label changes, possibly across several packages,
+// all or nothing. The version each label ended up on is returned, one
+// per change.
+func (c *Client) ChangeLabels(ctx context.Context, changes []*pb.LabelChange) ([]*pb.PackageInformation, error) {
+	resp, err := c.client.ChangeLabels(ctx, &pb.LabelTransaction{Changes: changes})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"changes": len(changes),
+		}).Error("ChangeLabels")
+		return nil, err
+	}
+
+	return resp.GetPackageData(), nil
+}
+
+// Return the history of a label on a package, oldest first. With an
+// empty label, the history of all labels on the package is returned.
+func (c *Client) LabelHistory(ctx context.Context, pkgName, label string) ([]*pb.LabelEvent, error) {
+	req := pb.LabelHistoryRequest{
+		PackageName: pkgName,
+		Label:       label,
+	}
+
+	resp, err := c.client.GetLabelHistory(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+			"label": label,
+		}).Error("LabelHistory")
+		return nil, err
+	}
+
+	return resp.GetEvents(), nil
+}
+
+// Move a label back to the version it was on before it last
+// moved. Protected labels need force.
+func (c *Client) RollbackLabel(ctx context.Context, pkgName, label string, force bool) (*pb.PackageInformation, error) {
+	req := pb.RollbackLabelRequest{
+		PackageName: pkgName,
+		Label:       label,
+		Force:       force,
+	}
+
+	resp, err := c.client.RollbackLabel(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+			"label": label,
+		}).Error("RollbackLabel")
+		return nil, err
+	}
+
+	return resp, nil
+}
@@ -25,3 +25,54 @@ func (c *Client) GetPackageInformation(ctx context.Context, name string) ([]*pb.
 
 	return resp.PackageData, nil
 }
+
+// List the packages whose names start with prefix and match pattern
+// (a glob, as in path.Match), sorted by name. Either can be empty to
+// match everything. All pages of the listing are fetched.
+func (c *Client) ListPackages(ctx context.Context, prefix, pattern string) ([]*pb.PackageSummary, error) {
+	return c.listPackages(ctx, &pb.ListPackagesRequest{Prefix: prefix, Pattern: pattern})
+}
+
+// List the packages that have a label on one of their versions. The
+// summary of each says which version that is.
+func (c *Client) PackagesWithLabel(ctx context.Context, label string) ([]*pb.PackageSummary, error) {
+	return c.listPackages(ctx, &pb.ListPackagesRequest{Label: label})
+}
+
+func (c *Client) listPackages(ctx context.Context, req *pb.ListPackagesRequest) ([]*pb.PackageSummary, error) {
+	var rv []*pb.PackageSummary
+
+	for {
+		resp, err := c.client.ListPackages(ctx, req)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"prefix":  req.GetPrefix(),
+				"pattern": req.GetPattern(),
+				"label":   req.GetLabel(),
+			}).Error("ListPackages")
+			return nil, err
+		}
+		rv = append(rv, resp.GetPackages()...)
+		if resp.GetNextPageToken() == "" {
+			return rv, nil
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+}
+
+// List the files in a version of a package, designated by label or
+// version.
+func (c *Client) ListFiles(ctx context.Context, pkgName, designator string) (*pb.FileList, error) {
+	req := pb.ListFilesRequest{PackageName: pkgName, Designator: designator}
+	resp, err := c.client.ListFiles(ctx, &req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"name":       pkgName,
+			"designator": designator,
+		}).Error("ListFiles")
+		return nil, err
+	}
+	return resp, nil
+}
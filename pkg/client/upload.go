@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// How much file data we send in each message of an upload.
+const uploadChunkSize = 64 * 1024
+
+// A file to upload. File describes how it looks in the package
+// (Contents are ignored), Path is where to read it from locally, and
+// is empty for directories.
+type UploadFile struct {
+	Path string
+	File *pb.File
+}
+
+// Look up the user and group names owning a file, falling back to the
+// numeric IDs if there are no names for them.
+func fileOwner(fi os.FileInfo) (string, string) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+
+	owner := strconv.Itoa(int(st.Uid))
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	group := strconv.Itoa(int(st.Gid))
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+
+	return owner, group
+}
+
+// Describe a local file (or directory) as it should be uploaded, under
+// the name name.
+func localUploadFile(localPath, name string) (UploadFile, error) {
+	fi, err := os.Stat(localPath)
+	if err != nil {
+		return UploadFile{}, err
+	}
+
+	owner, group := fileOwner(fi)
+	f := &pb.File{
+		Name:  name,
+		Owner: owner,
+		Group: group,
+		Mode:  int32(fi.Mode().Perm()),
+	}
+
+	switch {
+	case fi.IsDir():
+		if !strings.HasSuffix(f.Name, "/") {
+			f.Name = f.Name + "/"
+		}
+		return UploadFile{File: f}, nil
+	case fi.Mode().IsRegular():
+		return UploadFile{Path: localPath, File: f}, nil
+	}
+
+	return UploadFile{}, fmt.Errorf("%s is neither a file nor a directory", localPath)
+}
+
+// Build the upload list for a set of local files, given as paths
+// relative to root. Any directories leading up to the files are
+// included, so the package is complete.
+func UploadFilesFromPaths(root string, paths []string) ([]UploadFile, error) {
+	names := make(map[string]bool)
+	for _, p := range paths {
+		clean := filepath.ToSlash(filepath.Clean(p))
+		if filepath.IsAbs(p) || clean == "." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("%s is not a path under %s", p, root)
+		}
+		names[clean] = false
+		for dir := filepath.ToSlash(filepath.Dir(clean)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			names[dir] = true
+		}
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var rv []UploadFile
+	for _, name := range sorted {
+		uf, err := localUploadFile(filepath.Join(root, filepath.FromSlash(name)), name)
+		if err != nil {
+			return nil, err
+		}
+		if names[name] && uf.Path != "" {
+			return nil, fmt.Errorf("%s is used as a directory, but is not one", name)
+		}
+		rv = append(rv, uf)
+	}
+
+	return rv, nil
+}
+
+// Check if a path in a package matches any of a set of ignore
+// patterns. Patterns use filepath.Match syntax and are matched against
+// both the full path and the last element of it, so "*.o" ignores
+// object files anywhere, while "build/tmp" only ignores that one
+// directory.
+func ignored(name string, patterns []string) (bool, error) {
+	base := path.Base(name)
+	for _, p := range patterns {
+		for _, candidate := range []string{name, base} {
+			match, err := filepath.Match(p, candidate)
+			if err != nil {
+				return false, fmt.Errorf("bad ignore pattern %q: %v", p, err)
+			}
+			if match {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Build the upload list for everything under a local directory,
+// skipping anything matching the ignore patterns (an ignored directory
+// is skipped with all its contents). The directory itself becomes the
+// root of the package. Symlinks and other special files are not
+// supported.
+func UploadFilesFromDirectory(root string, ignore []string) ([]UploadFile, error) {
+	var rv []UploadFile
+
+	err := filepath.Walk(root, func(localPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if localPath == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, localPath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		skip, err := ignored(name, ignore)
+		if err != nil {
+			return err
+		}
+		if skip {
+			log.WithFields(log.Fields{
+				"name": name,
+			}).Debug("UploadFilesFromDirectory - ignoring")
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink, which is not supported", localPath)
+		}
+		uf, err := localUploadFile(localPath, name)
+		if err != nil {
+			return err
+		}
+		rv = append(rv, uf)
+		return nil
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"root":  root,
+		}).Error("UploadFilesFromDirectory")
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// Compare two package paths the way the server orders them when
+// computing the version, that is depth-first with the entries of each
+// directory sorted by name.
+func hashOrderLess(a, b string) bool {
+	as := strings.Split(strings.TrimSuffix(a, "/"), "/")
+	bs := strings.Split(strings.TrimSuffix(b, "/"), "/")
+	for ix := 0; ix < len(as) && ix < len(bs); ix++ {
+		if as[ix] != bs[ix] {
+			return as[ix] < bs[ix]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// Compute the version the server will give a package with the files
+// to upload, by reading the local files. This is the same hash the
+// server computes once the upload is finished.
+func PredictVersion(files []UploadFile) (string, error) {
+	sorted := append([]UploadFile{}, files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return hashOrderLess(sorted[i].File.GetName(), sorted[j].File.GetName())
+	})
+
+	vh := data.NewVersionHash()
+	for _, uf := range sorted {
+		f := uf.File
+		if uf.Path == "" {
+			vh.Add(f.GetName(), f.GetOwner(), f.GetMode(), nil)
+			continue
+		}
+		err := func() error {
+			in, err := os.Open(uf.Path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			return vh.Add(f.GetName(), f.GetOwner(), f.GetMode(), in)
+		}()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return vh.Version(), nil
+}
+
+// Upload everything under a local directory as a new version of a
+// package, skipping anything matching the ignore patterns. The version
+// is predicted before uploading, and it is an error if the server ends
+// up with a different one.
+func (c *Client) UploadDirectory(ctx context.Context, pkgName, root string, ignore []string, labels ...string) (*pb.PackageInformation, error) {
+	files, err := UploadFilesFromDirectory(root, ignore)
+	if err != nil {
+		return nil, err
+	}
+	predicted, err := PredictVersion(files)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.Upload(ctx, pkgName, files, labels...)
+	if err != nil {
+		return nil, err
+	}
+	if info.GetVersion() != predicted {
+		log.WithFields(log.Fields{
+			"name":      pkgName,
+			"predicted": predicted,
+			"version":   info.GetVersion(),
+		}).Error("UploadDirectory - version mismatch")
+		return info, fmt.Errorf("uploaded %s as version %s, expected %s", pkgName, info.GetVersion(), predicted)
+	}
+
+	return info, nil
+}
+
+// Upload a new version of a package, streaming the file contents to
+// the server. Any labels given are set on the new version, in addition
+// to "latest".
+func (c *Client) Upload(ctx context.Context, pkgName string, files []UploadFile, labels ...string) (*pb.PackageInformation, error) {
+	stream, err := c.client.UploadPackageStream(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("Upload - starting stream")
+		return nil, err
+	}
+
+	header := &pb.UploadHeader{PackageName: pkgName, Label: labels}
+	err = stream.Send(&pb.UploadRequest{Part: &pb.UploadRequest_Header{Header: header}})
+	if err != nil {
+		return nil, uploadError(stream, err)
+	}
+
+	for _, uf := range files {
+		err = stream.Send(&pb.UploadRequest{Part: &pb.UploadRequest_File{File: uf.File}})
+		if err != nil {
+			return nil, uploadError(stream, err)
+		}
+		if uf.Path == "" {
+			continue
+		}
+		err = sendFileData(uf.Path, func(buf []byte) error {
+			return stream.Send(&pb.UploadRequest{Part: &pb.UploadRequest_Data{Data: buf}})
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"name":  pkgName,
+				"path":  uf.Path,
+			}).Error("Upload - sending file")
+			return nil, uploadError(stream, err)
+		}
+	}
+
+	info, err := stream.CloseAndRecv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pkgName,
+		}).Error("Upload - finishing")
+	}
+	return info, err
+}
+
+// When Send fails, the real reason is found by receiving the status.
+func uploadError(stream pb.Mspm_UploadPackageStreamClient, err error) error {
+	if err == io.EOF {
+		_, err = stream.CloseAndRecv()
+	}
+	return err
+}
+
+// Read a local file, calling send with up to uploadChunkSize bytes at
+// a time.
+func sendFileData(localPath string, send func([]byte) error) error {
+	in, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	for {
+		buf := make([]byte, uploadChunkSize)
+		n, err := in.Read(buf)
+		if n > 0 {
+			if sendErr := send(buf[:n]); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
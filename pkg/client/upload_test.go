@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Collects what a client sends in an upload stream.
+type fakeUploadStream struct {
+	grpc.ClientStream
+	sent []*pb.UploadRequest
+}
+
+func (f *fakeUploadStream) Send(req *pb.UploadRequest) error {
+	f.sent = append(f.sent, req)
+	return nil
+}
+
+func (f *fakeUploadStream) CloseAndRecv() (*pb.PackageInformation, error) {
+	header := f.sent[0].GetHeader()
+	return &pb.PackageInformation{PackageName: header.GetPackageName(), Label: header.GetLabel()}, nil
+}
+
+type fakeUploadServer struct {
+	pb.MspmClient
+	stream *fakeUploadStream
+}
+
+func (f *fakeUploadServer) UploadPackageStream(ctx context.Context, opts ...grpc.CallOption) (pb.Mspm_UploadPackageStreamClient, error) {
+	return f.stream, nil
+}
+
+func makeUploadTree(t *testing.T) string {
+	root, err := ioutil.TempDir("./tempdir", "upload")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	err = os.MkdirAll(filepath.Join(root, "bin"), 0755)
+	if err != nil {
+		t.Fatalf("Failed to create bin: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(root, "bin", "start"), []byte("#!/bin/sh\n"), 0755)
+	if err != nil {
+		t.Fatalf("Failed to create bin/start: %v", err)
+	}
+
+	return root
+}
+
+func TestUploadFilesFromPaths(t *testing.T) {
+	root := makeUploadTree(t)
+	defer os.RemoveAll(root)
+
+	files, err := UploadFilesFromPaths(root, []string{"bin/start"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, saw %d", len(files))
+	}
+	if files[0].File.GetName() != "bin/" || files[0].Path != "" {
+		t.Errorf("Expected directory bin/ first, saw %v", files[0])
+	}
+	if files[1].File.GetName() != "bin/start" || files[1].Path == "" {
+		t.Errorf("Expected file bin/start second, saw %v", files[1])
+	}
+	if files[1].File.GetMode() != 0755 {
+		t.Errorf("Expected mode 0755, saw %o", files[1].File.GetMode())
+	}
+
+	for _, bad := range []string{"../start", "/bin/start", "."} {
+		_, err = UploadFilesFromPaths(root, []string{bad})
+		if err == nil {
+			t.Errorf("Expected an error for %s", bad)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	root := makeUploadTree(t)
+	defer os.RemoveAll(root)
+
+	files, err := UploadFilesFromPaths(root, []string{"bin/start"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fake := &fakeUploadServer{stream: &fakeUploadStream{}}
+	c := Client{client: fake}
+	info, err := c.Upload(context.Background(), "pkg", files, "stable")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.GetPackageName() != "pkg" {
+		t.Errorf("Expected package pkg, saw %s", info.GetPackageName())
+	}
+
+	sent := fake.stream.sent
+	if len(sent) != 4 {
+		t.Fatalf("Expected 4 messages, saw %d", len(sent))
+	}
+	if labels := sent[0].GetHeader().GetLabel(); len(labels) != 1 || labels[0] != "stable" {
+		t.Errorf("Expected label stable in header, saw %v", labels)
+	}
+	if sent[1].GetFile().GetName() != "bin/" {
+		t.Errorf("Expected bin/ announced, saw %v", sent[1])
+	}
+	if sent[2].GetFile().GetName() != "bin/start" {
+		t.Errorf("Expected bin/start announced, saw %v", sent[2])
+	}
+	if string(sent[3].GetData()) != "#!/bin/sh\n" {
+		t.Errorf("Unexpected data %q", sent[3].GetData())
+	}
+}
+
+func TestUploadFilesFromDirectory(t *testing.T) {
+	root := makeUploadTree(t)
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"bin-old", "bin/start.o", "README"} {
+		err := ioutil.WriteFile(filepath.Join(root, name), []byte(name), 0644)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "build"), 0755); err != nil {
+		t.Fatalf("Failed to create build: %v", err)
+	}
+
+	files, err := UploadFilesFromDirectory(root, []string{"*.o", "build"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	for _, uf := range files {
+		names = append(names, uf.File.GetName())
+	}
+	expected := []string{"README", "bin/", "bin/start", "bin-old"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v, saw %v", expected, names)
+	}
+	for ix := range expected {
+		if names[ix] != expected[ix] {
+			t.Errorf("Expected %s at %d, saw %s", expected[ix], ix, names[ix])
+		}
+	}
+
+	_, err = UploadFilesFromDirectory(root, []string{"["})
+	if err == nil {
+		t.Errorf("Expected an error for a bad pattern")
+	}
+}
+
+// The predicted version must be what the data store computes for the
+// same files, regardless of the order they are listed in.
+func TestPredictVersion(t *testing.T) {
+	root := makeUploadTree(t)
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "bin-old"), []byte("old"), 0600); err != nil {
+		t.Fatalf("Failed to create bin-old: %v", err)
+	}
+
+	files, err := UploadFilesFromDirectory(root, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reversed := make([]UploadFile, len(files))
+	for ix, uf := range files {
+		reversed[len(files)-1-ix] = uf
+	}
+	predicted, err := PredictVersion(reversed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("./tempdir", "store")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := data.NewDataStore(dir, dir)
+	if err != nil {
+		t.Fatalf("Failed to create data store: %v", err)
+	}
+	pv, err := ds.NewPackageVersion("pkg")
+	if err != nil {
+		t.Fatalf("Failed to create package version: %v", err)
+	}
+	for _, uf := range files {
+		if uf.Path == "" {
+			err = pv.AddDir(uf.File)
+		} else {
+			err = func() error {
+				out, err := pv.CreateFile(uf.File)
+				if err != nil {
+					return err
+				}
+				defer out.Close()
+				in, err := os.Open(uf.Path)
+				if err != nil {
+					return err
+				}
+				defer in.Close()
+				_, err = io.Copy(out, in)
+				return err
+			}()
+		}
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", uf.File.GetName(), err)
+		}
+	}
+	if err := pv.Finish(); err != nil {
+		t.Fatalf("Failed to finish package version: %v", err)
+	}
+
+	if pv.Version != predicted {
+		t.Errorf("Predicted %s, data store computed %s", predicted, pv.Version)
+	}
+}
@@ -0,0 +1,284 @@
+// Package tarballs, once finished, are kept in a BlobStore. The
+// data store only refers to them by key, the blob store decides where
+// they actually live.
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Returned (wrapped) when a blob does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// A BlobStore keeps blobs by key. Keys are plain names, without any
+// "/" in them. Put replaces any existing blob with the same key, and
+// the new blob is not visible until it is complete. Deleting a blob
+// that does not exist is not an error.
+type BlobStore interface {
+	Put(key string, r io.Reader, size int64) error
+	Get(key string) (io.ReadCloser, error)
+	Stat(key string) (BlobInfo, error)
+	Delete(key string) error
+	List() ([]BlobInfo, error)
+}
+
+// What a blob store knows about a blob.
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Check that a key is usable as a blob key.
+func checkBlobKey(key string) error {
+	if key == "" || strings.Contains(key, "/") || strings.HasPrefix(key, ".") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
+
+// A FileBlobStore keeps blobs as files in a local directory, named
+// by their keys. Files starting with a "." are not blobs.
+type FileBlobStore struct {
+	root string
+}
+
+// Create a blob store in a local directory, which must exist.
+func NewFileBlobStore(root string) *FileBlobStore {
+	return &FileBlobStore{root: root}
+}
+
+// Return the local path of a blob.
+func (fs *FileBlobStore) Path(key string) string {
+	return filepath.Join(fs.root, key)
+}
+
+// Store a blob, by writing it to a temporary file that is renamed
+// into place once complete.
+func (fs *FileBlobStore) Put(key string, r io.Reader, size int64) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(fs.root, ".blob-")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	n, err := io.Copy(tmp, r)
+	if err == nil && n != size {
+		err = fmt.Errorf("blob %s: wrote %d bytes, expected %d", key, n, size)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, fs.Path(key))
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"key":   key,
+		}).Error("FileBlobStore - storing blob")
+		os.Remove(tmpName)
+	}
+	return err
+}
+
+func (fs *FileBlobStore) Get(key string) (io.ReadCloser, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(fs.Path(key))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", key, ErrBlobNotFound)
+	}
+	return f, err
+}
+
+func (fs *FileBlobStore) Stat(key string) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+
+	fi, err := os.Stat(fs.Path(key))
+	if os.IsNotExist(err) {
+		return BlobInfo{}, fmt.Errorf("%s: %w", key, ErrBlobNotFound)
+	}
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Key: key, Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+func (fs *FileBlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(fs.Path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// List the blobs, sorted by key.
+func (fs *FileBlobStore) List() ([]BlobInfo, error) {
+	entries, err := ioutil.ReadDir(fs.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []BlobInfo
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		rv = append(rv, BlobInfo{Key: entry.Name(), Size: entry.Size(), ModTime: entry.ModTime()})
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Key < rv[j].Key })
+
+	return rv, nil
+}
+
+// Find the blob key for a tarball path, as recorded in the catalog.
+// Relative paths are keys already, and with a local blob store, paths
+// in its directory are too. Anything else is a tarball outside the
+// blob store.
+func (ds *DataStore) blobKey(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		return path, checkBlobKey(path) == nil
+	}
+	if fs, ok := ds.blobs.(*FileBlobStore); ok && filepath.Dir(path) == filepath.Clean(fs.root) {
+		return filepath.Base(path), true
+	}
+	return "", false
+}
+
+// Return the local path of a blob, if the blob store is local.
+func (ds *DataStore) blobPath(key string) string {
+	if fs, ok := ds.blobs.(*FileBlobStore); ok {
+		return fs.Path(key)
+	}
+	return ""
+}
+
+// Move a finished tarball from the playground into the blob store.
+func (ds *DataStore) storeTarball(pv *PackageVersion) error {
+	key := filepath.Base(pv.DataPath)
+	err := func() error {
+		in, err := os.Open(pv.DataPath)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		fi, err := in.Stat()
+		if err != nil {
+			return err
+		}
+		return ds.blobs.Put(key, in, fi.Size())
+	}()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(pv.DataPath); err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  pv.DataPath,
+		}).Warning("removing stored tarball from the playground")
+	}
+	pv.Blob = key
+	pv.DataPath = ds.blobPath(key)
+	return nil
+}
+
+// Open the tarball of a package version, wherever it is. Versions
+// stored as a manifest have their tarball rebuilt.
+func (ds *DataStore) openTarball(pv PackageVersion) (io.ReadCloser, error) {
+	if isManifest(pv.Blob) {
+		return ds.openManifestTarball(pv.Blob)
+	}
+	if pv.Blob != "" {
+		return ds.blobs.Get(pv.Blob)
+	}
+	return os.Open(pv.DataPath)
+}
+
+// Remove package tarballs. This is done once the catalog no longer
+// refers to them, so failing only leaves blobs behind. File contents
+// the removed manifests referred to are left for sweepRemoved.
+func (ds *DataStore) removeTarballs(pvs []PackageVersion) {
+	for _, pv := range pvs {
+		var err error
+		if pv.Blob != "" {
+			err = ds.blobs.Delete(pv.Blob)
+		} else {
+			err = os.Remove(pv.DataPath)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"name":    pv.Name,
+				"version": pv.Version,
+				"blob":    pv.Blob,
+				"path":    pv.DataPath,
+			}).Error("removing package tarball")
+		}
+	}
+
+}
+
+// Remove file contents only the removed versions referred to, if any
+// of them was stored as a manifest. Expects to be called without the
+// data store lock held.
+func (ds *DataStore) sweepRemoved(pvs []PackageVersion) {
+	for _, pv := range pvs {
+		if !isManifest(pv.Blob) {
+			continue
+		}
+		if err := ds.sweepFileBlobs(); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("sweeping file blobs")
+		}
+		return
+	}
+}
+
+// Return when the tarball of a package version was last modified, or
+// the zero time if that cannot be found.
+func (ds *DataStore) tarballTime(pv PackageVersion) time.Time {
+	if pv.Blob != "" {
+		if info, err := ds.blobs.Stat(pv.Blob); err == nil {
+			return info.ModTime.UTC()
+		}
+		return time.Time{}
+	}
+	if fi, err := os.Stat(pv.DataPath); err == nil {
+		return fi.ModTime().UTC()
+	}
+	return time.Time{}
+}
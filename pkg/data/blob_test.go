@@ -0,0 +1,91 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Exercise a blob store, which should start out empty.
+func checkBlobStore(t *testing.T, bs BlobStore) {
+	blobs := map[string]string{
+		"foo-beef.tgz": "beef",
+		"foo-f00d.tgz": "f00d f00d",
+		"bar cafe.tgz": "cafe",
+	}
+	for key, contents := range blobs {
+		if err := bs.Put(key, strings.NewReader(contents), int64(len(contents))); err != nil {
+			t.Fatalf("Unexpected error storing %s: %v", key, err)
+		}
+	}
+	if err := bs.Put("../escape", strings.NewReader("x"), 1); err == nil {
+		t.Errorf("Expected an error storing a key with a /")
+	}
+
+	for key, contents := range blobs {
+		in, err := bs.Get(key)
+		if err != nil {
+			t.Errorf("Unexpected error getting %s: %v", key, err)
+			continue
+		}
+		seen, _ := ioutil.ReadAll(in)
+		in.Close()
+		if string(seen) != contents {
+			t.Errorf("Blob %s, expected %q, saw %q", key, contents, seen)
+		}
+		info, err := bs.Stat(key)
+		if err != nil || info.Size != int64(len(contents)) || info.ModTime.IsZero() {
+			t.Errorf("Blob %s, unexpected info %v (%v)", key, info, err)
+		}
+	}
+
+	list, err := bs.List()
+	if err != nil {
+		t.Fatalf("Unexpected error listing: %v", err)
+	}
+	var keys []string
+	for _, info := range list {
+		keys = append(keys, info.Key)
+	}
+	if expected := []string{"bar cafe.tgz", "foo-beef.tgz", "foo-f00d.tgz"}; !sameStrings(keys, expected) {
+		t.Errorf("Expected %v, saw %v", expected, keys)
+	}
+
+	// Replacing a blob.
+	if err := bs.Put("foo-beef.tgz", bytes.NewReader([]byte("new")), 3); err != nil {
+		t.Errorf("Unexpected error replacing: %v", err)
+	}
+	if info, _ := bs.Stat("foo-beef.tgz"); info.Size != 3 {
+		t.Errorf("Expected the replaced blob to be 3 bytes, saw %d", info.Size)
+	}
+
+	if err := bs.Delete("foo-beef.tgz"); err != nil {
+		t.Errorf("Unexpected error deleting: %v", err)
+	}
+	if err := bs.Delete("foo-beef.tgz"); err != nil {
+		t.Errorf("Unexpected error deleting again: %v", err)
+	}
+	if _, err := bs.Get("foo-beef.tgz"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound getting a deleted blob, saw %v", err)
+	}
+	if _, err := bs.Stat("foo-beef.tgz"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Expected ErrBlobNotFound for a deleted blob, saw %v", err)
+	}
+}
+
+func TestFileBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-blobs")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Files starting with a dot, like temporary files, are not blobs.
+	if err := ioutil.WriteFile(dir+"/.blob-1234", []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to write temporary file: %v", err)
+	}
+	checkBlobStore(t, NewFileBlobStore(dir))
+}
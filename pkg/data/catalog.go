@@ -0,0 +1,347 @@
+// The catalog is the on-disk record of what the DataStore knows
+// about. It is re-written in its entirety whenever a package version
+// is added or deleted or a label moves, and read back when a DataStore
+// is created.
+package data
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const catalogName = "catalog.json"
+
+type catalog struct {
+	Packages  []catalogPackage `json:"packages"`
+	Deletions []Deletion       `json:"deletions,omitempty"`
+}
+
+type catalogPackage struct {
+	Name          string           `json:"name"`
+	Versions      []catalogVersion `json:"versions"`
+	DisableLatest bool             `json:"disableLatest,omitempty"`
+	History       []LabelEvent     `json:"history,omitempty"`
+}
+
+type catalogVersion struct {
+	Version string   `json:"version"`
+	Labels  []string `json:"labels,omitempty"`
+	// The blob key of the package tarball or, for tarballs outside
+	// the blob store, the absolute path to it. Keys are also the
+	// paths of tarballs relative to the store directory, when the
+	// blobs are kept there.
+	DataPath   string    `json:"dataPath"`
+	Size       int64     `json:"size,omitempty"`
+	Checksum   string    `json:"checksum,omitempty"`
+	UploadedBy string    `json:"uploadedBy,omitempty"`
+	Created    time.Time `json:"created"`
+}
+
+// Return the path of the catalog file for the data store.
+func (ds *DataStore) catalogPath() string {
+	return filepath.Join(ds.store, catalogName)
+}
+
+// Build a catalog from the current in-memory state. Expects to be
+// called with the data store lock held.
+func (ds *DataStore) buildCatalog() catalog {
+	var rv catalog
+
+	var names []string
+	for name := range ds.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := ds.packages[name]
+		rv.Packages = append(rv.Packages, p.catalogEntry())
+	}
+	rv.Deletions = ds.deletions
+
+	return rv
+}
+
+// Return the catalog entry for a package.
+func (p *Package) catalogEntry() catalogPackage {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	rv := catalogPackage{Name: p.name, DisableLatest: p.options.DisableLatest}
+
+	var versions []string
+	for version := range p.versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		pv := p.versions[version]
+		labels := pv.GetAllLabels()
+		sort.Strings(labels)
+		dataPath := pv.DataPath
+		if pv.Blob != "" {
+			dataPath = pv.Blob
+		}
+		rv.Versions = append(rv.Versions, catalogVersion{
+			Version:    version,
+			Labels:     labels,
+			DataPath:   dataPath,
+			Size:       pv.Size,
+			Checksum:   pv.Checksum,
+			UploadedBy: pv.UploadedBy,
+			Created:    pv.Created,
+		})
+	}
+
+	var labels []string
+	for label := range p.history {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		rv.History = append(rv.History, p.history[label]...)
+	}
+
+	return rv
+}
+
+// Write the catalog to disk. Expects to be called with the data
+// store lock held.
+func (ds *DataStore) saveCatalog() error {
+	data, err := json.MarshalIndent(ds.buildCatalog(), "", "  ")
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("marshalling catalog")
+		return err
+	}
+
+	err = writeFileAtomic(ds.catalogPath(), data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  ds.catalogPath(),
+		}).Error("writing catalog")
+	}
+	return err
+}
+
+// What the data store looked like before a change, so the change can
+// be undone if the catalog can not be saved. Only the packages named
+// when the checkpoint is taken can be restored.
+type checkpoint struct {
+	packages  map[string]*Package
+	deletions []Deletion
+	states    map[*Package]packageState
+}
+
+// The parts of a package a change can modify.
+type packageState struct {
+	versions map[string]*PackageVersion
+	labels   map[string]*PackageVersion
+	history  map[string][]LabelEvent
+	options  PackageOptions
+	// The labels of each version, which are changed in place.
+	versionLabels map[*PackageVersion]map[string]struct{}
+}
+
+// Take a checkpoint of the data store, and of the named packages.
+// Expects to be called with the data store lock held, and none of the
+// package locks.
+func (ds *DataStore) checkpoint(names ...string) *checkpoint {
+	cp := &checkpoint{
+		packages:  make(map[string]*Package, len(ds.packages)),
+		deletions: ds.deletions,
+		states:    make(map[*Package]packageState),
+	}
+	for name, p := range ds.packages {
+		cp.packages[name] = p
+	}
+	for _, name := range names {
+		if p, ok := ds.packages[name]; ok {
+			if _, seen := cp.states[p]; !seen {
+				cp.states[p] = p.state()
+			}
+		}
+	}
+	return cp
+}
+
+// Return a copy of the state of a package.
+func (p *Package) state() packageState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	rv := packageState{
+		versions:      make(map[string]*PackageVersion, len(p.versions)),
+		labels:        make(map[string]*PackageVersion, len(p.labels)),
+		history:       make(map[string][]LabelEvent, len(p.history)),
+		options:       p.options,
+		versionLabels: make(map[*PackageVersion]map[string]struct{}, len(p.versions)),
+	}
+	for version, pv := range p.versions {
+		rv.versions[version] = pv
+		labels := make(map[string]struct{}, len(pv.Labels))
+		for label := range pv.Labels {
+			labels[label] = struct{}{}
+		}
+		rv.versionLabels[pv] = labels
+	}
+	for label, pv := range p.labels {
+		rv.labels[label] = pv
+	}
+	for label, events := range p.history {
+		rv.history[label] = events
+	}
+	return rv
+}
+
+// Put the data store back the way it was when the checkpoint was
+// taken. Expects to be called with the data store lock held, and none
+// of the package locks.
+func (ds *DataStore) rollback(cp *checkpoint) {
+	ds.packages = cp.packages
+	ds.deletions = cp.deletions
+	for p, st := range cp.states {
+		p.lock.Lock()
+		p.versions = st.versions
+		p.labels = st.labels
+		p.history = st.history
+		p.options = st.options
+		for pv, labels := range st.versionLabels {
+			pv.Labels = labels
+		}
+		p.lock.Unlock()
+	}
+}
+
+// Save the catalog after a change, undoing the change if that fails,
+// so what is in memory always matches what is on disk. Expects to be
+// called with the data store lock held, and none of the package
+// locks.
+func (ds *DataStore) commitCatalog(cp *checkpoint) error {
+	err := ds.saveCatalog()
+	if err != nil {
+		ds.rollback(cp)
+	}
+	return err
+}
+
+// Read the catalog from disk and populate the data store from
+// it. A missing catalog is not an error, it simply means we're
+// starting from scratch.
+func (ds *DataStore) loadCatalog() error {
+	data, err := ioutil.ReadFile(ds.catalogPath())
+	if os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"path": ds.catalogPath(),
+		}).Info("no catalog found, starting empty")
+		return nil
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  ds.catalogPath(),
+		}).Error("reading catalog")
+		return err
+	}
+
+	var c catalog
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  ds.catalogPath(),
+		}).Error("parsing catalog")
+		return err
+	}
+
+	for _, cp := range c.Packages {
+		p := newPackage(cp.Name)
+		p.options.DisableLatest = cp.DisableLatest
+		for _, cv := range cp.Versions {
+			pv := &PackageVersion{
+				Name:       cp.Name,
+				Version:    cv.Version,
+				Labels:     make(map[string]struct{}),
+				DataPath:   cv.DataPath,
+				Size:       cv.Size,
+				Checksum:   cv.Checksum,
+				UploadedBy: cv.UploadedBy,
+				Created:    cv.Created,
+			}
+			if key, ok := ds.blobKey(cv.DataPath); ok {
+				pv.Blob = key
+				pv.DataPath = ds.blobPath(key)
+			}
+			if pv.Created.IsZero() {
+				// Catalogs from before versions had a
+				// creation time; the tarball is the
+				// best guess there is.
+				pv.Created = ds.tarballTime(*pv)
+			}
+			for _, label := range cv.Labels {
+				pv.Labels[label] = struct{}{}
+				p.labels[label] = pv
+			}
+			p.versions[cv.Version] = pv
+		}
+		for _, event := range cp.History {
+			p.history[event.Label] = append(p.history[event.Label], event)
+		}
+		ds.packages[cp.Name] = p
+	}
+	ds.deletions = c.Deletions
+
+	log.WithFields(log.Fields{
+		"path":     ds.catalogPath(),
+		"packages": len(ds.packages),
+	}).Debug("catalog loaded")
+	return nil
+}
+
+// Write data to a file in such a way that a reader will either see
+// the old contents or the new contents, never a partial write. We do
+// this by writing to a temporary file in the same directory, syncing
+// it, then renaming it over the target.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	// Make sure the rename itself is durable.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
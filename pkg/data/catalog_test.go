@@ -0,0 +1,111 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*DataStore, string) {
+	dir, err := ioutil.TempDir("", "mspm-catalog")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	for _, sub := range []string{"playground", "store"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", sub, err)
+		}
+	}
+
+	ds, err := NewDataStore(filepath.Join(dir, "playground"), filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatalf("Failed to create data store: %v", err)
+	}
+
+	return ds, dir
+}
+
+func TestCatalogRoundTrip(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	pv1 := newPackageVersion("foo", "beef")
+	pv1.DataPath = filepath.Join(ds.store, "foo-beef.tgz")
+	pv2 := newPackageVersion("foo", "f00d")
+	pv2.DataPath = filepath.Join(ds.store, "foo-f00d.tgz")
+
+	if err := ds.AddPackageVersion(pv1); err != nil {
+		t.Fatalf("Unexpected error adding pv1: %v", err)
+	}
+	if err := ds.AddPackageVersion(pv2); err != nil {
+		t.Fatalf("Unexpected error adding pv2: %v", err)
+	}
+	if err := ds.SetLabel("foo", "beef", "prod"); err != nil {
+		t.Fatalf("Unexpected error setting label: %v", err)
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+
+	testcases := []struct {
+		designator string
+		version    string
+	}{
+		{"beef", "beef"}, {"f00d", "f00d"}, {"latest", "f00d"}, {"prod", "beef"},
+	}
+
+	for ix, tc := range testcases {
+		pv, err := reloaded.GetPackageVersion("foo", tc.designator)
+		if err != nil {
+			t.Errorf("Case #%d, unexpected error %v", ix, err)
+			continue
+		}
+		if pv.Version != tc.version {
+			t.Errorf("Case #%d, saw version %s, want %s", ix, pv.Version, tc.version)
+		}
+		want := filepath.Join(ds.store, "foo-"+tc.version+".tgz")
+		if pv.DataPath != want {
+			t.Errorf("Case #%d, saw path %s, want %s", ix, pv.DataPath, want)
+		}
+	}
+}
+
+func TestCatalogIgnoresStaleTempFile(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	if err := ds.AddPackageVersion(newPackageVersion("foo", "beef")); err != nil {
+		t.Fatalf("Unexpected error adding version: %v", err)
+	}
+
+	// Simulate a crash half-way through writing a new catalog.
+	stale := filepath.Join(ds.store, ".catalog.json-12345")
+	if err := ioutil.WriteFile(stale, []byte(`{"packages": [{"na`), 0644); err != nil {
+		t.Fatalf("Failed to write stale file: %v", err)
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if _, ok := reloaded.GetPackageVersions("foo"); !ok {
+		t.Errorf("Expected package foo to survive reload")
+	}
+}
+
+func TestCatalogCorrupt(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(ds.catalogPath(), []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write catalog: %v", err)
+	}
+
+	if _, err := NewDataStore(ds.playground, ds.store); err == nil {
+		t.Errorf("Expected error loading corrupt catalog, saw none")
+	}
+}
@@ -2,30 +2,52 @@
 package data
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Returned (wrapped) when adding a version a package already has.
+var ErrVersionExists = errors.New("version already exists")
+
 // Represents a general MSPM package (that is, all versions and labels).
 type Package struct {
 	lock     sync.Mutex
 	name     string
 	versions map[string]*PackageVersion
 	labels   map[string]*PackageVersion
+	options  PackageOptions
+	history  map[string][]LabelEvent
 }
 
 // Data for a specific version of a package.
 type PackageVersion struct {
-	Name     string
-	Version  string
-	Labels   map[string]struct{}
+	Name    string
+	Version string
+	Labels  map[string]struct{}
+	// Where the files, or the tarball, are on local disk. Once
+	// the version is in the blob store, Blob is the key of its
+	// manifest (or tarball), and DataPath is only set if the blob
+	// store is local.
 	DataPath string
-	fileMap  map[string]fileInfo
+	Blob     string
+	// Size and SHA-512 checksum (in hex) of the package tarball.
+	Size     int64
+	Checksum string
+	// Who uploaded this version, if known.
+	UploadedBy string
+	// When the version was added to the data store.
+	Created time.Time
+	fileMap map[string]fileInfo
+	// The group of each file, kept for the manifest. It is not part
+	// of the version hash.
+	groups map[string]string
+	// Set by Finish, until the files are in the blob store.
+	manifest *Manifest
 }
 
 type fileInfo struct {
@@ -38,6 +60,12 @@ type DataStore struct {
 	playground string
 	store      string
 	packages   map[string]*Package
+	sessions   map[string]*UploadSession
+	protected  map[string]bool
+	deletions  []Deletion
+	blobs      BlobStore
+	pending    pendingBlobs
+	sweepLock  sync.Mutex
 }
 
 // Set the label newLabel on the package-version designated by
@@ -49,26 +77,15 @@ func (p *Package) SetLabel(designator, newLabel string) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	return p.setLabel(designator, newLabel)
+	return p.setLabel(designator, newLabel, "")
 }
 
 // Set a label on the designated version of a package. If that label
-// is attached to another version, make sure it is removed before we start.
+// is attached to another version, make sure it is removed before we
+// start, unless it is a protected label, in which case this fails
+// (see ChangeLabel for forcing it).
 func (ds *DataStore) SetLabel(pkgname, designator, newLabel string) error {
-	ds.lock.Lock()
-	defer ds.lock.Unlock()
-
-	p, ok := ds.packages[pkgname]
-	if !ok {
-		log.WithFields(log.Fields{
-			"name":       pkgname,
-			"designator": designator,
-			"newLabel":   newLabel,
-		}).Error("package not found")
-		return fmt.Errorf("package %s not found in data store", pkgname)
-	}
-
-	return p.SetLabel(designator, newLabel)
+	return ds.ChangeLabel(LabelChange{Package: pkgname, Designator: designator, Label: newLabel})
 }
 
 // Return the PackageVersion that corresponds to the requested
@@ -81,6 +98,9 @@ func (p *Package) GetVersion(designator string) (PackageVersion, bool) {
 	defer p.lock.Unlock()
 
 	pv, ok := p.getVersion(designator)
+	if !ok {
+		return PackageVersion{}, false
+	}
 	return *pv, ok
 }
 
@@ -97,14 +117,18 @@ func (p *Package) getVersion(designator string) (*PackageVersion, bool) {
 
 // Internal version of SetLabel, that doesn't perform any
 // locking. This means it's safe to call from (and only from)
-// functions that already hold the lock for a package.
-func (p *Package) setLabel(designator, newLabel string) error {
+// functions that already hold the lock for a package. If the label
+// moves, this is recorded in the label history, as done by identity.
+func (p *Package) setLabel(designator, newLabel, identity string) error {
 	target, ok := p.getVersion(designator)
 	if !ok {
 		return fmt.Errorf("No package-version designated by %s", designator)
 	}
 
 	old, ok := p.labels[newLabel]
+	if old != target {
+		p.recordLabelEvent(newLabel, old, target, identity)
+	}
 	if ok && old != target {
 		// There is a package that has this label, let us
 		// immediately get rid of it, so we do not have any
@@ -122,7 +146,8 @@ func (p *Package) setLabel(designator, newLabel string) error {
 }
 
 // Add a specific version of a Package. This will move the label
-// "latest" to point at the new addition.
+// "latest" to point at the new addition, unless the package has
+// DisableLatest set. Protected or not, "latest" is always moved.
 func (p *Package) AddVersion(pv PackageVersion) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -131,10 +156,13 @@ func (p *Package) AddVersion(pv PackageVersion) error {
 	_, ok := p.versions[version]
 	if ok {
 		// Never modify a package...
-		return fmt.Errorf("Package %s already has a version %s", pv.Name, version)
+		return fmt.Errorf("Package %s already has a version %s: %w", pv.Name, version, ErrVersionExists)
 	}
 	p.versions[version] = &pv
-	return p.setLabel(version, "latest")
+	if p.options.DisableLatest {
+		return nil
+	}
+	return p.setLabel(version, "latest", pv.UploadedBy)
 }
 
 func newPackage(name string) *Package {
@@ -142,55 +170,162 @@ func newPackage(name string) *Package {
 	p.name = name
 	p.versions = make(map[string]*PackageVersion)
 	p.labels = make(map[string]*PackageVersion)
+	p.history = make(map[string][]LabelEvent)
 
 	return p
 }
 
-// Create a new data store with a specific playground and storage location.
-func NewDataStore(playground, store string) *DataStore {
+// Create a new data store with a specific playground and storage
+// location. If there is a catalog in the storage location, the data
+// store is populated from it. Package tarballs are kept in the
+// storage location too.
+func NewDataStore(playground, store string) (*DataStore, error) {
+	return NewDataStoreWithBlobStore(playground, store, NewFileBlobStore(store))
+}
+
+// Create a new data store like NewDataStore, but keeping package
+// tarballs in a blob store of its own. The catalog is still kept in
+// the storage location.
+func NewDataStoreWithBlobStore(playground, store string, blobs BlobStore) (*DataStore, error) {
 	ds := new(DataStore)
 	ds.playground = playground
 	ds.store = store
+	ds.blobs = blobs
 	ds.packages = make(map[string]*Package)
+	ds.sessions = make(map[string]*UploadSession)
+	ds.protected = make(map[string]bool)
+
+	err := ds.loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	err = ds.loadUploadSessions()
+	if err != nil {
+		return nil, err
+	}
 
-	return ds
+	return ds, nil
 }
 
 // Add a PackageVersion to the data store. As we already have the
 // package name and version detail(s), we don't take them as extra
 // parameters. If we happen to already have the specific version
-// stored, we do nothing.
-func (ds *DataStore) AddPackageVersion(pv PackageVersion) {
+// stored, we leave things as they are and return an error. The
+// catalog is updated before we return.
+func (ds *DataStore) AddPackageVersion(pv PackageVersion) error {
+	return ds.AddLabelledPackageVersion(pv, nil)
+}
+
+// Add a PackageVersion to the data store, like AddPackageVersion, and
+// put labels on it in the same step. The Designator of each change is
+// ignored, they all go on the new version. The labels are checked
+// before anything is stored, so if one of them can not be set, the
+// version is not added either.
+func (ds *DataStore) AddLabelledPackageVersion(pv PackageVersion, labels []LabelChange) error {
+	// File contents can take a while to store, so that is done
+	// before taking the lock. They are kept safe from garbage
+	// collection until the manifest is in the catalog.
+	if pv.manifest != nil {
+		keys, err := ds.storeFileBlobs(&pv)
+		defer ds.pending.release(keys)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"path":    pv.DataPath,
+				"name":    pv.Name,
+				"version": pv.Version,
+			}).Error("storing PackageVersion files")
+			return err
+		}
+	}
+
 	ds.lock.Lock()
 	defer ds.lock.Unlock()
 
 	p, ok := ds.packages[pv.Name]
+	if ok {
+		if _, ok := p.GetVersion(pv.Version); ok {
+			return fmt.Errorf("Package %s already has a version %s: %w", pv.Name, pv.Version, ErrVersionExists)
+		}
+		if err := ds.checkNewVersionLabels(p, labels); err != nil {
+			return err
+		}
+	}
 
-	if !ok {
+	// A finished version has its file contents in the blob store
+	// by now, and its manifest needs to go there, as does a
+	// tarball in the playground.
+	if pv.manifest != nil {
+		err := ds.storeManifest(&pv)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"path":    pv.DataPath,
+				"name":    pv.Name,
+				"version": pv.Version,
+			}).Error("storing PackageVersion manifest")
+			return err
+		}
+	} else if strings.HasPrefix(pv.DataPath, ds.playground) {
+		err := ds.storeTarball(&pv)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"path":    pv.DataPath,
+				"name":    pv.Name,
+				"version": pv.Version,
+			}).Error("storing PackageVersion tarball")
+			return err
+		}
+	} else if key, ok := ds.blobKey(pv.DataPath); ok && pv.Blob == "" {
+		pv.Blob = key
+		pv.DataPath = ds.blobPath(key)
+	}
+
+	if pv.Created.IsZero() {
+		pv.Created = time.Now().UTC()
+	}
+
+	cp := ds.checkpoint(pv.Name)
+	if p == nil {
 		// No previous version of this package added, make it so
 		p = newPackage(pv.Name)
 		ds.packages[pv.Name] = p
 	}
 
-	// The package tarball is probably in the playground.
-	tarball := pv.DataPath
-	if strings.HasPrefix(tarball, ds.playground) {
-		fname := filepath.Base(tarball)
-		newName := filepath.Join(ds.store, fname)
-		err := os.Rename(tarball, newName)
+	err := p.AddVersion(pv)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Warning("adding PackageVersion")
+		ds.rollback(cp)
+		return err
+	}
+
+	if len(labels) > 0 {
+		changes := make([]LabelChange, len(labels))
+		for ix, lc := range labels {
+			lc.Package = pv.Name
+			lc.Designator = pv.Version
+			changes[ix] = lc
+		}
+		_, err = ds.applyLabelChanges(changes)
 		if err != nil {
+			// We checked the labels above, so this should
+			// never happen.
 			log.WithFields(log.Fields{
 				"error":   err,
-				"fname":   fname,
 				"name":    pv.Name,
 				"version": pv.Version,
-			}).Warning("renaming PackageVersion tarball")
-		} else {
-			pv.DataPath = newName
+			}).Error("labelling new PackageVersion")
+			ds.rollback(cp)
+			return err
 		}
 	}
 
-	p.AddVersion(pv)
+	return ds.commitCatalog(cp)
 }
 
 // Return all PackageVersions available for a specific Package. This
@@ -0,0 +1,196 @@
+// Deleting versions, and whole packages, from the data store. Every
+// deletion is recorded in the catalog, so there is a trace of what
+// used to be there even once the package itself is gone.
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Returned (wrapped) when deleting a package or version that does
+// not exist.
+var ErrNotFound = errors.New("not found")
+
+// A deleted version, or a deleted package if Version is empty.
+type Deletion struct {
+	Time     time.Time `json:"time"`
+	Package  string    `json:"package"`
+	Version  string    `json:"version,omitempty"`
+	Identity string    `json:"identity,omitempty"`
+}
+
+// Return the protected labels on a version, sorted. Expects to be
+// called with the data store lock held.
+func (ds *DataStore) protectedLabels(pv *PackageVersion) []string {
+	var rv []string
+	for label := range pv.Labels {
+		if ds.protected[label] {
+			rv = append(rv, label)
+		}
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+// Remove a version from a package, along with its labels, recording
+// the labels as removed. If the version was the latest one, "latest"
+// moves to the newest version left, unless the package has
+// DisableLatest set. Expects to be called with the package lock held.
+func (p *Package) removeVersion(pv *PackageVersion, identity string) {
+	_, wasLatest := pv.Labels["latest"]
+	for _, label := range pv.GetAllLabels() {
+		p.recordLabelEvent(label, pv, nil, identity)
+		delete(p.labels, label)
+	}
+	delete(p.versions, pv.Version)
+
+	if wasLatest && !p.options.DisableLatest {
+		if newest := p.newestVersion(); newest != nil {
+			p.setLabel(newest.Version, "latest", identity)
+		}
+	}
+}
+
+// Return the newest version of a package, by creation time, or nil if
+// there are no versions. Expects to be called with the package lock
+// held.
+func (p *Package) newestVersion() *PackageVersion {
+	var rv *PackageVersion
+	for _, pv := range p.versions {
+		if rv == nil || pv.Created.After(rv.Created) || (pv.Created.Equal(rv.Created) && pv.Version < rv.Version) {
+			rv = pv
+		}
+	}
+	return rv
+}
+
+// Add a deletion to the ones recorded in the catalog. Expects to be
+// called with the data store lock held.
+func (ds *DataStore) recordDeletion(pkg, version, identity string) {
+	ds.deletions = append(ds.deletions, Deletion{Time: time.Now().UTC(), Package: pkg, Version: version, Identity: identity})
+}
+
+// Delete a version of a package. The version has to be given as-is,
+// not by label. A version carrying protected labels is only deleted
+// if force is set. The labels on it are removed, which shows up in
+// their history as done by identity. Deleting the last version of a
+// package removes the package.
+func (ds *DataStore) DeleteVersion(pkg, version, identity string, force bool) (PackageVersion, error) {
+	rv, err := ds.deleteVersion(pkg, version, identity, force)
+	if err == nil {
+		ds.sweepRemoved([]PackageVersion{rv})
+	}
+	return rv, err
+}
+
+// Delete a version of a package, as DeleteVersion, leaving the file
+// contents for the caller to sweep.
+func (ds *DataStore) deleteVersion(pkg, version, identity string, force bool) (PackageVersion, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	p, ok := ds.packages[pkg]
+	if !ok {
+		return PackageVersion{}, fmt.Errorf("package %s: %w", pkg, ErrNotFound)
+	}
+
+	p.lock.Lock()
+	pv, ok := p.versions[version]
+	if !ok {
+		p.lock.Unlock()
+		return PackageVersion{}, fmt.Errorf("package %s version %s: %w", pkg, version, ErrNotFound)
+	}
+	if protected := ds.protectedLabels(pv); len(protected) > 0 && !force {
+		p.lock.Unlock()
+		return PackageVersion{}, fmt.Errorf("package %s version %s has %v: %w", pkg, version, protected, ErrLabelProtected)
+	}
+	rv := *pv
+	p.lock.Unlock()
+
+	cp := ds.checkpoint(pkg)
+	p.lock.Lock()
+	p.removeVersion(pv, identity)
+	empty := len(p.versions) == 0
+	p.lock.Unlock()
+	if empty {
+		delete(ds.packages, pkg)
+	}
+	ds.recordDeletion(pkg, version, identity)
+
+	err := ds.commitCatalog(cp)
+	if err != nil {
+		return PackageVersion{}, err
+	}
+	ds.removeTarballs([]PackageVersion{rv})
+
+	log.WithFields(log.Fields{
+		"name":     pkg,
+		"version":  version,
+		"identity": identity,
+	}).Info("deleted package version")
+	return rv, nil
+}
+
+// Delete a package, with all its versions, labels and history. If
+// any version carries protected labels, this needs force. The deleted
+// versions are returned.
+func (ds *DataStore) DeletePackage(pkg, identity string, force bool) ([]PackageVersion, error) {
+	rv, err := ds.deletePackage(pkg, identity, force)
+	if err == nil {
+		ds.sweepRemoved(rv)
+	}
+	return rv, err
+}
+
+// Delete a package, as DeletePackage, leaving the file contents for
+// the caller to sweep.
+func (ds *DataStore) deletePackage(pkg, identity string, force bool) ([]PackageVersion, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	p, ok := ds.packages[pkg]
+	if !ok {
+		return nil, fmt.Errorf("package %s: %w", pkg, ErrNotFound)
+	}
+
+	p.lock.Lock()
+	var rv []PackageVersion
+	for _, pv := range p.versions {
+		if protected := ds.protectedLabels(pv); len(protected) > 0 && !force {
+			p.lock.Unlock()
+			return nil, fmt.Errorf("package %s version %s has %v: %w", pkg, pv.Version, protected, ErrLabelProtected)
+		}
+		rv = append(rv, *pv)
+	}
+	p.lock.Unlock()
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Version < rv[j].Version })
+
+	cp := ds.checkpoint()
+	delete(ds.packages, pkg)
+	ds.recordDeletion(pkg, "", identity)
+	err := ds.commitCatalog(cp)
+	if err != nil {
+		return nil, err
+	}
+	ds.removeTarballs(rv)
+
+	log.WithFields(log.Fields{
+		"name":     pkg,
+		"versions": len(rv),
+		"identity": identity,
+	}).Info("deleted package")
+	return rv, nil
+}
+
+// Return the deletions recorded in the catalog, oldest first.
+func (ds *DataStore) Deletions() []Deletion {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	return append([]Deletion{}, ds.deletions...)
+}
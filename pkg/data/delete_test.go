@@ -0,0 +1,230 @@
+package data
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Give the versions of foo tarballs, so there is something to remove.
+func writeTarballs(t *testing.T, ds *DataStore) {
+	pvs, _ := ds.GetPackageVersions("foo")
+	for _, pv := range pvs {
+		if err := ioutil.WriteFile(pv.DataPath, []byte(pv.Version), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", pv.DataPath, err)
+		}
+	}
+}
+
+func TestDeleteVersion(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+	writeTarballs(t, ds)
+	ds.SetProtectedLabels([]string{"prod"})
+	ds.ChangeLabel(LabelChange{Package: "foo", Designator: "beef", Label: "prod"})
+
+	if _, err := ds.DeleteVersion("foo", "prod", "alice", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting by label, saw %v", err)
+	}
+	if _, err := ds.DeleteVersion("bar", "beef", "alice", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting from a missing package, saw %v", err)
+	}
+	if _, err := ds.DeleteVersion("foo", "beef", "alice", false); !errors.Is(err, ErrLabelProtected) {
+		t.Fatalf("Expected ErrLabelProtected, saw %v", err)
+	}
+
+	pv, err := ds.DeleteVersion("foo", "beef", "alice", true)
+	if err != nil {
+		t.Fatalf("Unexpected error forcing delete: %v", err)
+	}
+	if _, err := os.Stat(pv.DataPath); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, saw %v", pv.DataPath, err)
+	}
+	if _, err := ds.GetPackageVersion("foo", "prod"); err == nil {
+		t.Errorf("Expected prod to be gone with beef")
+	}
+	if labelled(t, ds, "latest") != "f00d" {
+		t.Errorf("Expected latest to stay on f00d")
+	}
+
+	events, _ := ds.LabelHistory("foo", "prod")
+	if last := events[len(events)-1]; last.Old != "beef" || last.New != "" || last.Identity != "alice" {
+		t.Errorf("Expected the removal of prod in its history, saw %v", last)
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	pvs, _ := reloaded.GetPackageVersions("foo")
+	if len(pvs) != 1 || pvs[0].Version != "f00d" {
+		t.Errorf("Expected only f00d after reloading, saw %v", pvs)
+	}
+	deletions := reloaded.Deletions()
+	if len(deletions) != 1 || deletions[0].Package != "foo" || deletions[0].Version != "beef" || deletions[0].Identity != "alice" {
+		t.Errorf("Expected the deletion of beef in the catalog, saw %v", deletions)
+	}
+}
+
+func TestDeletePackage(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+	writeTarballs(t, ds)
+	ds.SetProtectedLabels([]string{"prod"})
+	ds.ChangeLabel(LabelChange{Package: "foo", Designator: "f00d", Label: "prod"})
+
+	if _, err := ds.DeletePackage("foo", "bob", false); !errors.Is(err, ErrLabelProtected) {
+		t.Fatalf("Expected ErrLabelProtected, saw %v", err)
+	}
+	pvs, err := ds.DeletePackage("foo", "bob", true)
+	if err != nil {
+		t.Fatalf("Unexpected error forcing delete: %v", err)
+	}
+	if len(pvs) != 2 || pvs[0].Version != "beef" || pvs[1].Version != "f00d" {
+		t.Errorf("Expected beef and f00d deleted, saw %v", pvs)
+	}
+	for _, pv := range pvs {
+		if _, err := os.Stat(pv.DataPath); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, saw %v", pv.DataPath, err)
+		}
+	}
+	if _, ok := ds.GetPackageVersions("foo"); ok {
+		t.Errorf("Expected foo to be gone")
+	}
+	if _, err := ds.DeletePackage("foo", "bob", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting foo again, saw %v", err)
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if _, ok := reloaded.GetPackageVersions("foo"); ok {
+		t.Errorf("Expected foo to stay gone after reloading")
+	}
+	if deletions := reloaded.Deletions(); len(deletions) != 1 || deletions[0].Version != "" {
+		t.Errorf("Expected the deletion of foo in the catalog, saw %v", deletions)
+	}
+}
+
+func TestDeleteLatestVersion(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	// Versions a (oldest) to c, so c is latest.
+	now := time.Now().UTC()
+	for ix, version := range []string{"a", "b", "c"} {
+		pv := newPackageVersion("foo", version)
+		pv.DataPath = filepath.Join(ds.store, "foo-"+version+".tgz")
+		pv.Created = now.Add(time.Duration(ix) * time.Hour)
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding %s: %v", version, err)
+		}
+	}
+	// Labelling an older version latest must not make it the
+	// newest one.
+	ds.SetLabel("foo", "a", "latest")
+
+	if _, err := ds.DeleteVersion("foo", "a", "alice", false); err != nil {
+		t.Fatalf("Unexpected error deleting a: %v", err)
+	}
+	if v := labelled(t, ds, "latest"); v != "c" {
+		t.Errorf("Expected latest to move to c, saw %s", v)
+	}
+	events, _ := ds.LabelHistory("foo", "latest")
+	if last := events[len(events)-1]; last.New != "c" || last.Identity != "alice" {
+		t.Errorf("Expected the move of latest in its history, saw %v", last)
+	}
+
+	for _, version := range []string{"c", "b"} {
+		if _, err := ds.DeleteVersion("foo", version, "alice", false); err != nil {
+			t.Fatalf("Unexpected error deleting %s: %v", version, err)
+		}
+	}
+	if _, ok := ds.GetPackageVersions("foo"); ok {
+		t.Errorf("Expected foo to be gone with its last version")
+	}
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if _, ok := reloaded.GetPackageVersions("foo"); ok {
+		t.Errorf("Expected foo to be gone from the catalog")
+	}
+	if deletions := reloaded.Deletions(); len(deletions) != 3 {
+		t.Errorf("Expected three deletions, saw %v", deletions)
+	}
+}
+
+func TestDeleteVersionDisableLatest(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	if err := ds.SetPackageOptions("foo", PackageOptions{DisableLatest: true}); err != nil {
+		t.Fatalf("Unexpected error setting options: %v", err)
+	}
+	if _, err := ds.DeleteVersion("foo", "f00d", "alice", false); err != nil {
+		t.Fatalf("Unexpected error deleting f00d: %v", err)
+	}
+	if _, err := ds.GetPackageVersion("foo", "latest"); err == nil {
+		t.Errorf("Expected latest not to move with DisableLatest set")
+	}
+}
+
+// Make saving the catalog fail, by putting a directory in its way.
+func breakCatalog(t *testing.T, ds *DataStore) func() {
+	saved := ds.catalogPath() + ".saved"
+	if err := os.Rename(ds.catalogPath(), saved); err != nil {
+		t.Fatalf("Failed to move the catalog: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(ds.catalogPath(), "blocker"), 0755); err != nil {
+		t.Fatalf("Failed to block the catalog: %v", err)
+	}
+	return func() {
+		os.RemoveAll(ds.catalogPath())
+		os.Rename(saved, ds.catalogPath())
+	}
+}
+
+func TestFailedSaveRollsBack(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+	writeTarballs(t, ds)
+
+	restore := breakCatalog(t, ds)
+	if _, err := ds.DeleteVersion("foo", "f00d", "alice", false); err == nil {
+		t.Fatalf("Expected an error deleting without a catalog")
+	}
+	if _, err := ds.DeletePackage("foo", "alice", false); err == nil {
+		t.Fatalf("Expected an error deleting without a catalog")
+	}
+	if err := ds.SetLabel("foo", "beef", "staging"); err == nil {
+		t.Fatalf("Expected an error labelling without a catalog")
+	}
+	restore()
+
+	pvs, _ := ds.GetPackageVersions("foo")
+	if len(pvs) != 2 {
+		t.Errorf("Expected both versions to be kept, saw %v", pvs)
+	}
+	if v := labelled(t, ds, "latest"); v != "f00d" {
+		t.Errorf("Expected latest to stay on f00d, saw %s", v)
+	}
+	if _, err := ds.GetPackageVersion("foo", "staging"); err == nil {
+		t.Errorf("Expected staging not to be set")
+	}
+	if deletions := ds.Deletions(); len(deletions) != 0 {
+		t.Errorf("Expected no deletions recorded, saw %v", deletions)
+	}
+	if events, _ := ds.LabelHistory("foo", "latest"); len(events) != 2 {
+		t.Errorf("Expected the history of latest to be unchanged, saw %v", events)
+	}
+	for _, pv := range pvs {
+		if _, err := os.Stat(pv.DataPath); err != nil {
+			t.Errorf("Expected %s to be kept: %v", pv.DataPath, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Deltas between package versions, so a client that has one version
+// installed only needs the contents of the files that changed to
+// install another.
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Returned (wrapped) when a package version is stored as a tarball,
+// and so has no manifest to compare with.
+var ErrNoManifest = errors.New("no manifest")
+
+// A file, or directory, in the version being moved to. Installed is
+// the name of a file with the same contents in the installed version,
+// if there is one, otherwise the contents have to be sent.
+type DeltaFile struct {
+	ManifestEntry
+	Installed string
+}
+
+// What changed between two versions of a package. Files has every
+// file in the new version, in manifest order, and Removed the names
+// only in the installed version, sorted.
+type Delta struct {
+	Files   []DeltaFile
+	Removed []string
+}
+
+// Return the contents hashes that have to be sent for a delta, each
+// only once, in the order they are first needed.
+func (d Delta) Changed() []string {
+	var rv []string
+	seen := make(map[string]bool)
+	for _, f := range d.Files {
+		if f.Hash == "" || f.Installed != "" || seen[f.Hash] {
+			continue
+		}
+		seen[f.Hash] = true
+		rv = append(rv, f.Hash)
+	}
+	return rv
+}
+
+// Return the manifest of a package version.
+func (ds *DataStore) PackageManifest(pv PackageVersion) (*Manifest, error) {
+	if !isManifest(pv.Blob) {
+		return nil, fmt.Errorf("package %s version %s: %w", pv.Name, pv.Version, ErrNoManifest)
+	}
+	return ds.loadManifest(pv.Blob)
+}
+
+// Compute the delta from an installed version of a package to a
+// target version. Files in the target are matched with files in the
+// installed version with the same contents, preferring the one with
+// the same name.
+func (ds *DataStore) PackageDelta(installed, target PackageVersion) (Delta, error) {
+	from, err := ds.PackageManifest(installed)
+	if err != nil {
+		return Delta{}, err
+	}
+	to, err := ds.PackageManifest(target)
+	if err != nil {
+		return Delta{}, err
+	}
+
+	byHash := make(map[string]string)
+	byName := make(map[string]string)
+	for _, entry := range from.Files {
+		byName[entry.Name] = entry.Hash
+		if _, ok := byHash[entry.Hash]; !ok && entry.Hash != "" {
+			byHash[entry.Hash] = entry.Name
+		}
+	}
+
+	var rv Delta
+	kept := make(map[string]bool)
+	for _, entry := range to.Files {
+		kept[entry.Name] = true
+		df := DeltaFile{ManifestEntry: entry}
+		if entry.Hash != "" {
+			if byName[entry.Name] == entry.Hash {
+				df.Installed = entry.Name
+			} else {
+				df.Installed = byHash[entry.Hash]
+			}
+		}
+		rv.Files = append(rv.Files, df)
+	}
+	for name := range byName {
+		if !kept[name] {
+			rv.Removed = append(rv.Removed, name)
+		}
+	}
+	sort.Strings(rv.Removed)
+
+	return rv, nil
+}
+
+// Open the contents of a file, by its hash.
+func (ds *DataStore) OpenFileContents(hash string) (io.ReadCloser, error) {
+	entry := ManifestEntry{Hash: hash}
+	if !fileBlobName.MatchString(entry.blobKey()) {
+		return nil, fmt.Errorf("invalid contents hash %q", hash)
+	}
+	in, err := ds.openFileBlob(entry)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil, fmt.Errorf("contents %s: %w", hash, ErrNotFound)
+	}
+	return in, err
+}
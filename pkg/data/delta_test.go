@@ -0,0 +1,106 @@
+package data
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Build and add a package version with the given files, directories
+// ending in "/".
+func addFilesPackage(t *testing.T, ds *DataStore, name string, files []*pb.File) PackageVersion {
+	if err := os.MkdirAll(filepath.Join(ds.playground, "tmp", name), 0755); err != nil {
+		t.Fatalf("Failed to create playground: %v", err)
+	}
+	pv, err := ds.NewPackageVersion(name)
+	if err != nil {
+		t.Fatalf("Failed to create package version: %v", err)
+	}
+	for _, f := range files {
+		if f.Name[len(f.Name)-1] == '/' {
+			err = pv.AddDir(f)
+		} else {
+			err = pv.AddFile(f)
+		}
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", f.Name, err)
+		}
+	}
+	if err := pv.Finish(); err != nil {
+		t.Fatalf("Failed to finish: %v", err)
+	}
+	if err := ds.AddPackageVersion(pv); err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+	stored, _ := ds.GetPackageVersion(name, pv.Version)
+	return stored
+}
+
+func TestPackageDelta(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	old := addFilesPackage(t, ds, "foo", []*pb.File{
+		{Name: "bin/", Owner: "root", Mode: 0755},
+		{Name: "bin/start", Owner: "root", Mode: 0755, Contents: []byte("v1")},
+		{Name: "old", Owner: "root", Mode: 0644, Contents: []byte("moved")},
+		{Name: "gone", Owner: "root", Mode: 0644, Contents: []byte("gone")},
+	})
+	target := addFilesPackage(t, ds, "foo", []*pb.File{
+		{Name: "bin/", Owner: "root", Mode: 0755},
+		{Name: "bin/start", Owner: "root", Mode: 0755, Contents: []byte("v2")},
+		{Name: "bin/stop", Owner: "root", Mode: 0755, Contents: []byte("v2")},
+		{Name: "new", Owner: "root", Mode: 0600, Contents: []byte("moved")},
+	})
+
+	delta, err := ds.PackageDelta(old, target)
+	if err != nil {
+		t.Fatalf("Unexpected error computing delta: %v", err)
+	}
+	installed := make(map[string]string)
+	for _, f := range delta.Files {
+		installed[f.Name] = f.Installed
+	}
+	want := map[string]string{"bin/": "", "bin/start": "", "bin/stop": "", "new": "old"}
+	if !reflect.DeepEqual(installed, want) {
+		t.Errorf("Saw files %v, want %v", installed, want)
+	}
+	if !reflect.DeepEqual(delta.Removed, []string{"gone", "old"}) {
+		t.Errorf("Saw removed %v, want [gone old]", delta.Removed)
+	}
+
+	// bin/start and bin/stop have the same contents, sent once.
+	changed := delta.Changed()
+	if len(changed) != 1 {
+		t.Fatalf("Expected one changed contents hash, saw %v", changed)
+	}
+	in, err := ds.OpenFileContents(changed[0])
+	if err != nil {
+		t.Fatalf("Unexpected error opening contents: %v", err)
+	}
+	contents, _ := ioutil.ReadAll(in)
+	in.Close()
+	if string(contents) != "v2" {
+		t.Errorf("Saw contents %q, want v2", contents)
+	}
+
+	if _, err := ds.OpenFileContents("../catalog.json"); err == nil {
+		t.Errorf("Expected error opening an invalid hash")
+	}
+}
+
+func TestPackageDeltaNoManifest(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	installed, _ := ds.GetPackageVersion("foo", "beef")
+	target, _ := ds.GetPackageVersion("foo", "f00d")
+	if _, err := ds.PackageDelta(installed, target); !errors.Is(err, ErrNoManifest) {
+		t.Errorf("Expected ErrNoManifest, saw %v", err)
+	}
+}
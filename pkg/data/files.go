@@ -7,10 +7,9 @@
 package data
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"crypto/sha512"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
@@ -23,8 +22,46 @@ import (
 	pb "github.com/vatine/mspm/pkg/protos"
 )
 
-func (ds DataStore) NewPackageVersion(name string) (PackageVersion, error) {
+// Check that a package name is usable as part of a file name.
+func validPackageName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid package name %q", name)
+	}
+	return nil
+}
+
+// Check that a file name in a package stays within the package. We
+// expect relative, clean, paths, with directories having a trailing
+// "/".
+func validFileName(name string) error {
+	trimmed := strings.TrimSuffix(name, "/")
+	if trimmed == "" || filepath.IsAbs(trimmed) || filepath.Clean(trimmed) != trimmed {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	if trimmed == ".." || strings.HasPrefix(trimmed, "../") {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
+
+// Create a new PackageVersion, with a fresh working directory in the
+// playground. Files are added to it with AddDir and AddFile, and it
+// is turned into a tarball with Finish.
+func (ds *DataStore) NewPackageVersion(name string) (PackageVersion, error) {
+	if err := validPackageName(name); err != nil {
+		return PackageVersion{}, err
+	}
+
 	tdPath := filepath.Join(ds.playground, "tmp", name)
+	err := os.MkdirAll(tdPath, 0755)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"tdPath": tdPath,
+			"name":   name,
+		}).Error("creating playground directory")
+		return PackageVersion{}, err
+	}
 	dataPath, err := ioutil.TempDir(tdPath, "tmp-")
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -38,18 +75,55 @@ func (ds DataStore) NewPackageVersion(name string) (PackageVersion, error) {
 		Labels:   make(map[string]struct{}),
 		DataPath: dataPath,
 		fileMap:  make(map[string]fileInfo),
+		groups:   make(map[string]string),
 	}, err
 }
 
+// Throw away a PackageVersion that will not be added to the data
+// store, removing its working directory or tarball. Only things in
+// the playground are ever removed.
+func (ds *DataStore) DiscardPackageVersion(pv PackageVersion) error {
+	rel, err := filepath.Rel(ds.playground, pv.DataPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		log.WithFields(log.Fields{
+			"name": pv.Name,
+			"path": pv.DataPath,
+		}).Warning("not discarding data outside the playground")
+		return nil
+	}
+
+	err = os.RemoveAll(pv.DataPath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pv.Name,
+			"path":  pv.DataPath,
+		}).Error("discarding PackageVersion")
+	}
+	return err
+}
+
 func (pv *PackageVersion) AddDir(pvFile *pb.File) error {
+	if err := validFileName(pvFile.Name); err != nil {
+		return err
+	}
 	targetPath := filepath.Join(pv.DataPath, pvFile.Name)
 
 	pv.fileMap[pvFile.Name] = fileInfo{pvFile.Owner, pvFile.Mode}
+	pv.groups[pvFile.Name] = pvFile.Group
 	return os.Mkdir(targetPath, os.ModeDir|0777)
 }
 
-// Add a file to the on-disk temporary storage of a file.
-func (pv PackageVersion) AddFile(pvFile *pb.File) error {
+// Create a file in the on-disk temporary storage of a package
+// version, returning a writer for its contents. The Contents of
+// pvFile are ignored, this is for when the contents arrive piecemeal.
+func (pv PackageVersion) CreateFile(pvFile *pb.File) (io.WriteCloser, error) {
+	if err := validFileName(pvFile.Name); err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(pvFile.Name, "/") {
+		return nil, fmt.Errorf("%s is a directory", pvFile.Name)
+	}
 	targetPath := filepath.Join(pv.DataPath, pvFile.Name)
 
 	out, err := os.Create(targetPath)
@@ -59,8 +133,21 @@ func (pv PackageVersion) AddFile(pvFile *pb.File) error {
 			"name":       pv.Name,
 			"targetPath": targetPath,
 		}).Error("opening PackageVersion file")
+		return nil, err
+	}
+
+	pv.fileMap[pvFile.Name] = fileInfo{pvFile.Owner, pvFile.Mode}
+	pv.groups[pvFile.Name] = pvFile.Group
+	return out, nil
+}
+
+// Add a file to the on-disk temporary storage of a file.
+func (pv PackageVersion) AddFile(pvFile *pb.File) error {
+	out, err := pv.CreateFile(pvFile)
+	if err != nil {
 		return err
 	}
+	defer out.Close()
 
 	written := 0
 	for written < len(pvFile.Contents) {
@@ -86,8 +173,7 @@ func (pv PackageVersion) AddFile(pvFile *pb.File) error {
 		written += n
 	}
 
-	pv.fileMap[pvFile.Name] = fileInfo{pvFile.Owner, pvFile.Mode}
-	return nil
+	return out.Close()
 }
 
 // Returns a slice of os.FileInfo, sorted asciibetically after name
@@ -195,8 +281,44 @@ func (f fileInfo) forHash() string {
 
 }
 
+// A VersionHash computes the version of a package from its
+// contents. Entries must be added in the order pathsUnderRoot would
+// list them, directories with a trailing "/".
+type VersionHash struct {
+	hash hash.Hash
+	ix   int
+}
+
+// Create a new, empty, VersionHash.
+func NewVersionHash() *VersionHash {
+	return &VersionHash{hash: sha512.New()}
+}
+
+// Add an entry to the hash. The contents are ignored for directories
+// and may be nil.
+func (vh *VersionHash) Add(name, owner string, mode int32, contents io.Reader) error {
+	fi := fileInfo{owner: owner, mode: mode}
+	fmt.Fprintf(vh.hash, "«%d»«%s»%s", vh.ix, name, fi.forHash())
+	vh.ix++
+	if strings.HasSuffix(name, "/") || contents == nil {
+		return nil
+	}
+	_, err := io.Copy(vh.hash, contents)
+	return err
+}
+
+// Return the raw hash sum.
+func (vh *VersionHash) Sum() []byte {
+	return vh.hash.Sum(nil)
+}
+
+// Return the version string corresponding to the hash sum.
+func (vh *VersionHash) Version() string {
+	return fmt.Sprintf("%x", vh.Sum())
+}
+
 func (pv *PackageVersion) hash() ([]byte, error) {
-	hash := sha512.New()
+	vh := NewVersionHash()
 
 	paths, err := pathsUnderRoot(pv.DataPath)
 	if err != nil {
@@ -207,33 +329,34 @@ func (pv *PackageVersion) hash() ([]byte, error) {
 		return []byte{}, err
 	}
 
-	for ix, name := range paths {
+	for _, name := range paths {
 		fi, ok := pv.fileMap[name]
 		if !ok {
 			log.WithFields(log.Fields{
 				"pv.Name": pv.Name,
 				"name":    name,
 			}).Error("missing fileIfo for name")
-			return hash.Sum(nil), fmt.Errorf("File %s is unknown", name)
+			return vh.Sum(), fmt.Errorf("File %s is unknown", name)
 		}
-		fmt.Fprintf(hash, "«%d»«%s»%s", ix, name, fi.forHash())
-		if !strings.HasSuffix(name, "/") {
-			func() {
-				f, err := os.Open(filepath.Join(pv.DataPath, name))
-				if err != nil {
-					return
-				}
-				defer f.Close()
-				io.Copy(hash, f)
-			}()
+		if strings.HasSuffix(name, "/") {
+			vh.Add(name, fi.owner, fi.mode, nil)
+			continue
 		}
+		func() {
+			f, err := os.Open(filepath.Join(pv.DataPath, name))
+			if err != nil {
+				vh.Add(name, fi.owner, fi.mode, nil)
+				return
+			}
+			defer f.Close()
+			vh.Add(name, fi.owner, fi.mode, f)
+		}()
 	}
 
-	return hash.Sum(nil), nil
+	return vh.Sum(), nil
 }
 
 func (pv *PackageVersion) Finish() error {
-	var saveErr error
 	hash, err := pv.hash()
 
 	if err != nil {
@@ -246,63 +369,117 @@ func (pv *PackageVersion) Finish() error {
 
 	pv.Version = fmt.Sprintf("%x", hash)
 
-	files, _ := pathsUnderRoot(pv.DataPath)
-	outName := filepath.Join(pv.DataPath, "../..", fmt.Sprintf("%s-%s.tgz", pv.Name, pv.Version))
+	manifest, err := pv.buildManifest()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Error("building manifest")
+		return err
+	}
 
-	out, err := os.Create(outName)
+	// The tarball is only built to find its size and checksum, it
+	// is rebuilt from the manifest when downloaded.
+	checksum := sha512.New()
+	counter := &countingWriter{w: checksum}
+	err = writeTarball(counter, manifest, func(entry ManifestEntry) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(pv.DataPath, entry.Name))
+	})
 	if err != nil {
 		log.WithFields(log.Fields{
-			"error":    err,
-			"pv":       pv.Name,
-			"version":  pv.Version,
-			"filename": outName,
-		}).Error("opening archive file")
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Error("archiving package version")
 		return err
 	}
-	defer out.Close()
+	manifest.Size = counter.n
+	manifest.Checksum = fmt.Sprintf("%x", checksum.Sum(nil))
+
+	// The working directory is kept until the files are in the
+	// blob store.
+	pv.manifest = manifest
+	pv.Size = manifest.Size
+	pv.Checksum = manifest.Checksum
+	pv.fileMap = make(map[string]fileInfo)
+	pv.groups = make(map[string]string)
+	return nil
+}
 
-	zipper := gzip.NewWriter(out)
+// An io.Writer that counts what passes through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
 
-	tarball := tar.NewWriter(zipper)
-	defer tarball.Close()
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
 
-	for _, fname := range files {
-		fsname := filepath.Join(pv.DataPath, fname)
-		tarname := fmt.Sprintf("%s-%s/%s", pv.Name, pv.Version, fname)
-		fi, err := os.Stat(fsname)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"error":  err,
-				"fsname": fsname,
-			}).Error("archiving stat failed")
-			return err
-		}
-		func() {
-			pvMode := pv.fileMap[fname].mode & 0777
-			tarHdr, _ := tar.FileInfoHeader(fi, "")
-			tarHdr.Name = tarname
-			tarHdr.Mode = (tarHdr.Mode & 0xFFFE00) | int64(pvMode)
-			tarHdr.Uid = 0
-			tarHdr.Gid = 0
-
-			in, err := os.Open(fsname)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"error":  err,
-					"fsname": fsname,
-				}).Error("failed to open input file")
-			}
-			defer in.Close()
-			tarball.WriteHeader(tarHdr)
-			io.Copy(tarball, in)
-		}()
+// Open the tarball of a package version for reading.
+func (ds *DataStore) OpenPackageData(pv PackageVersion) (io.ReadCloser, error) {
+	f, err := ds.openTarball(pv)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+			"blob":    pv.Blob,
+			"path":    pv.DataPath,
+		}).Error("opening package data")
+		return nil, err
 	}
+	return f, nil
+}
 
-	if saveErr != nil {
-		return saveErr
+// Return the size and SHA-512 checksum of the tarball of a package
+// version. These are normally recorded by Finish, but versions that
+// were recovered (or stored before we kept track) have them computed
+// on first use, and recorded in the catalog. Failing to save the
+// catalog is only logged, the checksum is still good.
+func (ds *DataStore) PackageChecksum(pv PackageVersion) (int64, string, error) {
+	if pv.Checksum != "" {
+		return pv.Size, pv.Checksum, nil
 	}
 
-	pv.DataPath = outName
-	pv.fileMap = make(map[string]fileInfo)
-	return nil
+	in, err := ds.OpenPackageData(pv)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	checksum := sha512.New()
+	size, err := io.Copy(checksum, in)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Error("computing package checksum")
+		return 0, "", err
+	}
+	sum := fmt.Sprintf("%x", checksum.Sum(nil))
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	if p, ok := ds.packages[pv.Name]; ok {
+		p.lock.Lock()
+		if stored, ok := p.versions[pv.Version]; ok {
+			stored.Size = size
+			stored.Checksum = sum
+		}
+		p.lock.Unlock()
+		if err := ds.saveCatalog(); err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"name":    pv.Name,
+				"version": pv.Version,
+			}).Error("recording package checksum")
+		}
+	}
+
+	return size, sum, nil
 }
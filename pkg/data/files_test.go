@@ -2,9 +2,54 @@ package data
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// Copy the hashing test tree to a temporary directory. Git does not
+// track empty directories, so the ones the tests expect are created
+// there rather than in the source tree.
+func hashTestTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mspm-hash")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	src := "./testdata/hash"
+	err = filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, contents, 0644)
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("Failed to copy %s: %v", src, err)
+	}
+	for _, empty := range []string{"dir2/dir21", "dir3"} {
+		if err := os.MkdirAll(filepath.Join(dir, empty), 0755); err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("Failed to create %s: %v", empty, err)
+		}
+	}
+
+	return dir
+}
+
 func TestModes(t *testing.T) {
 	cases := []struct {
 		m int32
@@ -42,7 +87,9 @@ func TestFileInfo(t *testing.T) {
 }
 
 func TestListFiles(t *testing.T) {
-	paths, _ := pathsUnderRoot("./testdata/hash")
+	root := hashTestTree(t)
+	defer os.RemoveAll(root)
+	paths, _ := pathsUnderRoot(root)
 
 	expected := []string{"dir1/", "dir1/f1", "dir1/f2", "dir2/", "dir2/dir21/", "dir2/f1", "dir3/"}
 
@@ -59,9 +106,11 @@ func TestListFiles(t *testing.T) {
 }
 
 func TestHash(t *testing.T) {
+	root := hashTestTree(t)
+	defer os.RemoveAll(root)
 	pv := &PackageVersion{
 		Name:     "testpackage",
-		DataPath: "./testdata/hash",
+		DataPath: root,
 		Labels:   make(map[string]struct{}),
 		fileMap:  make(map[string]fileInfo),
 	}
@@ -0,0 +1,110 @@
+// Every time a label moves, the package records where it moved from,
+// where it moved to and who moved it. The history is append-only, and
+// kept in the catalog.
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Returned (wrapped) when there is no previous version to roll a
+// label back to.
+var ErrNoHistory = errors.New("no earlier version in label history")
+
+// A label moving from one version to another. Old is empty if the
+// label was not on any version before, New if it was removed.
+type LabelEvent struct {
+	Time     time.Time `json:"time"`
+	Label    string    `json:"label"`
+	Old      string    `json:"old,omitempty"`
+	New      string    `json:"new,omitempty"`
+	Identity string    `json:"identity,omitempty"`
+}
+
+// Add an event to the history of a label. Expects to be called with
+// the package lock held.
+func (p *Package) recordLabelEvent(label string, old, target *PackageVersion, identity string) {
+	event := LabelEvent{
+		Time:     time.Now().UTC(),
+		Label:    label,
+		Identity: identity,
+	}
+	if old != nil {
+		event.Old = old.Version
+	}
+	if target != nil {
+		event.New = target.Version
+	}
+	p.history[label] = append(p.history[label], event)
+}
+
+// Return the history of a label on a package, oldest first. If label
+// is empty, the history of all labels is returned, sorted by time.
+func (ds *DataStore) LabelHistory(pkg, label string) ([]LabelEvent, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	p, ok := ds.packages[pkg]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found in data store", pkg)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if label != "" {
+		return append([]LabelEvent{}, p.history[label]...), nil
+	}
+
+	var rv []LabelEvent
+	for _, events := range p.history {
+		rv = append(rv, events...)
+	}
+	sort.SliceStable(rv, func(i, j int) bool { return rv[i].Time.Before(rv[j].Time) })
+	return rv, nil
+}
+
+// Move a label back to the version it was on before it last moved,
+// returning that version. Rolling back twice returns the label to
+// where it started, as the rollback itself is a move. This is a label
+// change like any other, so protected labels need force.
+func (ds *DataStore) RollbackLabel(pkg, label, identity string, force bool) (string, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	p, ok := ds.packages[pkg]
+	if !ok {
+		return "", fmt.Errorf("package %s not found in data store", pkg)
+	}
+	p.lock.Lock()
+	events := p.history[label]
+	p.lock.Unlock()
+	if len(events) == 0 || events[len(events)-1].Old == "" {
+		return "", fmt.Errorf("label %s on %s: %w", label, pkg, ErrNoHistory)
+	}
+	last := events[len(events)-1]
+
+	lc := LabelChange{
+		Package:    pkg,
+		Designator: last.Old,
+		Label:      label,
+		Force:      force,
+		Expected:   last.New,
+		Identity:   identity,
+	}
+	if lc.Expected == "" {
+		lc.Expected = ExpectUnset
+	}
+	cp := ds.checkpoint(pkg)
+	_, err := ds.applyLabelChanges([]LabelChange{lc})
+	if err != nil {
+		return "", err
+	}
+	if err := ds.commitCatalog(cp); err != nil {
+		return "", err
+	}
+
+	return last.Old, nil
+}
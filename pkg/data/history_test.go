@@ -0,0 +1,123 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLabelHistory(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	ds.ChangeLabel(LabelChange{Package: "foo", Designator: "beef", Label: "prod", Identity: "alice"})
+	ds.ChangeLabel(LabelChange{Package: "foo", Designator: "beef", Label: "prod", Identity: "alice"})
+	ds.ChangeLabel(LabelChange{Package: "foo", Designator: "f00d", Label: "prod", Identity: "bob"})
+
+	events, err := ds.LabelHistory("foo", "prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []LabelEvent{
+		{Label: "prod", Old: "", New: "beef", Identity: "alice"},
+		{Label: "prod", Old: "beef", New: "f00d", Identity: "bob"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, saw %v", len(expected), events)
+	}
+	for ix, e := range expected {
+		seen := events[ix]
+		if seen.Label != e.Label || seen.Old != e.Old || seen.New != e.New || seen.Identity != e.Identity {
+			t.Errorf("Event #%d, expected %v, saw %v", ix, e, seen)
+		}
+		if seen.Time.IsZero() {
+			t.Errorf("Event #%d has no time", ix)
+		}
+	}
+
+	// Both uploads moved latest, and that is in the history too.
+	all, err := ds.LabelHistory("foo", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 events in total, saw %d", len(all))
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	events, _ = reloaded.LabelHistory("foo", "prod")
+	if len(events) != 2 || events[1].Identity != "bob" {
+		t.Errorf("Expected history to survive a reload, saw %v", events)
+	}
+
+	if _, err := ds.LabelHistory("bar", "prod"); err == nil {
+		t.Errorf("Expected an error for an unknown package")
+	}
+}
+
+func TestRollbackLabel(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := ds.RollbackLabel("foo", "prod", "alice", false); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory for an unused label, saw %v", err)
+	}
+	ds.SetLabel("foo", "beef", "prod")
+	if _, err := ds.RollbackLabel("foo", "prod", "alice", false); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory for a label that never moved, saw %v", err)
+	}
+
+	ds.SetLabel("foo", "f00d", "prod")
+	version, err := ds.RollbackLabel("foo", "prod", "alice", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if version != "beef" || labelled(t, ds, "prod") != "beef" {
+		t.Errorf("Expected prod rolled back to beef, saw %s", version)
+	}
+
+	// Rolling back again undoes the rollback.
+	version, err = ds.RollbackLabel("foo", "prod", "alice", false)
+	if err != nil || version != "f00d" {
+		t.Errorf("Expected prod back on f00d, saw %s, %v", version, err)
+	}
+
+	// Protected labels need force.
+	ds.SetProtectedLabels([]string{"prod"})
+	if _, err := ds.RollbackLabel("foo", "prod", "alice", false); !errors.Is(err, ErrLabelProtected) {
+		t.Errorf("Expected ErrLabelProtected, saw %v", err)
+	}
+	if _, err := ds.RollbackLabel("foo", "prod", "alice", true); err != nil {
+		t.Errorf("Unexpected error forcing a rollback: %v", err)
+	}
+
+	events, _ := ds.LabelHistory("foo", "prod")
+	if last := events[len(events)-1]; last.Identity != "alice" || last.New != "beef" {
+		t.Errorf("Expected the rollback in the history, saw %v", last)
+	}
+}
+
+func TestUploadedByInHistory(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	pv := newPackageVersion("foo", "beef")
+	pv.DataPath = filepath.Join(ds.store, "foo-beef.tgz")
+	pv.UploadedBy = "ci"
+	if err := ds.AddPackageVersion(pv); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	events, _ := ds.LabelHistory("foo", "latest")
+	if len(events) != 1 || events[0].Identity != "ci" {
+		t.Errorf("Expected latest moved by ci, saw %v", events)
+	}
+	stored, _ := ds.GetPackageVersion("foo", "beef")
+	if stored.UploadedBy != "ci" {
+		t.Errorf("Expected UploadedBy ci, saw %q", stored.UploadedBy)
+	}
+}
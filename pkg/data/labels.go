@@ -0,0 +1,245 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// Returned (wrapped) when a protected label would be moved
+	// from one version to another, without that being forced.
+	ErrLabelProtected = errors.New("label is protected")
+	// Returned (wrapped) when a label is not on the version a
+	// LabelChange expected it to be on.
+	ErrLabelMoved = errors.New("label has moved")
+)
+
+// The Expected version of a label that is not on any version.
+const ExpectUnset = "-"
+
+// A request to put a label on a version of a package. Protected
+// labels can only be moved away from the version they are on if Force
+// is set. If Expected is set, the label must currently be on that
+// version (or, for ExpectUnset, on no version at all). Identity is who
+// asked for the change, for the label history.
+type LabelChange struct {
+	Package    string
+	Designator string
+	Label      string
+	Force      bool
+	Expected   string
+	Identity   string
+}
+
+// Per-package settings.
+type PackageOptions struct {
+	// Do not move "latest" to newly added versions.
+	DisableLatest bool
+}
+
+// Set the labels that are protected. This replaces any previously
+// protected labels.
+func (ds *DataStore) SetProtectedLabels(labels []string) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	ds.protected = make(map[string]bool)
+	for _, label := range labels {
+		ds.protected[label] = true
+	}
+}
+
+// Check if a label is protected.
+func (ds *DataStore) IsProtected(label string) bool {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	return ds.protected[label]
+}
+
+// Put a label on a version of a package, as described by the
+// LabelChange, and update the catalog.
+func (ds *DataStore) ChangeLabel(lc LabelChange) error {
+	_, err := ds.ChangeLabels([]LabelChange{lc})
+	return err
+}
+
+// A label on a package, used to keep track of where labels will be
+// while checking a batch of changes.
+type packageLabel struct {
+	pkg   string
+	label string
+}
+
+// Apply a batch of label changes, all or nothing. The changes are
+// checked in order, as if the earlier ones had already been applied,
+// so a batch can move the same label more than once. Designators are
+// resolved before any change is made. If any change fails its checks,
+// nothing is changed. The catalog is updated once, at the end. The
+// version each change put its label on is returned, one per change,
+// as it was once the whole batch was applied.
+func (ds *DataStore) ChangeLabels(changes []LabelChange) ([]PackageVersion, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	var names []string
+	for _, lc := range changes {
+		names = append(names, lc.Package)
+	}
+	cp := ds.checkpoint(names...)
+	rv, err := ds.applyLabelChanges(changes)
+	if err != nil {
+		return nil, err
+	}
+	if err := ds.commitCatalog(cp); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Check and apply a batch of label changes, returning the targets as
+// ChangeLabels does. Expects to be called with the data store lock
+// held.
+func (ds *DataStore) applyLabelChanges(changes []LabelChange) ([]PackageVersion, error) {
+	// Lock every package involved, in name order, so nobody sees a
+	// half-applied batch.
+	var names []string
+	pkgs := make(map[string]*Package)
+	for _, lc := range changes {
+		p, ok := ds.packages[lc.Package]
+		if !ok {
+			log.WithFields(log.Fields{
+				"name":       lc.Package,
+				"designator": lc.Designator,
+				"newLabel":   lc.Label,
+			}).Error("package not found")
+			return nil, fmt.Errorf("package %s not found in data store", lc.Package)
+		}
+		if _, seen := pkgs[lc.Package]; !seen {
+			names = append(names, lc.Package)
+			pkgs[lc.Package] = p
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkgs[name].lock.Lock()
+		defer pkgs[name].lock.Unlock()
+	}
+
+	targets := make([]*PackageVersion, len(changes))
+	current := make(map[packageLabel]string)
+	for ix, lc := range changes {
+		p := pkgs[lc.Package]
+		target, ok := p.getVersion(lc.Designator)
+		if !ok {
+			return nil, fmt.Errorf("No package-version of %s designated by %s", lc.Package, lc.Designator)
+		}
+		targets[ix] = target
+
+		key := packageLabel{lc.Package, lc.Label}
+		held, ok := current[key]
+		if !ok {
+			if old, ok := p.labels[lc.Label]; ok {
+				held = old.Version
+			}
+		}
+
+		if lc.Expected != "" {
+			want := lc.Expected
+			if want == ExpectUnset {
+				want = ""
+			}
+			if held != want {
+				return nil, fmt.Errorf("label %s on %s is on version %q, expected %q: %w", lc.Label, lc.Package, held, want, ErrLabelMoved)
+			}
+		}
+		if held != "" && held != target.Version && ds.protected[lc.Label] && !lc.Force {
+			return nil, fmt.Errorf("label %s on %s is on version %s: %w", lc.Label, lc.Package, held, ErrLabelProtected)
+		}
+
+		current[key] = target.Version
+	}
+
+	for ix, lc := range changes {
+		if lc.Force && ds.protected[lc.Label] {
+			log.WithFields(log.Fields{
+				"name":    lc.Package,
+				"version": targets[ix].Version,
+				"label":   lc.Label,
+			}).Info("forced protected label")
+		}
+		err := pkgs[lc.Package].setLabel(targets[ix].Version, lc.Label, lc.Identity)
+		if err != nil {
+			// We checked the target exists above, so this
+			// should never happen.
+			return nil, err
+		}
+	}
+
+	rv := make([]PackageVersion, len(targets))
+	for ix, target := range targets {
+		rv[ix] = *target
+		rv[ix].Labels = make(map[string]struct{})
+		for label := range target.Labels {
+			rv[ix].Labels[label] = struct{}{}
+		}
+	}
+	return rv, nil
+}
+
+// Check that the labels can be put on a version that is not yet in
+// the package, without changing anything. Expects to be called with
+// the data store lock held.
+func (ds *DataStore) checkNewVersionLabels(p *Package, labels []LabelChange) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, lc := range labels {
+		held, ok := p.labels[lc.Label]
+		if ok && ds.protected[lc.Label] && !lc.Force {
+			return fmt.Errorf("label %s on %s is on version %s: %w", lc.Label, p.name, held.Version, ErrLabelProtected)
+		}
+	}
+	return nil
+}
+
+// Change the settings for a package. The package does not need to
+// have any versions yet, so settings can be in place before the first
+// upload.
+func (ds *DataStore) SetPackageOptions(name string, opts PackageOptions) error {
+	if err := validPackageName(name); err != nil {
+		return err
+	}
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	cp := ds.checkpoint(name)
+	p, ok := ds.packages[name]
+	if !ok {
+		p = newPackage(name)
+		ds.packages[name] = p
+	}
+	p.lock.Lock()
+	p.options = opts
+	p.lock.Unlock()
+
+	return ds.commitCatalog(cp)
+}
+
+// Return the settings for a package, and whether the package is known.
+func (ds *DataStore) GetPackageOptions(name string) (PackageOptions, bool) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	p, ok := ds.packages[name]
+	if !ok {
+		return PackageOptions{}, false
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.options, true
+}
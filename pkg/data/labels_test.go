@@ -0,0 +1,200 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Create a data store with package foo, versions beef and f00d.
+func newLabelTestStore(t *testing.T) (*DataStore, string) {
+	ds, dir := newTestStore(t)
+
+	for _, version := range []string{"beef", "f00d"} {
+		pv := newPackageVersion("foo", version)
+		pv.DataPath = filepath.Join(ds.store, "foo-"+version+".tgz")
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding %s: %v", version, err)
+		}
+	}
+
+	return ds, dir
+}
+
+func labelled(t *testing.T, ds *DataStore, label string) string {
+	pv, err := ds.GetPackageVersion("foo", label)
+	if err != nil {
+		t.Fatalf("Unexpected error looking up %s: %v", label, err)
+	}
+	return pv.Version
+}
+
+func TestProtectedLabels(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+	ds.SetProtectedLabels([]string{"prod"})
+
+	if !ds.IsProtected("prod") || ds.IsProtected("staging") {
+		t.Errorf("Expected only prod to be protected")
+	}
+
+	// Setting a protected label nothing has is fine, as is setting
+	// it again on the same version.
+	for ix := 0; ix < 2; ix++ {
+		if err := ds.SetLabel("foo", "beef", "prod"); err != nil {
+			t.Fatalf("Unexpected error setting prod: %v", err)
+		}
+	}
+
+	err := ds.SetLabel("foo", "f00d", "prod")
+	if !errors.Is(err, ErrLabelProtected) {
+		t.Errorf("Expected ErrLabelProtected, saw %v", err)
+	}
+	if v := labelled(t, ds, "prod"); v != "beef" {
+		t.Errorf("Expected prod to stay on beef, saw %s", v)
+	}
+
+	err = ds.ChangeLabel(LabelChange{Package: "foo", Designator: "f00d", Label: "prod", Force: true})
+	if err != nil {
+		t.Errorf("Unexpected error forcing prod: %v", err)
+	}
+	if v := labelled(t, ds, "prod"); v != "f00d" {
+		t.Errorf("Expected prod to move to f00d, saw %s", v)
+	}
+
+	// Unprotected labels move freely.
+	ds.SetLabel("foo", "beef", "staging")
+	if err := ds.SetLabel("foo", "f00d", "staging"); err != nil {
+		t.Errorf("Unexpected error moving staging: %v", err)
+	}
+}
+
+func TestDisableLatest(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	if err := ds.SetPackageOptions("foo", PackageOptions{DisableLatest: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pv := newPackageVersion("foo", "cafe")
+	pv.DataPath = filepath.Join(ds.store, "foo-cafe.tgz")
+	if err := ds.AddPackageVersion(pv); err != nil {
+		t.Fatalf("Unexpected error adding cafe: %v", err)
+	}
+	if v := labelled(t, ds, "latest"); v != "f00d" {
+		t.Errorf("Expected latest to stay on f00d, saw %s", v)
+	}
+
+	// Options can be set before a package has any versions, and
+	// survive a reload.
+	if err := ds.SetPackageOptions("bar", PackageOptions{DisableLatest: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		opts, ok := reloaded.GetPackageOptions(name)
+		if !ok || !opts.DisableLatest {
+			t.Errorf("Expected %s to have DisableLatest after reload, saw %v, %v", name, opts, ok)
+		}
+	}
+
+	if err := ds.SetPackageOptions("../bad", PackageOptions{}); err == nil {
+		t.Errorf("Expected an error for a bad package name")
+	}
+}
+
+func TestCompareAndSwapLabel(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	// prod is not on anything yet.
+	err := ds.ChangeLabel(LabelChange{Package: "foo", Designator: "beef", Label: "prod", Expected: "f00d"})
+	if !errors.Is(err, ErrLabelMoved) {
+		t.Errorf("Expected ErrLabelMoved, saw %v", err)
+	}
+	err = ds.ChangeLabel(LabelChange{Package: "foo", Designator: "beef", Label: "prod", Expected: ExpectUnset})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = ds.ChangeLabel(LabelChange{Package: "foo", Designator: "f00d", Label: "prod", Expected: ExpectUnset})
+	if !errors.Is(err, ErrLabelMoved) {
+		t.Errorf("Expected ErrLabelMoved, saw %v", err)
+	}
+	err = ds.ChangeLabel(LabelChange{Package: "foo", Designator: "f00d", Label: "prod", Expected: "beef"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v := labelled(t, ds, "prod"); v != "f00d" {
+		t.Errorf("Expected prod on f00d, saw %s", v)
+	}
+}
+
+func TestChangeLabelsAllOrNothing(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	changes := []LabelChange{
+		{Package: "foo", Designator: "beef", Label: "staging"},
+		{Package: "foo", Designator: "beef", Label: "prod", Expected: "f00d"},
+	}
+	_, err := ds.ChangeLabels(changes)
+	if !errors.Is(err, ErrLabelMoved) {
+		t.Errorf("Expected ErrLabelMoved, saw %v", err)
+	}
+	if _, err := ds.GetPackageVersion("foo", "staging"); err == nil {
+		t.Errorf("Expected staging not to be set after a failed batch")
+	}
+
+	// Later changes see the effect of earlier ones.
+	changes = []LabelChange{
+		{Package: "foo", Designator: "beef", Label: "staging"},
+		{Package: "foo", Designator: "f00d", Label: "staging", Expected: "beef"},
+	}
+	if _, err := ds.ChangeLabels(changes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v := labelled(t, ds, "staging"); v != "f00d" {
+		t.Errorf("Expected staging on f00d, saw %s", v)
+	}
+}
+
+func TestChangeLabelsAcrossPackages(t *testing.T) {
+	ds, dir := newLabelTestStore(t)
+	defer os.RemoveAll(dir)
+
+	pv := newPackageVersion("bar", "cafe")
+	pv.DataPath = filepath.Join(ds.store, "bar-cafe.tgz")
+	if err := ds.AddPackageVersion(pv); err != nil {
+		t.Fatalf("Unexpected error adding bar: %v", err)
+	}
+
+	changes := []LabelChange{
+		{Package: "foo", Designator: "beef", Label: "train"},
+		{Package: "bar", Designator: "cafe", Label: "train"},
+		{Package: "baz", Designator: "cafe", Label: "train"},
+	}
+	if _, err := ds.ChangeLabels(changes); err == nil {
+		t.Errorf("Expected an error for an unknown package")
+	}
+	if _, err := ds.GetPackageVersion("foo", "train"); err == nil {
+		t.Errorf("Expected train not to be set after a failed batch")
+	}
+
+	targets, err := ds.ChangeLabels(changes[:2])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Version != "beef" || targets[1].Version != "cafe" {
+		t.Errorf("Unexpected targets %v", targets)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		if _, err := ds.GetPackageVersion(name, "train"); err != nil {
+			t.Errorf("Expected train to be set on %s: %v", name, err)
+		}
+	}
+}
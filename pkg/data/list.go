@@ -0,0 +1,95 @@
+// Listing and searching the packages in the data store.
+package data
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Returned (wrapped) when a listing has an invalid pattern.
+var ErrBadPattern = errors.New("bad package pattern")
+
+// What to list packages by. Empty fields match every package. Pattern
+// uses path.Match syntax, and Label only matches packages that have
+// that label on one of their versions.
+type PackageFilter struct {
+	Prefix  string
+	Pattern string
+	Label   string
+}
+
+// A short description of a package, as returned by ListPackages.
+type PackageSummary struct {
+	Name     string
+	Versions int
+	// Which version each label is on.
+	Labels map[string]string
+}
+
+// Check that the filter pattern is valid.
+func (f PackageFilter) validate() error {
+	if _, err := path.Match(f.Pattern, ""); err != nil {
+		return fmt.Errorf("%q: %v: %w", f.Pattern, err, ErrBadPattern)
+	}
+	return nil
+}
+
+func (f PackageFilter) matches(name string, p *Package) bool {
+	if !strings.HasPrefix(name, f.Prefix) {
+		return false
+	}
+	if f.Pattern != "" {
+		if ok, _ := path.Match(f.Pattern, name); !ok {
+			return false
+		}
+	}
+	if f.Label != "" {
+		if _, ok := p.labels[f.Label]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the packages matching a filter, sorted by name. Only packages
+// sorting after the name after are returned, so a listing can be
+// picked up where an earlier one left off.
+func (ds *DataStore) ListPackages(filter PackageFilter, after string) ([]PackageSummary, error) {
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	var names []string
+	for name := range ds.packages {
+		if name > after {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var rv []PackageSummary
+	for _, name := range names {
+		p := ds.packages[name]
+		p.lock.Lock()
+		if filter.matches(name, p) {
+			summary := PackageSummary{
+				Name:     name,
+				Versions: len(p.versions),
+				Labels:   make(map[string]string),
+			}
+			for label, pv := range p.labels {
+				summary.Labels[label] = pv.Version
+			}
+			rv = append(rv, summary)
+		}
+		p.lock.Unlock()
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,615 @@
+// Package versions are stored as a manifest, listing the files in
+// the version, with the contents of each file kept as a blob of its
+// own, keyed by its SHA-512. Files that are the same in several
+// versions, or packages, are only stored once. The tarball clients
+// download is rebuilt from the manifest, the same way every time, so
+// the size and checksum recorded when the version was finished still
+// hold.
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Returned (wrapped) when asking for the contents of a directory.
+var ErrIsDirectory = errors.New("is a directory")
+
+// Manifests are stored as <name>-<version>.manifest.
+const manifestSuffix = ".manifest"
+
+var manifestName = regexp.MustCompile(`^(.+)-([0-9a-f]{128})\.manifest$`)
+
+// File contents are stored as sha512-<hash in hex>.
+var fileBlobName = regexp.MustCompile(`^sha512-[0-9a-f]{128}$`)
+
+// The files in a package version, in the order they are hashed and
+// archived in, along with the size and checksum of the tarball.
+type Manifest struct {
+	Name     string          `json:"name"`
+	Version  string          `json:"version"`
+	Size     int64           `json:"size"`
+	Checksum string          `json:"checksum"`
+	Files    []ManifestEntry `json:"files"`
+}
+
+// A file, or directory, in a manifest. Directory names end in "/",
+// and have no Size or Hash.
+type ManifestEntry struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	// The group is not part of the version hash, so uploading the
+	// same contents again with other groups gets the existing
+	// version, with the groups it was first uploaded with. It is
+	// empty for versions stored before it was recorded.
+	Group   string    `json:"group,omitempty"`
+	Mode    int32     `json:"mode"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash,omitempty"`
+}
+
+// Return "dir" for directories, and "file" for files.
+func (e ManifestEntry) Type() string {
+	if strings.HasSuffix(e.Name, "/") {
+		return "dir"
+	}
+	return "file"
+}
+
+// The key the contents of a file are stored under.
+func (e ManifestEntry) blobKey() string {
+	return "sha512-" + e.Hash
+}
+
+// Split a manifest blob key into package name and version. The bool
+// is false if the key does not look like a manifest.
+func splitManifestName(key string) (string, string, bool) {
+	m := manifestName.FindStringSubmatch(key)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+func isManifest(key string) bool {
+	return strings.HasSuffix(key, manifestSuffix)
+}
+
+// Build the manifest for the files in the working directory of a
+// package version. Expects the version to be set.
+func (pv *PackageVersion) buildManifest() (*Manifest, error) {
+	paths, err := pathsUnderRoot(pv.DataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Name: pv.Name, Version: pv.Version}
+	for _, name := range paths {
+		localPath := filepath.Join(pv.DataPath, name)
+		fi, err := os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+		entry := ManifestEntry{
+			Name:    name,
+			Owner:   pv.fileMap[name].owner,
+			Group:   pv.groups[name],
+			Mode:    pv.fileMap[name].mode & 0777,
+			ModTime: fi.ModTime().UTC().Truncate(time.Second),
+		}
+		if !strings.HasSuffix(name, "/") {
+			entry.Size = fi.Size()
+			entry.Hash, err = fileHash(localPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		m.Files = append(m.Files, entry)
+	}
+
+	return m, nil
+}
+
+// Return the SHA-512, in hex, of a local file.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Write the tarball for a manifest, getting the contents of each file
+// from open. Everything in the tarball comes from the manifest, so
+// the same manifest always gives the same tarball.
+func writeTarball(w io.Writer, m *Manifest, open func(ManifestEntry) (io.ReadCloser, error)) error {
+	zipper := gzip.NewWriter(w)
+	tarball := tar.NewWriter(zipper)
+
+	for _, entry := range m.Files {
+		hdr := &tar.Header{
+			Name:    fmt.Sprintf("%s-%s/%s", m.Name, m.Version, entry.Name),
+			Mode:    int64(entry.Mode),
+			Uname:   entry.Owner,
+			Gname:   entry.Group,
+			ModTime: entry.ModTime,
+		}
+		if strings.HasSuffix(entry.Name, "/") {
+			hdr.Typeflag = tar.TypeDir
+			if err := tarball.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = entry.Size
+		if err := tarball.WriteHeader(hdr); err != nil {
+			return err
+		}
+		err := func() error {
+			in, err := open(entry)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			n, err := io.Copy(tarball, in)
+			if err == nil && n != entry.Size {
+				err = fmt.Errorf("file %s: saw %d bytes, expected %d", entry.Name, n, entry.Size)
+			}
+			return err
+		}()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tarball.Close(); err != nil {
+		return err
+	}
+	return zipper.Close()
+}
+
+// Store the file contents of a finished package version in the blob
+// store. Contents already there are not stored again. The keys of the
+// file blobs are returned, and stay pending (safe from garbage
+// collection) until they are released, even if this fails.
+func (ds *DataStore) storeFileBlobs(pv *PackageVersion) ([]string, error) {
+	m := pv.manifest
+	var keys []string
+	for _, entry := range m.Files {
+		if entry.Hash != "" {
+			keys = append(keys, entry.blobKey())
+		}
+	}
+	ds.pending.add(keys)
+
+	stored := 0
+	for _, entry := range m.Files {
+		if entry.Hash == "" {
+			continue
+		}
+		if _, err := ds.blobs.Stat(entry.blobKey()); err == nil {
+			continue
+		}
+		err := func() error {
+			in, err := os.Open(filepath.Join(pv.DataPath, entry.Name))
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			return ds.blobs.Put(entry.blobKey(), in, entry.Size)
+		}()
+		if err != nil {
+			return keys, err
+		}
+		stored++
+	}
+
+	log.WithFields(log.Fields{
+		"name":    pv.Name,
+		"version": pv.Version,
+		"files":   len(keys),
+		"stored":  stored,
+	}).Debug("stored file blobs")
+	return keys, nil
+}
+
+// Store the manifest of a finished package version in the blob
+// store, once its file contents are there. The working directory is
+// removed once the manifest is stored. Expects to be called with the
+// data store lock held.
+func (ds *DataStore) storeManifest(pv *PackageVersion) error {
+	m := pv.manifest
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s-%s%s", pv.Name, pv.Version, manifestSuffix)
+	err = ds.blobs.Put(key, strings.NewReader(string(buf)), int64(len(buf)))
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"name":    pv.Name,
+		"version": pv.Version,
+	}).Debug("stored manifest")
+
+	if err := os.RemoveAll(pv.DataPath); err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"workDir": pv.DataPath,
+		}).Warning("removing working directory")
+	}
+	pv.Blob = key
+	pv.DataPath = ds.blobPath(key)
+	pv.manifest = nil
+	return nil
+}
+
+// Read a manifest from the blob store.
+func (ds *DataStore) loadManifest(key string) (*Manifest, error) {
+	in, err := ds.blobs.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(in).Decode(&m); err != nil {
+		return nil, fmt.Errorf("manifest %s: %v", key, err)
+	}
+	return &m, nil
+}
+
+// Get the contents of a file in a manifest from the blob store.
+func (ds *DataStore) openFileBlob(entry ManifestEntry) (io.ReadCloser, error) {
+	return ds.blobs.Get(entry.blobKey())
+}
+
+// Rebuild the tarball of a package version stored as a manifest. The
+// tarball is written as it is read.
+func (ds *DataStore) openManifestTarball(key string) (io.ReadCloser, error) {
+	m, err := ds.loadManifest(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeTarball(w, m, ds.openFileBlob))
+	}()
+	return r, nil
+}
+
+// Compute the version of a package from a manifest in the blob
+// store, and the file contents it refers to.
+func (ds *DataStore) manifestVersion(key, name, version string) (*Manifest, string, error) {
+	m, err := ds.loadManifest(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if m.Name != name || m.Version != version {
+		return nil, "", fmt.Errorf("manifest %s is for %s-%s", key, m.Name, m.Version)
+	}
+
+	vh := NewVersionHash()
+	for _, entry := range m.Files {
+		if entry.Hash == "" {
+			vh.Add(entry.Name, entry.Owner, entry.Mode, nil)
+			continue
+		}
+		err := func() error {
+			in, err := ds.openFileBlob(entry)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			return vh.Add(entry.Name, entry.Owner, entry.Mode, in)
+		}()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return m, vh.Version(), nil
+}
+
+// File blobs in use by versions that are not in the catalog yet, so
+// the sweep must leave them alone. Keys released while a sweep is
+// running are kept until it is done, as the sweep may have looked at
+// the catalog before their version was in it.
+type pendingBlobs struct {
+	lock     sync.Mutex
+	uses     map[string]int
+	sweeping bool
+	released map[string]bool
+}
+
+func (pb *pendingBlobs) add(keys []string) {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	if pb.uses == nil {
+		pb.uses = make(map[string]int)
+	}
+	for _, key := range keys {
+		pb.uses[key]++
+	}
+}
+
+func (pb *pendingBlobs) release(keys []string) {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	for _, key := range keys {
+		pb.uses[key]--
+		if pb.uses[key] <= 0 {
+			delete(pb.uses, key)
+		}
+		if pb.sweeping {
+			pb.released[key] = true
+		}
+	}
+}
+
+func (pb *pendingBlobs) startSweep() {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	pb.sweeping = true
+	pb.released = make(map[string]bool)
+}
+
+func (pb *pendingBlobs) endSweep() {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	pb.sweeping = false
+	pb.released = nil
+}
+
+// Delete a blob, unless it is pending. This is done holding the lock,
+// so an upload either sees the blob gone, or keeps it.
+func (pb *pendingBlobs) deleteUnused(key string, blobs BlobStore) (bool, error) {
+	pb.lock.Lock()
+	defer pb.lock.Unlock()
+
+	if pb.uses[key] > 0 || pb.released[key] {
+		return false, nil
+	}
+	return true, blobs.Delete(key)
+}
+
+// Delete file contents no stored manifest refers to any more. This
+// reads every manifest, so is only done after versions are deleted,
+// and without holding the data store lock. Blobs of versions being
+// added are pending, and left alone.
+func (ds *DataStore) sweepFileBlobs() error {
+	ds.sweepLock.Lock()
+	defer ds.sweepLock.Unlock()
+	ds.pending.startSweep()
+	defer ds.pending.endSweep()
+
+	var keys []string
+	ds.lock.Lock()
+	for _, p := range ds.packages {
+		p.lock.Lock()
+		for _, pv := range p.versions {
+			if isManifest(pv.Blob) {
+				keys = append(keys, pv.Blob)
+			}
+		}
+		p.lock.Unlock()
+	}
+	ds.lock.Unlock()
+
+	referenced := make(map[string]bool)
+	for _, key := range keys {
+		m, err := ds.loadManifest(key)
+		if err != nil {
+			// Better to keep too much than to lose
+			// something still in use.
+			return err
+		}
+		for _, entry := range m.Files {
+			if entry.Hash != "" {
+				referenced[entry.blobKey()] = true
+			}
+		}
+	}
+
+	blobs, err := ds.blobs.List()
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for _, blob := range blobs {
+		if !fileBlobName.MatchString(blob.Key) || referenced[blob.Key] {
+			continue
+		}
+		deleted, err := ds.pending.deleteUnused(blob.Key, ds.blobs)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			removed++
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"removed": removed,
+	}).Debug("swept file blobs")
+	return nil
+}
+
+// Build the manifest entries for a package tarball, as produced by
+// PackageVersion.Finish before versions were stored as manifests.
+func entriesFromTarball(r io.Reader, name, version string) ([]ManifestEntry, error) {
+	unzipper, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer unzipper.Close()
+
+	prefix := fmt.Sprintf("%s-%s/", name, version)
+	var rv []ManifestEntry
+	tarball := tar.NewReader(unzipper)
+	for {
+		hdr, err := tarball.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(hdr.Name, prefix) {
+			return nil, fmt.Errorf("unexpected entry %s in tarball", hdr.Name)
+		}
+
+		entry := ManifestEntry{
+			Name:    strings.TrimPrefix(hdr.Name, prefix),
+			Owner:   hdr.Uname,
+			Group:   hdr.Gname,
+			Mode:    int32(hdr.Mode & 0777),
+			ModTime: hdr.ModTime.UTC(),
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			if !strings.HasSuffix(entry.Name, "/") {
+				entry.Name = entry.Name + "/"
+			}
+		} else {
+			h := sha512.New()
+			entry.Size, err = io.Copy(h, tarball)
+			if err != nil {
+				return nil, err
+			}
+			entry.Hash = fmt.Sprintf("%x", h.Sum(nil))
+		}
+		rv = append(rv, entry)
+	}
+
+	return rv, nil
+}
+
+// List the files in a package version. Versions stored as a tarball
+// have the list built by reading the tarball.
+func (ds *DataStore) ListFiles(pv PackageVersion) ([]ManifestEntry, error) {
+	m, err := ds.PackageManifest(pv)
+	if err == nil {
+		return m.Files, nil
+	}
+	if !errors.Is(err, ErrNoManifest) {
+		return nil, err
+	}
+
+	in, err := ds.openTarball(pv)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return entriesFromTarball(in, pv.Name, pv.Version)
+}
+
+// The contents of a single file in a package tarball. Closing it
+// closes the tarball.
+type tarballFile struct {
+	io.Reader
+	io.Closer
+}
+
+// Open a single file in a package version, returning what the
+// manifest says about it along with the contents. The name may start
+// with a "/". Versions stored as a tarball are read up to the file,
+// and have no hash in the entry.
+func (ds *DataStore) OpenFile(pv PackageVersion, name string) (ManifestEntry, io.ReadCloser, error) {
+	name = strings.TrimPrefix(name, "/")
+	m, err := ds.PackageManifest(pv)
+	if errors.Is(err, ErrNoManifest) {
+		return ds.openTarballFile(pv, name)
+	}
+	if err != nil {
+		return ManifestEntry{}, nil, err
+	}
+
+	for _, entry := range m.Files {
+		if entry.Name == name || entry.Name == name+"/" {
+			if entry.Type() == "dir" {
+				return entry, nil, fmt.Errorf("package %s version %s, %s: %w", pv.Name, pv.Version, entry.Name, ErrIsDirectory)
+			}
+			in, err := ds.openFileBlob(entry)
+			return entry, in, err
+		}
+	}
+	return ManifestEntry{}, nil, fmt.Errorf("package %s version %s, file %s: %w", pv.Name, pv.Version, name, ErrNotFound)
+}
+
+// Find a single file in a package tarball.
+func (ds *DataStore) openTarballFile(pv PackageVersion, name string) (ManifestEntry, io.ReadCloser, error) {
+	in, err := ds.openTarball(pv)
+	if err != nil {
+		return ManifestEntry{}, nil, err
+	}
+	unzipper, err := gzip.NewReader(in)
+	if err != nil {
+		in.Close()
+		return ManifestEntry{}, nil, err
+	}
+
+	prefix := fmt.Sprintf("%s-%s/", pv.Name, pv.Version)
+	tarball := tar.NewReader(unzipper)
+	for {
+		hdr, err := tarball.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			in.Close()
+			return ManifestEntry{}, nil, err
+		}
+		fname := strings.TrimPrefix(hdr.Name, prefix)
+		if strings.TrimSuffix(fname, "/") != name {
+			continue
+		}
+
+		entry := ManifestEntry{
+			Name:    fname,
+			Owner:   hdr.Uname,
+			Group:   hdr.Gname,
+			Mode:    int32(hdr.Mode & 0777),
+			ModTime: hdr.ModTime.UTC(),
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			in.Close()
+			if !strings.HasSuffix(entry.Name, "/") {
+				entry.Name = entry.Name + "/"
+			}
+			return entry, nil, fmt.Errorf("package %s version %s, %s: %w", pv.Name, pv.Version, entry.Name, ErrIsDirectory)
+		}
+		entry.Size = hdr.Size
+		return entry, tarballFile{Reader: tarball, Closer: in}, nil
+	}
+
+	in.Close()
+	return ManifestEntry{}, nil, fmt.Errorf("package %s version %s, file %s: %w", pv.Name, pv.Version, name, ErrNotFound)
+}
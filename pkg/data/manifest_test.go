@@ -0,0 +1,288 @@
+package data
+
+import (
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// The keys of the file contents in a data store's blob store.
+func storedFileBlobs(t *testing.T, ds *DataStore) []string {
+	blobs, err := ds.blobs.List()
+	if err != nil {
+		t.Fatalf("Failed to list blobs: %v", err)
+	}
+	var rv []string
+	for _, blob := range blobs {
+		if fileBlobName.MatchString(blob.Key) {
+			rv = append(rv, blob.Key)
+		}
+	}
+	return rv
+}
+
+func TestManifestDeduplication(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	// The same contents in two packages, and a changed file in a
+	// second version of one of them.
+	pvs := []PackageVersion{
+		buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v1\n"),
+		buildTestPackage(t, ds, "bar", "#!/bin/sh\necho v1\n"),
+		buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v2\n"),
+	}
+	for ix, pv := range pvs {
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding version %d: %v", ix, err)
+		}
+	}
+	if blobs := storedFileBlobs(t, ds); len(blobs) != 2 {
+		t.Errorf("Expected two file blobs, saw %v", blobs)
+	}
+	if files := filesUnderDir(t, ds.playground); len(files) != 0 {
+		t.Errorf("Expected an empty playground, saw %v", files)
+	}
+
+	for _, want := range pvs {
+		pv, err := ds.GetPackageVersion(want.Name, want.Version)
+		if err != nil {
+			t.Fatalf("Missing %s-%s: %v", want.Name, want.Version, err)
+		}
+		if !isManifest(pv.Blob) {
+			t.Errorf("Expected %s-%s to be stored as a manifest, saw %q", pv.Name, pv.Version, pv.Blob)
+		}
+
+		// The rebuilt tarball matches what Finish recorded,
+		// and has the right contents.
+		in, err := ds.OpenPackageData(pv)
+		if err != nil {
+			t.Fatalf("Unexpected error opening %s-%s: %v", pv.Name, pv.Version, err)
+		}
+		checksum := sha512.New()
+		tee := io.TeeReader(in, checksum)
+		seen, err := versionFromTarball(tee, pv.Name, pv.Version)
+		if err == nil {
+			_, err = io.Copy(checksum, in)
+		}
+		in.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s-%s: %v", pv.Name, pv.Version, err)
+		}
+		if seen != pv.Version {
+			t.Errorf("Tarball for %s-%s hashes to %s", pv.Name, pv.Version, seen)
+		}
+		if sum := fmt.Sprintf("%x", checksum.Sum(nil)); sum != pv.Checksum {
+			t.Errorf("Tarball for %s-%s has checksum %s, expected %s", pv.Name, pv.Version, sum, pv.Checksum)
+		}
+	}
+}
+
+func TestManifestDeletionSweepsFileBlobs(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	v1 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v1\n")
+	shared := buildTestPackage(t, ds, "bar", "#!/bin/sh\necho v1\n")
+	v2 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v2\n")
+	for _, pv := range []PackageVersion{v1, shared, v2} {
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding %s-%s: %v", pv.Name, pv.Version, err)
+		}
+	}
+
+	// The contents of foo v1 are still used by bar.
+	if _, err := ds.DeleteVersion("foo", v1.Version, "alice", false); err != nil {
+		t.Fatalf("Unexpected error deleting v1: %v", err)
+	}
+	if blobs := storedFileBlobs(t, ds); len(blobs) != 2 {
+		t.Errorf("Expected two file blobs after deleting foo v1, saw %v", blobs)
+	}
+
+	if _, err := ds.DeletePackage("bar", "alice", true); err != nil {
+		t.Fatalf("Unexpected error deleting bar: %v", err)
+	}
+	if blobs := storedFileBlobs(t, ds); len(blobs) != 1 {
+		t.Errorf("Expected one file blob after deleting bar, saw %v", blobs)
+	}
+
+	pv, _ := ds.GetPackageVersion("foo", v2.Version)
+	in, err := ds.OpenPackageData(pv)
+	if err != nil {
+		t.Fatalf("Unexpected error opening v2: %v", err)
+	}
+	defer in.Close()
+	if seen, err := versionFromTarball(in, "foo", v2.Version); err != nil || seen != v2.Version {
+		t.Errorf("Remaining version hashes to %s (%v)", seen, err)
+	}
+}
+
+func TestSweepKeepsPendingFileBlobs(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	// The file contents of an upload on its way into the catalog
+	// are in the blob store, but no manifest refers to them yet.
+	pv := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v1\n")
+	keys, err := ds.storeFileBlobs(&pv)
+	if err != nil {
+		t.Fatalf("Unexpected error storing file blobs: %v", err)
+	}
+	if err := ds.sweepFileBlobs(); err != nil {
+		t.Fatalf("Unexpected error sweeping: %v", err)
+	}
+	if blobs := storedFileBlobs(t, ds); len(blobs) != len(keys) {
+		t.Errorf("Sweep removed pending blobs, saw %v, want %v", blobs, keys)
+	}
+
+	ds.pending.release(keys)
+	if err := ds.sweepFileBlobs(); err != nil {
+		t.Fatalf("Unexpected error sweeping: %v", err)
+	}
+	if blobs := storedFileBlobs(t, ds); len(blobs) != 0 {
+		t.Errorf("Expected released blobs to be swept, saw %v", blobs)
+	}
+}
+
+func TestRecoverFromManifests(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	v1 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v1\n")
+	v2 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v2\n")
+	for _, pv := range []PackageVersion{v1, v2} {
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding %s: %v", pv.Version, err)
+		}
+	}
+
+	// A manifest referring to contents that are not there does
+	// not verify.
+	broken := fmt.Sprintf("bar-%s%s", strings.Repeat("ab", 64), manifestSuffix)
+	manifest := fmt.Sprintf(`{"name":"bar","version":"%s","files":[{"name":"x","size":1,"hash":"%s"}]}`, strings.Repeat("ab", 64), strings.Repeat("cd", 64))
+	if err := ds.blobs.Put(broken, strings.NewReader(manifest), int64(len(manifest))); err != nil {
+		t.Fatalf("Failed to store broken manifest: %v", err)
+	}
+
+	if err := os.Remove(ds.catalogPath()); err != nil {
+		t.Fatalf("Failed to remove catalog: %v", err)
+	}
+	fresh, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error creating data store: %v", err)
+	}
+	if err := fresh.Recover("latest"); err == nil {
+		t.Errorf("Expected an error for the broken manifest")
+	}
+
+	for _, want := range []PackageVersion{v1, v2} {
+		pv, err := fresh.GetPackageVersion("foo", want.Version)
+		if err != nil {
+			t.Fatalf("Version %s not recovered: %v", want.Version, err)
+		}
+		if pv.Size != want.Size || pv.Checksum != want.Checksum {
+			t.Errorf("Recovered %s with size %d and checksum %s, expected %d and %s", pv.Version, pv.Size, pv.Checksum, want.Size, want.Checksum)
+		}
+	}
+	if _, ok := fresh.GetPackageVersions("bar"); ok {
+		t.Errorf("Did not expect bar to be recovered")
+	}
+}
+
+var listFilesTestFiles = []*pb.File{
+	{Name: "bin/", Owner: "root", Group: "wheel", Mode: 0755},
+	{Name: "bin/start", Owner: "root", Group: "wheel", Mode: 0750, Contents: []byte("start")},
+}
+
+// A data store with the same files in a version stored as a manifest,
+// and in version "beef", stored as a tarball the way versions used to
+// be. Returns the version of the first.
+func newFilesTestStore(t *testing.T) (*DataStore, string, string) {
+	ds, dir := newTestStore(t)
+	pv := addFilesPackage(t, ds, "foo", listFilesTestFiles)
+
+	legacy := newPackageVersion("foo", "beef")
+	legacy.DataPath = filepath.Join(ds.store, "foo-beef.tgz")
+	out, err := os.Create(legacy.DataPath)
+	if err != nil {
+		t.Fatalf("Failed to create tarball: %v", err)
+	}
+	m, _ := ds.PackageManifest(pv)
+	m.Version = "beef"
+	err = writeTarball(out, m, ds.openFileBlob)
+	out.Close()
+	if err != nil {
+		t.Fatalf("Failed to write tarball: %v", err)
+	}
+	if err := ds.AddPackageVersion(legacy); err != nil {
+		t.Fatalf("Failed to add legacy version: %v", err)
+	}
+
+	return ds, dir, pv.Version
+}
+
+func TestDataStoreListFiles(t *testing.T) {
+	ds, dir, version := newFilesTestStore(t)
+	defer os.RemoveAll(dir)
+	files := listFilesTestFiles
+
+	for _, version := range []string{version, "beef"} {
+		stored, _ := ds.GetPackageVersion("foo", version)
+		entries, err := ds.ListFiles(stored)
+		if err != nil {
+			t.Fatalf("Unexpected error listing %s: %v", version, err)
+		}
+		if len(entries) != len(files) {
+			t.Fatalf("Listing %s saw %d entries, want %d", version, len(entries), len(files))
+		}
+		for ix, f := range files {
+			e := entries[ix]
+			if e.Name != f.Name || e.Owner != f.Owner || e.Group != f.Group || e.Mode != f.Mode || e.Size != int64(len(f.Contents)) {
+				t.Errorf("Listing %s saw %+v, want %v", version, e, f)
+			}
+		}
+		if entries[0].Type() != "dir" || entries[1].Type() != "file" {
+			t.Errorf("Listing %s saw types %s and %s", version, entries[0].Type(), entries[1].Type())
+		}
+		if want := fmt.Sprintf("%x", sha512.Sum512([]byte("start"))); entries[1].Hash != want {
+			t.Errorf("Listing %s saw hash %s, want %s", version, entries[1].Hash, want)
+		}
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	ds, dir, version := newFilesTestStore(t)
+	defer os.RemoveAll(dir)
+
+	for _, version := range []string{version, "beef"} {
+		pv, _ := ds.GetPackageVersion("foo", version)
+
+		entry, in, err := ds.OpenFile(pv, "/bin/start")
+		if err != nil {
+			t.Fatalf("Unexpected error opening bin/start in %s: %v", version, err)
+		}
+		contents, err := ioutil.ReadAll(in)
+		in.Close()
+		if err != nil || string(contents) != "start" {
+			t.Errorf("Read %q (%v) from %s, want start", contents, err, version)
+		}
+		if entry.Name != "bin/start" || entry.Size != 5 || entry.Mode != 0750 {
+			t.Errorf("Saw entry %+v in %s", entry, version)
+		}
+
+		if _, _, err := ds.OpenFile(pv, "bin"); !errors.Is(err, ErrIsDirectory) {
+			t.Errorf("Expected ErrIsDirectory opening bin in %s, saw %v", version, err)
+		}
+		if _, _, err := ds.OpenFile(pv, "bin/stop"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound opening bin/stop in %s, saw %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Recovery of the catalog from the package manifests and tarballs in
+// the blob store. This is used when the catalog has been lost, or the store
+// has been copied to a new host without it.
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Tarballs are named <name>-<sha512 in hex>.tgz, and package names
+// may themselves contain dashes.
+var tarballName = regexp.MustCompile(`^(.+)-([0-9a-f]{128})\.tgz$`)
+
+// Split a tarball file name into package name and version. The bool
+// is false if the name does not look like a package tarball.
+func splitTarballName(fname string) (string, string, bool) {
+	m := tarballName.FindStringSubmatch(fname)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Compute the version of a package from a tarball, as produced by
+// PackageVersion.Finish.
+func versionFromTarball(r io.Reader, name, version string) (string, error) {
+	unzipper, err := gzip.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	defer unzipper.Close()
+
+	prefix := fmt.Sprintf("%s-%s/", name, version)
+	vh := NewVersionHash()
+	tarball := tar.NewReader(unzipper)
+	for {
+		hdr, err := tarball.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(hdr.Name, prefix) {
+			return "", fmt.Errorf("unexpected entry %s in tarball", hdr.Name)
+		}
+		fname := strings.TrimPrefix(hdr.Name, prefix)
+		mode := int32(hdr.Mode & 0777)
+		if hdr.Typeflag == tar.TypeDir {
+			if !strings.HasSuffix(fname, "/") {
+				fname = fname + "/"
+			}
+			err = vh.Add(fname, hdr.Uname, mode, nil)
+		} else {
+			err = vh.Add(fname, hdr.Uname, mode, tarball)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return vh.Version(), nil
+}
+
+// Compute the version of a package from a tarball in the blob store.
+func (ds *DataStore) blobVersion(key, name, version string) (string, error) {
+	in, err := ds.blobs.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	return versionFromTarball(in, name, version)
+}
+
+// List the blob store, verify every package manifest and tarball in
+// it and add the ones that are not already known to the data store.
+// Each recovered version gets the label defaultLabel (unless it is
+// empty). Blobs are processed oldest first, so "latest" ends up on
+// the most recent one.
+//
+// Versions that fail verification are logged and skipped; if there
+// were any, an error is returned after all others have been added.
+func (ds *DataStore) Recover(defaultLabel string) error {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	blobs, err := ds.blobs.List()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"store": ds.store,
+		}).Error("Recover - listing blobs")
+		return err
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].ModTime.Before(blobs[j].ModTime)
+	})
+
+	failed := 0
+	recovered := 0
+	for _, blob := range blobs {
+		name, version, ok := splitTarballName(blob.Key)
+		if !ok {
+			name, version, ok = splitManifestName(blob.Key)
+		}
+		if !ok {
+			continue
+		}
+
+		p, ok := ds.packages[name]
+		if ok {
+			if _, ok := p.versions[version]; ok {
+				log.WithFields(log.Fields{
+					"name":    name,
+					"version": version,
+				}).Debug("Recover - version already known")
+				continue
+			}
+		}
+
+		var m *Manifest
+		var seen string
+		if isManifest(blob.Key) {
+			m, seen, err = ds.manifestVersion(blob.Key, name, version)
+		} else {
+			seen, err = ds.blobVersion(blob.Key, name, version)
+		}
+		if err != nil || seen != version {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"blob":    blob.Key,
+				"version": version,
+				"seen":    seen,
+			}).Error("Recover - tarball failed verification")
+			failed++
+			continue
+		}
+
+		if p == nil {
+			p = newPackage(name)
+			ds.packages[name] = p
+		}
+		pv := PackageVersion{
+			Name:     name,
+			Version:  version,
+			Labels:   make(map[string]struct{}),
+			DataPath: ds.blobPath(blob.Key),
+			Blob:     blob.Key,
+			Created:  blob.ModTime.UTC(),
+		}
+		if m != nil {
+			pv.Size = m.Size
+			pv.Checksum = m.Checksum
+		}
+		err = p.AddVersion(pv)
+		if err == nil && defaultLabel != "" {
+			err = p.SetLabel(version, defaultLabel)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":   err,
+				"name":    name,
+				"version": version,
+			}).Error("Recover - adding version")
+			failed++
+			continue
+		}
+		recovered++
+	}
+
+	log.WithFields(log.Fields{
+		"recovered": recovered,
+		"failed":    failed,
+	}).Info("Recover - done")
+
+	err = ds.saveCatalog()
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d tarball(s) could not be recovered", failed)
+	}
+	return nil
+}
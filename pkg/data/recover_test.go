@@ -0,0 +1,117 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Build and finish a small package version in the data store's playground.
+func buildTestPackage(t *testing.T, ds *DataStore, name, contents string) PackageVersion {
+	if err := os.MkdirAll(filepath.Join(ds.playground, "tmp", name), 0755); err != nil {
+		t.Fatalf("Failed to create playground: %v", err)
+	}
+	pv, err := ds.NewPackageVersion(name)
+	if err != nil {
+		t.Fatalf("Failed to create package version: %v", err)
+	}
+	if err := pv.AddDir(&pb.File{Name: "bin/", Owner: "root", Mode: 0755}); err != nil {
+		t.Fatalf("Failed to add dir: %v", err)
+	}
+	if err := pv.AddFile(&pb.File{Name: "bin/start", Owner: "root", Mode: 0755, Contents: []byte(contents)}); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	if err := pv.Finish(); err != nil {
+		t.Fatalf("Failed to finish: %v", err)
+	}
+
+	return pv
+}
+
+func TestSplitTarballName(t *testing.T) {
+	version := strings.Repeat("ab", 64)
+	testcases := []struct {
+		fname   string
+		name    string
+		version string
+		ok      bool
+	}{
+		{"foo-" + version + ".tgz", "foo", version, true},
+		{"foo-bar-" + version + ".tgz", "foo-bar", version, true},
+		{"foo-" + version, "", "", false},
+		{"foo-beef.tgz", "", "", false},
+		{"catalog.json", "", "", false},
+	}
+
+	for ix, tc := range testcases {
+		name, version, ok := splitTarballName(tc.fname)
+		if ok != tc.ok || name != tc.name || version != tc.version {
+			t.Errorf("Case #%d, saw (%s, %s, %v), want (%s, %s, %v)", ix, name, version, ok, tc.name, tc.version, tc.ok)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	pv1 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v1\n")
+	if err := ds.AddPackageVersion(pv1); err != nil {
+		t.Fatalf("Unexpected error adding pv1: %v", err)
+	}
+	pv2 := buildTestPackage(t, ds, "foo", "#!/bin/sh\necho v2\n")
+	if err := ds.AddPackageVersion(pv2); err != nil {
+		t.Fatalf("Unexpected error adding pv2: %v", err)
+	}
+
+	// Lose the catalog.
+	if err := os.Remove(ds.catalogPath()); err != nil {
+		t.Fatalf("Failed to remove catalog: %v", err)
+	}
+	fresh, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error creating data store: %v", err)
+	}
+	if err := fresh.Recover("recovered"); err != nil {
+		t.Fatalf("Unexpected error recovering: %v", err)
+	}
+
+	for _, want := range []string{pv1.Version, pv2.Version} {
+		if _, err := fresh.GetPackageVersion("foo", want); err != nil {
+			t.Errorf("Version %s not recovered: %v", want, err)
+		}
+	}
+	if _, err := fresh.GetPackageVersion("foo", "recovered"); err != nil {
+		t.Errorf("Label not set on recovered version: %v", err)
+	}
+
+	// And the recovered catalog should have been written out.
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if pvs, _ := reloaded.GetPackageVersions("foo"); len(pvs) != 2 {
+		t.Errorf("Saw %d versions after reload, want 2", len(pvs))
+	}
+}
+
+func TestRecoverBadTarball(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	fname := "foo-" + strings.Repeat("0", 128) + ".tgz"
+	if err := ioutil.WriteFile(filepath.Join(ds.store, fname), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("Failed to write tarball: %v", err)
+	}
+
+	if err := ds.Recover(""); err == nil {
+		t.Errorf("Expected error recovering a bad tarball, saw none")
+	}
+	if _, ok := ds.GetPackageVersions("foo"); ok {
+		t.Errorf("Bad tarball should not have been registered")
+	}
+}
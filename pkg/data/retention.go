@@ -0,0 +1,225 @@
+// Retention policies decide which versions of a package are worth
+// keeping. Everything else can be garbage collected, so the store does
+// not grow forever.
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A RetentionPolicy is a list of rules, the first rule matching a
+// package name applies to it. Packages no rule matches are kept in
+// full.
+type RetentionPolicy struct {
+	Rules []RetentionRule `json:"rules"`
+}
+
+// A retention rule applies to packages matching any of Packages
+// (path.Match patterns). A version is kept if it is one of the KeepLast
+// newest, if it has a label and KeepLabelled is set, or if it is
+// younger than MaxAgeDays. Everything else is deleted. Versions with
+// protected labels, the version labelled "latest" and the newest
+// version are always kept, so a package is never emptied, even with
+// DisableLatest set.
+type RetentionRule struct {
+	Packages     []string `json:"packages"`
+	KeepLast     int      `json:"keepLast,omitempty"`
+	KeepLabelled bool     `json:"keepLabelled,omitempty"`
+	MaxAgeDays   int      `json:"maxAgeDays,omitempty"`
+}
+
+// Read a retention policy from a JSON file. A rule must keep at least
+// something, by setting KeepLast or MaxAgeDays.
+func LoadRetentionPolicy(filename string) (*RetentionPolicy, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv RetentionPolicy
+	err = json.Unmarshal(buf, &rv)
+	if err != nil {
+		return nil, err
+	}
+	for ix, rule := range rv.Rules {
+		if len(rule.Packages) == 0 {
+			return nil, fmt.Errorf("retention rule #%d has no packages", ix)
+		}
+		for _, p := range rule.Packages {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, err
+			}
+		}
+		if rule.KeepLast < 0 || rule.MaxAgeDays < 0 {
+			return nil, fmt.Errorf("retention rule #%d has a negative limit", ix)
+		}
+		if rule.KeepLast == 0 && rule.MaxAgeDays == 0 {
+			return nil, fmt.Errorf("retention rule #%d would delete every version, set keepLast or maxAgeDays", ix)
+		}
+	}
+
+	return &rv, nil
+}
+
+// Return the rule applying to a package, or nil if there is none.
+func (rp *RetentionPolicy) ruleFor(pkg string) *RetentionRule {
+	for ix, rule := range rp.Rules {
+		for _, p := range rule.Packages {
+			if ok, _ := path.Match(p, pkg); ok {
+				return &rp.Rules[ix]
+			}
+		}
+	}
+	return nil
+}
+
+// Return the versions of a package the rule does not keep. Expects
+// to be called with the package lock held.
+func (r *RetentionRule) expired(p *Package, protected map[string]bool, now time.Time) []*PackageVersion {
+	var versions []*PackageVersion
+	for _, pv := range p.versions {
+		versions = append(versions, pv)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if !versions[i].Created.Equal(versions[j].Created) {
+			return versions[i].Created.After(versions[j].Created)
+		}
+		return versions[i].Version < versions[j].Version
+	})
+
+	maxAge := time.Duration(r.MaxAgeDays) * 24 * time.Hour
+	var rv []*PackageVersion
+	for ix, pv := range versions {
+		keep := ix == 0 || ix < r.KeepLast ||
+			(r.KeepLabelled && len(pv.Labels) > 0) ||
+			(r.MaxAgeDays > 0 && now.Sub(pv.Created) < maxAge) ||
+			isLatest(pv) ||
+			hasProtectedLabel(pv, protected)
+		if !keep {
+			rv = append(rv, pv)
+		}
+	}
+
+	return rv
+}
+
+func isLatest(pv *PackageVersion) bool {
+	_, ok := pv.Labels["latest"]
+	return ok
+}
+
+func hasProtectedLabel(pv *PackageVersion, protected map[string]bool) bool {
+	for label := range pv.Labels {
+		if protected[label] {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the versions, per package, the policy does not keep. Expects
+// to be called with the data store lock held.
+func (ds *DataStore) expiredVersions(policy *RetentionPolicy, now time.Time) map[*Package][]*PackageVersion {
+	rv := make(map[*Package][]*PackageVersion)
+	for name, p := range ds.packages {
+		rule := policy.ruleFor(name)
+		if rule == nil {
+			continue
+		}
+		p.lock.Lock()
+		if expired := rule.expired(p, ds.protected, now); len(expired) > 0 {
+			rv[p] = expired
+		}
+		p.lock.Unlock()
+	}
+	return rv
+}
+
+// Flatten the expired versions into a list, sorted by package name,
+// oldest first within each package.
+func expiredList(expired map[*Package][]*PackageVersion) []PackageVersion {
+	var rv []PackageVersion
+	for _, pvs := range expired {
+		for _, pv := range pvs {
+			rv = append(rv, *pv)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Name != rv[j].Name {
+			return rv[i].Name < rv[j].Name
+		}
+		return rv[i].Created.Before(rv[j].Created)
+	})
+	return rv
+}
+
+// Return the versions the retention policy would delete, as of now,
+// without deleting anything.
+func (ds *DataStore) ExpiredVersions(policy *RetentionPolicy, now time.Time) []PackageVersion {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	return expiredList(ds.expiredVersions(policy, now))
+}
+
+// Delete the versions the retention policy does not keep, as of now,
+// returning them. The deletions are recorded as done by identity.
+func (ds *DataStore) CollectGarbage(policy *RetentionPolicy, now time.Time, identity string) ([]PackageVersion, error) {
+	rv, err := ds.collectGarbage(policy, now, identity)
+	if err == nil {
+		ds.sweepRemoved(rv)
+	}
+	return rv, err
+}
+
+// Delete the versions the retention policy does not keep, as
+// CollectGarbage, leaving the file contents for the caller to sweep.
+func (ds *DataStore) collectGarbage(policy *RetentionPolicy, now time.Time, identity string) ([]PackageVersion, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	expired := ds.expiredVersions(policy, now)
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	rv := expiredList(expired)
+
+	var names []string
+	for p := range expired {
+		names = append(names, p.name)
+	}
+	cp := ds.checkpoint(names...)
+	for p, pvs := range expired {
+		p.lock.Lock()
+		for _, pv := range pvs {
+			p.removeVersion(pv, identity)
+		}
+		empty := len(p.versions) == 0
+		p.lock.Unlock()
+		if empty {
+			delete(ds.packages, p.name)
+		}
+	}
+	for _, pv := range rv {
+		ds.recordDeletion(pv.Name, pv.Version, identity)
+	}
+
+	err := ds.commitCatalog(cp)
+	if err != nil {
+		return nil, err
+	}
+	ds.removeTarballs(rv)
+
+	log.WithFields(log.Fields{
+		"deleted":  len(rv),
+		"identity": identity,
+	}).Info("garbage collected package versions")
+	return rv, nil
+}
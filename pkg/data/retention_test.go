@@ -0,0 +1,154 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRetentionPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-retention")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		policy string
+		ok     bool
+	}{
+		{`{"rules": [{"packages": ["web-*"], "keepLast": 3, "keepLabelled": true}]}`, true},
+		{`{"rules": [{"packages": ["*"], "maxAgeDays": 30}]}`, true},
+		{`{"rules": [{"packages": ["*"], "keepLabelled": true}]}`, false},
+		{`{"rules": [{"keepLast": 3}]}`, false},
+		{`{"rules": [{"packages": ["[web"], "keepLast": 3}]}`, false},
+		{`{"rules": [{"packages": ["*"], "keepLast": -1, "maxAgeDays": 3}]}`, false},
+	}
+	for ix, c := range cases {
+		filename := filepath.Join(dir, "retention.json")
+		if err := ioutil.WriteFile(filename, []byte(c.policy), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+		_, err := LoadRetentionPolicy(filename)
+		if (err == nil) != c.ok {
+			t.Errorf("Case #%d, expected ok=%v, saw %v", ix, c.ok, err)
+		}
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+	ds.SetProtectedLabels([]string{"prod"})
+
+	// Versions v1 (oldest) to v6, a day apart, and one version of
+	// a package no rule applies to.
+	now := time.Now().UTC()
+	for ix, version := range []string{"v1", "v2", "v3", "v4", "v5", "v6"} {
+		pv := newPackageVersion("foo", version)
+		pv.DataPath = filepath.Join(ds.store, "foo-"+version+".tgz")
+		pv.Created = now.Add(time.Duration(ix-6) * 24 * time.Hour)
+		if err := ioutil.WriteFile(pv.DataPath, []byte(version), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", pv.DataPath, err)
+		}
+		if err := ds.AddPackageVersion(pv); err != nil {
+			t.Fatalf("Unexpected error adding %s: %v", version, err)
+		}
+	}
+	other := newPackageVersion("bar", "v1")
+	other.DataPath = filepath.Join(ds.store, "bar-v1.tgz")
+	other.Created = now.Add(-100 * 24 * time.Hour)
+	ds.AddPackageVersion(other)
+	ds.SetLabel("foo", "v1", "prod")
+	ds.SetLabel("foo", "v2", "staging")
+
+	// A package that never gets latest, with only old versions.
+	ds.SetPackageOptions("qux", PackageOptions{DisableLatest: true})
+	for ix, version := range []string{"v1", "v2"} {
+		pv := newPackageVersion("qux", version)
+		pv.DataPath = filepath.Join(ds.store, "qux-"+version+".tgz")
+		pv.Created = now.Add(time.Duration(ix-100) * 24 * time.Hour)
+		ds.AddPackageVersion(pv)
+	}
+
+	versions := func(pvs []PackageVersion) []string {
+		var rv []string
+		for _, pv := range pvs {
+			rv = append(rv, pv.Name+":"+pv.Version)
+		}
+		return rv
+	}
+
+	cases := []struct {
+		rule     RetentionRule
+		expected []string
+	}{
+		{RetentionRule{Packages: []string{"foo"}, KeepLast: 2}, []string{"foo:v2", "foo:v3", "foo:v4"}},
+		{RetentionRule{Packages: []string{"f*"}, KeepLast: 2, KeepLabelled: true}, []string{"foo:v3", "foo:v4"}},
+		{RetentionRule{Packages: []string{"foo"}, MaxAgeDays: 3, KeepLabelled: true}, []string{"foo:v3", "foo:v4"}},
+		{RetentionRule{Packages: []string{"baz"}, KeepLast: 1}, nil},
+		// The only version of bar is too old, but it is the
+		// latest one.
+		{RetentionRule{Packages: []string{"bar"}, MaxAgeDays: 30}, nil},
+		// Without latest, the newest version is kept anyway.
+		{RetentionRule{Packages: []string{"qux"}, MaxAgeDays: 30}, []string{"qux:v1"}},
+	}
+	for ix, c := range cases {
+		policy := &RetentionPolicy{Rules: []RetentionRule{c.rule}}
+		seen := versions(ds.ExpiredVersions(policy, now))
+		if !sameStrings(seen, c.expected) {
+			t.Errorf("Case #%d, expected %v, saw %v", ix, c.expected, seen)
+		}
+	}
+
+	// The first matching rule applies.
+	policy := &RetentionPolicy{Rules: []RetentionRule{
+		{Packages: []string{"foo"}, KeepLast: 4},
+		{Packages: []string{"*"}, MaxAgeDays: 30},
+	}}
+	deleted, err := ds.CollectGarbage(policy, now, "retention")
+	if err != nil {
+		t.Fatalf("Unexpected error collecting garbage: %v", err)
+	}
+	if seen := versions(deleted); !sameStrings(seen, []string{"foo:v2", "qux:v1"}) {
+		t.Fatalf("Expected foo:v2 and qux:v1 deleted, saw %v", seen)
+	}
+	if _, err := os.Stat(deleted[0].DataPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the tarball of v2 to be removed, saw %v", err)
+	}
+	if _, err := ds.GetPackageVersion("bar", "v1"); err != nil {
+		t.Errorf("Expected bar to keep its latest version, saw %v", err)
+	}
+	if _, err := ds.GetPackageVersion("qux", "v2"); err != nil {
+		t.Errorf("Expected qux to keep its newest version, saw %v", err)
+	}
+	if deletions := ds.Deletions(); len(deletions) != 2 || deletions[0].Identity != "retention" {
+		t.Errorf("Expected the deletions to be recorded, saw %v", deletions)
+	}
+	if events, _ := ds.LabelHistory("foo", "staging"); events[len(events)-1].New != "" {
+		t.Errorf("Expected staging to be removed with v2, saw %v", events)
+	}
+
+	reloaded, err := NewDataStore(ds.playground, ds.store)
+	if err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	pv, err := reloaded.GetPackageVersion("foo", "v3")
+	if err != nil || !pv.Created.Equal(now.Add(-4*24*time.Hour)) {
+		t.Errorf("Expected v3 to keep its creation time, saw %v (%v)", pv.Created, err)
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ix := range a {
+		if a[ix] != b[ix] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,347 @@
+// A blob store in an S3-compatible object store, talking plain HTTP
+// with AWS Signature Version 4 request signing. Buckets are addressed
+// path-style (<endpoint>/<bucket>/<key>), which every S3-compatible
+// store supports.
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The payload hash used when the body is not signed, so it can be
+// streamed.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// The SHA-256 of an empty body.
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// How to reach an S3 bucket. Blobs are stored under Prefix in the
+// bucket.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// An S3BlobStore keeps blobs as objects in an S3 bucket.
+type S3BlobStore struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// Create a blob store in an S3 bucket. Nothing is checked until the
+// store is used.
+func NewS3BlobStore(config S3Config) (*S3BlobStore, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint %q needs to be a URL", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("no S3 bucket specified")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	return &S3BlobStore{config: config, endpoint: endpoint, client: http.DefaultClient, now: time.Now}, nil
+}
+
+// Escape a string the way SigV4 wants it, which is stricter than
+// url.QueryEscape (spaces are %20, "~" is left alone).
+func awsEscape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// Sign a request with AWS Signature Version 4. The host and all
+// headers already set on the request are signed; the request must
+// have x-amz-content-sha256 set.
+func signV4(req *http.Request, region, accessKey, secretKey string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	var params []string
+	for key, values := range query {
+		for _, value := range values {
+			params = append(params, awsEscape(key, false)+"="+awsEscape(value, false))
+		}
+	}
+	sort.Strings(params)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+// Build a signed request for an object (or, with an empty key, the
+// bucket itself).
+func (s3 *S3BlobStore) request(method, key string, query url.Values, body io.Reader, size int64) (*http.Request, error) {
+	u := *s3.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s3.config.Bucket + "/"
+	if key != "" {
+		u.Path += s3.config.Prefix + key
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	u.RawQuery = ""
+	if query != nil {
+		var params []string
+		for k, values := range query {
+			for _, v := range values {
+				params = append(params, awsEscape(k, false)+"="+awsEscape(v, false))
+			}
+		}
+		sort.Strings(params)
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("x-amz-content-sha256", unsignedPayload)
+	} else {
+		req.Header.Set("x-amz-content-sha256", emptyPayload)
+	}
+	signV4(req, s3.config.Region, s3.config.AccessKey, s3.config.SecretKey, s3.now())
+	return req, nil
+}
+
+// Make a request, turning anything but a 2xx response into an error.
+// The caller closes the body of the response.
+func (s3 *S3BlobStore) do(req *http.Request, key string) (*http.Response, error) {
+	resp, err := s3.client.Do(req)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"method": req.Method,
+			"key":    key,
+		}).Error("S3BlobStore")
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", key, ErrBlobNotFound)
+	}
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"method": req.Method,
+		"key":    key,
+	}).Error("S3BlobStore")
+	return nil, fmt.Errorf("S3 %s %s: %s: %s", req.Method, key, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// Store a blob. S3 only makes an object visible once it has been
+// uploaded in full.
+func (s3 *S3BlobStore) Put(key string, r io.Reader, size int64) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	req, err := s3.request(http.MethodPut, key, nil, ioutil.NopCloser(r), size)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, key)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s3 *S3BlobStore) Get(key string) (io.ReadCloser, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+
+	req, err := s3.request(http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s3.do(req, key)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s3 *S3BlobStore) Stat(key string) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+
+	req, err := s3.request(http.MethodHead, key, nil, nil, 0)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp, err := s3.do(req, key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+
+	rv := BlobInfo{Key: key, Size: resp.ContentLength}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		rv.ModTime = modified
+	}
+	return rv, nil
+}
+
+// Delete a blob. S3 does not complain about deleting objects that do
+// not exist.
+func (s3 *S3BlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+
+	req, err := s3.request(http.MethodDelete, key, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.do(req, key)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// The parts of a ListObjectsV2 response we care about.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List the blobs under the prefix, sorted by key.
+func (s3 *S3BlobStore) List() ([]BlobInfo, error) {
+	var rv []BlobInfo
+
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if s3.config.Prefix != "" {
+			query.Set("prefix", s3.config.Prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s3.request(http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s3.do(req, "")
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing S3 listing: %v", err)
+		}
+
+		for _, c := range result.Contents {
+			key := strings.TrimPrefix(c.Key, s3.config.Prefix)
+			if checkBlobKey(key) != nil {
+				continue
+			}
+			rv = append(rv, BlobInfo{Key: key, Size: c.Size, ModTime: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Key < rv[j].Key })
+
+	return rv, nil
+}
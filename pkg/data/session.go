@@ -0,0 +1,532 @@
+// Upload sessions let a client upload a package version over several
+// connections. A session wraps a PackageVersion in the playground and
+// keeps track of how much of each file has been received, so an
+// interrupted upload can pick up from the last byte written. The
+// state of each session is kept on disk, beside the playground, so
+// sessions survive a server restart. The state is only written when a
+// file is announced; how far each file has gotten is what is on disk.
+package data
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+var (
+	// Returned (wrapped) when an upload session does not exist,
+	// or has expired.
+	ErrNoSession = errors.New("no such upload session")
+	// Returned (wrapped) when data is written to a session file
+	// at some other offset than where the last write ended.
+	ErrOffsetMismatch = errors.New("offset mismatch")
+)
+
+// An upload session in progress. The ID is what clients use to refer
+// to it.
+type UploadSession struct {
+	lock       sync.Mutex
+	ID         string
+	Labels     []string
+	pv         PackageVersion
+	files      map[string]*sessionFile
+	lastActive time.Time
+	finished   bool
+	statePath  string
+}
+
+type sessionFile struct {
+	owner  string
+	group  string
+	mode   int32
+	offset int64
+}
+
+// The on-disk state of an upload session.
+type sessionState struct {
+	ID         string             `json:"id"`
+	Package    string             `json:"package"`
+	DataPath   string             `json:"data_path"`
+	Labels     []string           `json:"labels,omitempty"`
+	Files      []sessionFileState `json:"files,omitempty"`
+	LastActive time.Time          `json:"last_active"`
+}
+
+type sessionFileState struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	Group string `json:"group,omitempty"`
+	Mode  int32  `json:"mode"`
+}
+
+// How far a file in an upload session has gotten.
+type FileOffset struct {
+	Name   string
+	Offset int64
+}
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", buf), nil
+}
+
+// Start a new upload session for a package. Labels are not used by
+// the data store, they are kept for whoever finishes the session.
+func (ds *DataStore) NewUploadSession(name string, labels []string) (*UploadSession, error) {
+	id, err := newSessionID()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("creating session ID")
+		return nil, err
+	}
+
+	pv, err := ds.NewPackageVersion(name)
+	if err != nil {
+		return nil, err
+	}
+
+	us := &UploadSession{
+		ID:         id,
+		Labels:     labels,
+		pv:         pv,
+		files:      make(map[string]*sessionFile),
+		lastActive: time.Now(),
+		statePath:  filepath.Join(ds.sessionDir(), id+".json"),
+	}
+	err = os.MkdirAll(ds.sessionDir(), 0755)
+	if err == nil {
+		err = us.save()
+	}
+	if err != nil {
+		ds.DiscardPackageVersion(pv)
+		return nil, err
+	}
+
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	ds.sessions[id] = us
+
+	return us, nil
+}
+
+// Return the upload session with a specific ID.
+func (ds *DataStore) GetUploadSession(id string) (*UploadSession, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	us, ok := ds.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session %s: %w", id, ErrNoSession)
+	}
+	return us, nil
+}
+
+// Remove an upload session from the data store, and its state from
+// disk, returning it.
+func (ds *DataStore) takeUploadSession(id string) (*UploadSession, error) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+
+	us, ok := ds.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session %s: %w", id, ErrNoSession)
+	}
+	delete(ds.sessions, id)
+
+	err := os.Remove(us.statePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"session": id,
+			"path":    us.statePath,
+		}).Warning("removing session state")
+	}
+	return us, nil
+}
+
+// Finish an upload session, returning the finished PackageVersion,
+// ready to be added to the data store. The session is gone
+// afterwards, whether this succeeds or not.
+func (ds *DataStore) FinishUploadSession(id string) (PackageVersion, error) {
+	us, err := ds.takeUploadSession(id)
+	if err != nil {
+		return PackageVersion{}, err
+	}
+
+	us.lock.Lock()
+	defer us.lock.Unlock()
+	us.finished = true
+
+	err = us.pv.Finish()
+	if err != nil {
+		ds.DiscardPackageVersion(us.pv)
+		return PackageVersion{}, err
+	}
+	return us.pv, nil
+}
+
+// Throw away an upload session and everything uploaded in it.
+func (ds *DataStore) AbortUploadSession(id string) error {
+	us, err := ds.takeUploadSession(id)
+	if err != nil {
+		return err
+	}
+
+	us.lock.Lock()
+	defer us.lock.Unlock()
+	us.finished = true
+
+	return ds.DiscardPackageVersion(us.pv)
+}
+
+// Throw away all upload sessions that have been idle for longer than
+// maxIdle. Returns the number of sessions removed.
+func (ds *DataStore) ExpireUploadSessions(maxIdle time.Duration) int {
+	cutoff := time.Now().Add(-maxIdle)
+
+	// Looking at a session waits for any write in progress, so
+	// that is done without holding the data store lock.
+	ds.lock.Lock()
+	var sessions []*UploadSession
+	for _, us := range ds.sessions {
+		sessions = append(sessions, us)
+	}
+	ds.lock.Unlock()
+
+	var expired []string
+	for _, us := range sessions {
+		if us.LastActive().Before(cutoff) {
+			expired = append(expired, us.ID)
+		}
+	}
+
+	for _, id := range expired {
+		log.WithFields(log.Fields{
+			"session": id,
+		}).Info("expiring upload session")
+		ds.AbortUploadSession(id)
+	}
+
+	return len(expired)
+}
+
+// The name of the package being uploaded.
+func (us *UploadSession) PackageName() string {
+	return us.pv.Name
+}
+
+// When the session was last used.
+func (us *UploadSession) LastActive() time.Time {
+	us.lock.Lock()
+	defer us.lock.Unlock()
+
+	return us.lastActive
+}
+
+// Announce a file (or directory) in the session. Announcing a file
+// that is already known is fine, as long as it has the same owner and
+// mode; this is what a resuming client does.
+func (us *UploadSession) AddFile(pvFile *pb.File) error {
+	us.lock.Lock()
+	defer us.lock.Unlock()
+
+	if us.finished {
+		return fmt.Errorf("session %s: %w", us.ID, ErrNoSession)
+	}
+	us.lastActive = time.Now()
+
+	name := pvFile.GetName()
+	if sf, ok := us.files[name]; ok {
+		if sf.owner != pvFile.GetOwner() || sf.mode != pvFile.GetMode() {
+			return fmt.Errorf("file %s re-announced with different owner or mode", name)
+		}
+		return nil
+	}
+
+	if strings.HasSuffix(name, "/") {
+		err := us.pv.AddDir(pvFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		out, err := us.pv.CreateFile(pvFile)
+		if err != nil {
+			return err
+		}
+		err = out.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	us.files[name] = &sessionFile{
+		owner: pvFile.GetOwner(),
+		group: pvFile.GetGroup(),
+		mode:  pvFile.GetMode(),
+	}
+	us.saveOrWarn()
+	return nil
+}
+
+// Write data to a file in the session, starting at offset. The offset
+// must be where the previous write ended. The new offset is returned,
+// and in the case of ErrOffsetMismatch, the offset the session
+// expected.
+func (us *UploadSession) Write(name string, offset int64, data []byte) (int64, error) {
+	us.lock.Lock()
+	defer us.lock.Unlock()
+
+	if us.finished {
+		return 0, fmt.Errorf("session %s: %w", us.ID, ErrNoSession)
+	}
+	us.lastActive = time.Now()
+
+	sf, ok := us.files[name]
+	if !ok || strings.HasSuffix(name, "/") {
+		return 0, fmt.Errorf("file %s not announced in session %s", name, us.ID)
+	}
+	if offset != sf.offset {
+		return sf.offset, fmt.Errorf("file %s, write at %d, expected %d: %w", name, offset, sf.offset, ErrOffsetMismatch)
+	}
+
+	path := filepath.Join(us.pv.DataPath, name)
+	out, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return sf.offset, err
+	}
+	defer out.Close()
+
+	// Anything beyond the last acknowledged offset is left over
+	// from an interrupted write, and is not to be trusted.
+	err = out.Truncate(offset)
+	if err != nil {
+		return sf.offset, err
+	}
+	n, err := out.WriteAt(data, offset)
+	if err == nil {
+		err = out.Sync()
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"session": us.ID,
+			"file":    name,
+			"n":       n,
+		}).Error("writing session data")
+		return sf.offset, err
+	}
+
+	sf.offset += int64(n)
+	return sf.offset, nil
+}
+
+// Return how far each file in the session has gotten, sorted by name.
+func (us *UploadSession) Offsets() []FileOffset {
+	us.lock.Lock()
+	defer us.lock.Unlock()
+
+	var rv []FileOffset
+	for name, sf := range us.files {
+		rv = append(rv, FileOffset{Name: name, Offset: sf.offset})
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Name < rv[j].Name })
+
+	return rv
+}
+
+// The directory upload session state is kept in.
+func (ds *DataStore) sessionDir() string {
+	return filepath.Join(ds.playground, "sessions")
+}
+
+// Write the session state to disk. Expects to be called with the
+// session lock held.
+func (us *UploadSession) save() error {
+	state := sessionState{
+		ID:         us.ID,
+		Package:    us.pv.Name,
+		DataPath:   us.pv.DataPath,
+		Labels:     us.Labels,
+		LastActive: us.lastActive,
+	}
+	for name, sf := range us.files {
+		state.Files = append(state.Files, sessionFileState{
+			Name:  name,
+			Owner: sf.owner,
+			Group: sf.group,
+			Mode:  sf.mode,
+		})
+	}
+	sort.Slice(state.Files, func(i, j int) bool { return state.Files[i].Name < state.Files[j].Name })
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(us.statePath, data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"session": us.ID,
+			"path":    us.statePath,
+		}).Error("writing session state")
+	}
+	return err
+}
+
+// Write the session state to disk. If this fails, files announced
+// since the last successful write are unknown after a restart, which
+// only means a resuming client announces and sends them again.
+func (us *UploadSession) saveOrWarn() {
+	if us.save() != nil {
+		log.WithFields(log.Fields{
+			"session": us.ID,
+		}).Warning("session state on disk is out of date")
+	}
+}
+
+// Reload the upload sessions left by a previous run, and remove any
+// working directory in the playground that no session owns. Expects
+// to be called before the data store is in use.
+func (ds *DataStore) loadUploadSessions() error {
+	owned := make(map[string]bool)
+
+	entries, err := ioutil.ReadDir(ds.sessionDir())
+	if err != nil && !os.IsNotExist(err) {
+		log.WithFields(log.Fields{
+			"error": err,
+			"path":  ds.sessionDir(),
+		}).Error("reading session directory")
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(ds.sessionDir(), entry.Name())
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		us, err := ds.loadUploadSession(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  path,
+			}).Warning("dropping unreadable upload session")
+			os.Remove(path)
+			continue
+		}
+		ds.sessions[us.ID] = us
+		owned[us.pv.DataPath] = true
+	}
+
+	workdirs, err := filepath.Glob(filepath.Join(ds.playground, "tmp", "*", "tmp-*"))
+	if err != nil {
+		return err
+	}
+	for _, workdir := range workdirs {
+		if owned[workdir] {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"path": workdir,
+		}).Info("removing stale playground directory")
+		err := os.RemoveAll(workdir)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  workdir,
+			}).Warning("removing stale playground directory")
+		}
+	}
+
+	return nil
+}
+
+// Rebuild an upload session from its state on disk. Data is synced
+// before a write is acknowledged, so the offset of each file is its
+// size on disk. The session was last active no earlier than its
+// files were last written.
+func (ds *DataStore) loadUploadSession(path string) (*UploadSession, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state sessionState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	if state.ID+".json" != filepath.Base(path) {
+		return nil, fmt.Errorf("session state %s is for session %s", path, state.ID)
+	}
+	if err := validPackageName(state.Package); err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(filepath.Join(ds.playground, "tmp", state.Package), state.DataPath)
+	if err != nil || !strings.HasPrefix(rel, "tmp-") || strings.ContainsRune(rel, filepath.Separator) {
+		return nil, fmt.Errorf("session %s has its data outside the playground", state.ID)
+	}
+	if info, err := os.Stat(state.DataPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("session %s has lost its data directory", state.ID)
+	}
+
+	us := &UploadSession{
+		ID:     state.ID,
+		Labels: state.Labels,
+		pv: PackageVersion{
+			Name:     state.Package,
+			Labels:   make(map[string]struct{}),
+			DataPath: state.DataPath,
+			fileMap:  make(map[string]fileInfo),
+			groups:   make(map[string]string),
+		},
+		files:      make(map[string]*sessionFile),
+		lastActive: state.LastActive,
+		statePath:  path,
+	}
+	for _, f := range state.Files {
+		if err := validFileName(f.Name); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(filepath.Join(state.DataPath, f.Name))
+		if err != nil {
+			return nil, err
+		}
+		var offset int64
+		if !info.IsDir() {
+			offset = info.Size()
+		}
+		if info.ModTime().After(us.lastActive) {
+			us.lastActive = info.ModTime()
+		}
+		us.files[f.Name] = &sessionFile{
+			owner:  f.Owner,
+			group:  f.Group,
+			mode:   f.Mode,
+			offset: offset,
+		}
+		us.pv.fileMap[f.Name] = fileInfo{f.Owner, f.Mode}
+		us.pv.groups[f.Name] = f.Group
+	}
+
+	log.WithFields(log.Fields{
+		"session": us.ID,
+		"name":    us.pv.Name,
+	}).Info("reloaded upload session")
+	return us, nil
+}
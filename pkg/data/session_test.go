@@ -0,0 +1,180 @@
+package data
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestUploadSessionResume(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	us, err := ds.NewUploadSession("foo", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	file := &pb.File{Name: "data", Owner: "root", Mode: 0644}
+	if err := us.AddFile(file); err != nil {
+		t.Fatalf("Unexpected error adding file: %v", err)
+	}
+	if _, err := us.Write("data", 0, []byte("hello, ")); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+
+	// The client loses track, re-announces the file and
+	// tries writing from the wrong place.
+	if err := us.AddFile(file); err != nil {
+		t.Fatalf("Unexpected error re-adding file: %v", err)
+	}
+	offset, err := us.Write("data", 3, []byte("lo, world"))
+	if !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("Expected offset mismatch, saw %v", err)
+	}
+	if offset != 7 {
+		t.Errorf("Saw offset %d, want 7", offset)
+	}
+	if _, err := us.Write("data", offset, []byte("world")); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+
+	offsets := us.Offsets()
+	if len(offsets) != 1 || offsets[0].Offset != 12 {
+		t.Errorf("Unexpected offsets %v", offsets)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(us.pv.DataPath, "data"))
+	if err != nil || string(data) != "hello, world" {
+		t.Errorf("Saw contents %q (error %v), want %q", data, err, "hello, world")
+	}
+
+	pv, err := ds.FinishUploadSession(us.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error finishing: %v", err)
+	}
+	if pv.Version == "" {
+		t.Errorf("Finished session has no version")
+	}
+	if _, err := ds.GetUploadSession(us.ID); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected finished session to be gone, saw %v", err)
+	}
+}
+
+func TestUploadSessionChangedFile(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	us, err := ds.NewUploadSession("foo", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	if err := us.AddFile(&pb.File{Name: "data", Owner: "root", Mode: 0644}); err != nil {
+		t.Fatalf("Unexpected error adding file: %v", err)
+	}
+	if err := us.AddFile(&pb.File{Name: "data", Owner: "root", Mode: 0755}); err == nil {
+		t.Errorf("Expected error re-announcing with a different mode")
+	}
+	if _, err := us.Write("other", 0, []byte("x")); err == nil {
+		t.Errorf("Expected error writing to an unannounced file")
+	}
+}
+
+func TestExpireUploadSessions(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	old, err := ds.NewUploadSession("foo", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	old.lastActive = time.Now().Add(-2 * time.Hour)
+	fresh, err := ds.NewUploadSession("foo", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	if n := ds.ExpireUploadSessions(time.Hour); n != 1 {
+		t.Errorf("Expired %d sessions, want 1", n)
+	}
+	if _, err := ds.GetUploadSession(old.ID); err == nil {
+		t.Errorf("Old session still present")
+	}
+	if _, err := os.Stat(old.pv.DataPath); !os.IsNotExist(err) {
+		t.Errorf("Old session directory still present: %v", err)
+	}
+	if _, err := ds.GetUploadSession(fresh.ID); err != nil {
+		t.Errorf("Fresh session gone: %v", err)
+	}
+	if _, err := old.Write("data", 0, []byte("x")); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected writes to expired session to fail, saw %v", err)
+	}
+}
+
+func TestUploadSessionRestart(t *testing.T) {
+	ds, dir := newTestStore(t)
+	defer os.RemoveAll(dir)
+
+	us, err := ds.NewUploadSession("foo", []string{"stable"})
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	if err := us.AddFile(&pb.File{Name: "data", Owner: "root", Mode: 0644}); err != nil {
+		t.Fatalf("Unexpected error adding file: %v", err)
+	}
+	if _, err := us.Write("data", 0, []byte("hello, ")); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+	stale, err := ds.NewPackageVersion("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error creating package version: %v", err)
+	}
+
+	restarted, err := NewDataStore(filepath.Join(dir, "playground"), filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatalf("Failed to reload data store: %v", err)
+	}
+	if _, err := os.Stat(stale.DataPath); !os.IsNotExist(err) {
+		t.Errorf("Stale playground directory still present: %v", err)
+	}
+
+	resumed, err := restarted.GetUploadSession(us.ID)
+	if err != nil {
+		t.Fatalf("Session lost over restart: %v", err)
+	}
+	if len(resumed.Labels) != 1 || resumed.Labels[0] != "stable" {
+		t.Errorf("Saw labels %v, want [stable]", resumed.Labels)
+	}
+	offsets := resumed.Offsets()
+	if len(offsets) != 1 || offsets[0].Offset != 7 {
+		t.Errorf("Unexpected offsets %v", offsets)
+	}
+	if _, err := resumed.Write("data", 7, []byte("world")); err != nil {
+		t.Fatalf("Unexpected error writing: %v", err)
+	}
+
+	pv, err := restarted.FinishUploadSession(us.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error finishing: %v", err)
+	}
+	direct, err := ds.NewPackageVersion("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error creating package version: %v", err)
+	}
+	if err := direct.AddFile(&pb.File{Name: "data", Owner: "root", Mode: 0644, Contents: []byte("hello, world")}); err != nil {
+		t.Fatalf("Unexpected error adding file: %v", err)
+	}
+	if err := direct.Finish(); err != nil {
+		t.Fatalf("Unexpected error finishing: %v", err)
+	}
+	if pv.Version != direct.Version {
+		t.Errorf("Resumed version %s differs from direct upload %s", pv.Version, direct.Version)
+	}
+	if _, err := os.Stat(resumed.statePath); !os.IsNotExist(err) {
+		t.Errorf("Session state left behind: %v", err)
+	}
+}
@@ -18,20 +18,28 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Set labels on the version designated by Version (a version or a
+// label). Protected labels can only be moved away from another
+// version if Force is set. For labels in Expected, the label must
+// currently be on the version given (or, for an empty string, on no
+// version at all), otherwise nothing is changed. All labels are set,
+// or none are.
 type SetLabelRequest struct {
-	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
-	Version              string   `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
-	Label                []string `protobuf:"bytes,3,rep,name=Label,proto3" json:"Label,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	PackageName          string            `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Version              string            `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
+	Label                []string          `protobuf:"bytes,3,rep,name=Label,proto3" json:"Label,omitempty"`
+	Force                bool              `protobuf:"varint,4,opt,name=Force,proto3" json:"Force,omitempty"`
+	Expected             map[string]string `protobuf:"bytes,5,rep,name=Expected,proto3" json:"Expected,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *SetLabelRequest) Reset()         { *m = SetLabelRequest{} }
 func (m *SetLabelRequest) String() string { return proto.CompactTextString(m) }
 func (*SetLabelRequest) ProtoMessage()    {}
 func (*SetLabelRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{0}
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{0}
 }
 func (m *SetLabelRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SetLabelRequest.Unmarshal(m, b)
@@ -72,132 +80,1616 @@ func (m *SetLabelRequest) GetLabel() []string {
 	return nil
 }
 
-type PackageInformationRequest struct {
+func (m *SetLabelRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+func (m *SetLabelRequest) GetExpected() map[string]string {
+	if m != nil {
+		return m.Expected
+	}
+	return nil
+}
+
+// One change in a label transaction: put Label on the version of
+// PackageName designated by Designator. If Expected is set, the label
+// must currently be on that version; if ExpectUnset is set, it must
+// not be on any version.
+type LabelChange struct {
 	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Designator           string   `protobuf:"bytes,2,opt,name=Designator,proto3" json:"Designator,omitempty"`
+	Label                string   `protobuf:"bytes,3,opt,name=Label,proto3" json:"Label,omitempty"`
+	Force                bool     `protobuf:"varint,4,opt,name=Force,proto3" json:"Force,omitempty"`
+	Expected             string   `protobuf:"bytes,5,opt,name=Expected,proto3" json:"Expected,omitempty"`
+	ExpectUnset          bool     `protobuf:"varint,6,opt,name=ExpectUnset,proto3" json:"ExpectUnset,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *PackageInformationRequest) Reset()         { *m = PackageInformationRequest{} }
-func (m *PackageInformationRequest) String() string { return proto.CompactTextString(m) }
-func (*PackageInformationRequest) ProtoMessage()    {}
-func (*PackageInformationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{1}
+func (m *LabelChange) Reset()         { *m = LabelChange{} }
+func (m *LabelChange) String() string { return proto.CompactTextString(m) }
+func (*LabelChange) ProtoMessage()    {}
+func (*LabelChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{1}
 }
-func (m *PackageInformationRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_PackageInformationRequest.Unmarshal(m, b)
+func (m *LabelChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelChange.Unmarshal(m, b)
 }
-func (m *PackageInformationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_PackageInformationRequest.Marshal(b, m, deterministic)
+func (m *LabelChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelChange.Marshal(b, m, deterministic)
 }
-func (dst *PackageInformationRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PackageInformationRequest.Merge(dst, src)
+func (dst *LabelChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelChange.Merge(dst, src)
 }
-func (m *PackageInformationRequest) XXX_Size() int {
-	return xxx_messageInfo_PackageInformationRequest.Size(m)
+func (m *LabelChange) XXX_Size() int {
+	return xxx_messageInfo_LabelChange.Size(m)
 }
-func (m *PackageInformationRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_PackageInformationRequest.DiscardUnknown(m)
+func (m *LabelChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelChange.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PackageInformationRequest proto.InternalMessageInfo
+var xxx_messageInfo_LabelChange proto.InternalMessageInfo
 
-func (m *PackageInformationRequest) GetPackageName() string {
+func (m *LabelChange) GetPackageName() string {
 	if m != nil {
 		return m.PackageName
 	}
 	return ""
 }
 
-type PackageInformation struct {
+func (m *LabelChange) GetDesignator() string {
+	if m != nil {
+		return m.Designator
+	}
+	return ""
+}
+
+func (m *LabelChange) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *LabelChange) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+func (m *LabelChange) GetExpected() string {
+	if m != nil {
+		return m.Expected
+	}
+	return ""
+}
+
+func (m *LabelChange) GetExpectUnset() bool {
+	if m != nil {
+		return m.ExpectUnset
+	}
+	return false
+}
+
+// A batch of label changes, applied all or nothing, in order.
+type LabelTransaction struct {
+	Changes              []*LabelChange `protobuf:"bytes,1,rep,name=Changes,proto3" json:"Changes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *LabelTransaction) Reset()         { *m = LabelTransaction{} }
+func (m *LabelTransaction) String() string { return proto.CompactTextString(m) }
+func (*LabelTransaction) ProtoMessage()    {}
+func (*LabelTransaction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{2}
+}
+func (m *LabelTransaction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelTransaction.Unmarshal(m, b)
+}
+func (m *LabelTransaction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelTransaction.Marshal(b, m, deterministic)
+}
+func (dst *LabelTransaction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelTransaction.Merge(dst, src)
+}
+func (m *LabelTransaction) XXX_Size() int {
+	return xxx_messageInfo_LabelTransaction.Size(m)
+}
+func (m *LabelTransaction) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelTransaction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelTransaction proto.InternalMessageInfo
+
+func (m *LabelTransaction) GetChanges() []*LabelChange {
+	if m != nil {
+		return m.Changes
+	}
+	return nil
+}
+
+// The versions labelled by a transaction, one per change.
+type LabelTransactionResponse struct {
+	PackageData          []*PackageInformation `protobuf:"bytes,1,rep,name=PackageData,proto3" json:"PackageData,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *LabelTransactionResponse) Reset()         { *m = LabelTransactionResponse{} }
+func (m *LabelTransactionResponse) String() string { return proto.CompactTextString(m) }
+func (*LabelTransactionResponse) ProtoMessage()    {}
+func (*LabelTransactionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{3}
+}
+func (m *LabelTransactionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelTransactionResponse.Unmarshal(m, b)
+}
+func (m *LabelTransactionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelTransactionResponse.Marshal(b, m, deterministic)
+}
+func (dst *LabelTransactionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelTransactionResponse.Merge(dst, src)
+}
+func (m *LabelTransactionResponse) XXX_Size() int {
+	return xxx_messageInfo_LabelTransactionResponse.Size(m)
+}
+func (m *LabelTransactionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelTransactionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelTransactionResponse proto.InternalMessageInfo
+
+func (m *LabelTransactionResponse) GetPackageData() []*PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+// Ask for the history of a label on a package. With no Label, the
+// history of all labels on the package is returned.
+type LabelHistoryRequest struct {
 	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
-	Version              string   `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
-	Label                []string `protobuf:"bytes,3,rep,name=Label,proto3" json:"Label,omitempty"`
+	Label                string   `protobuf:"bytes,2,opt,name=Label,proto3" json:"Label,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *PackageInformation) Reset()         { *m = PackageInformation{} }
-func (m *PackageInformation) String() string { return proto.CompactTextString(m) }
-func (*PackageInformation) ProtoMessage()    {}
-func (*PackageInformation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{2}
+func (m *LabelHistoryRequest) Reset()         { *m = LabelHistoryRequest{} }
+func (m *LabelHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*LabelHistoryRequest) ProtoMessage()    {}
+func (*LabelHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{4}
 }
-func (m *PackageInformation) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_PackageInformation.Unmarshal(m, b)
+func (m *LabelHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelHistoryRequest.Unmarshal(m, b)
 }
-func (m *PackageInformation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_PackageInformation.Marshal(b, m, deterministic)
+func (m *LabelHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelHistoryRequest.Marshal(b, m, deterministic)
 }
-func (dst *PackageInformation) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PackageInformation.Merge(dst, src)
+func (dst *LabelHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelHistoryRequest.Merge(dst, src)
 }
-func (m *PackageInformation) XXX_Size() int {
-	return xxx_messageInfo_PackageInformation.Size(m)
+func (m *LabelHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_LabelHistoryRequest.Size(m)
 }
-func (m *PackageInformation) XXX_DiscardUnknown() {
-	xxx_messageInfo_PackageInformation.DiscardUnknown(m)
+func (m *LabelHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelHistoryRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PackageInformation proto.InternalMessageInfo
+var xxx_messageInfo_LabelHistoryRequest proto.InternalMessageInfo
 
-func (m *PackageInformation) GetPackageName() string {
+func (m *LabelHistoryRequest) GetPackageName() string {
 	if m != nil {
 		return m.PackageName
 	}
 	return ""
 }
 
-func (m *PackageInformation) GetVersion() string {
+func (m *LabelHistoryRequest) GetLabel() string {
 	if m != nil {
-		return m.Version
+		return m.Label
 	}
 	return ""
 }
 
-func (m *PackageInformation) GetLabel() []string {
+// A label moving. Time is in seconds since the epoch. OldVersion is
+// empty if the label was not on any version before.
+type LabelEvent struct {
+	Label                string   `protobuf:"bytes,1,opt,name=Label,proto3" json:"Label,omitempty"`
+	Time                 int64    `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"`
+	OldVersion           string   `protobuf:"bytes,3,opt,name=OldVersion,proto3" json:"OldVersion,omitempty"`
+	NewVersion           string   `protobuf:"bytes,4,opt,name=NewVersion,proto3" json:"NewVersion,omitempty"`
+	Identity             string   `protobuf:"bytes,5,opt,name=Identity,proto3" json:"Identity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LabelEvent) Reset()         { *m = LabelEvent{} }
+func (m *LabelEvent) String() string { return proto.CompactTextString(m) }
+func (*LabelEvent) ProtoMessage()    {}
+func (*LabelEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{5}
+}
+func (m *LabelEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelEvent.Unmarshal(m, b)
+}
+func (m *LabelEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelEvent.Marshal(b, m, deterministic)
+}
+func (dst *LabelEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelEvent.Merge(dst, src)
+}
+func (m *LabelEvent) XXX_Size() int {
+	return xxx_messageInfo_LabelEvent.Size(m)
+}
+func (m *LabelEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelEvent proto.InternalMessageInfo
+
+func (m *LabelEvent) GetLabel() string {
 	if m != nil {
 		return m.Label
 	}
+	return ""
+}
+
+func (m *LabelEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *LabelEvent) GetOldVersion() string {
+	if m != nil {
+		return m.OldVersion
+	}
+	return ""
+}
+
+func (m *LabelEvent) GetNewVersion() string {
+	if m != nil {
+		return m.NewVersion
+	}
+	return ""
+}
+
+func (m *LabelEvent) GetIdentity() string {
+	if m != nil {
+		return m.Identity
+	}
+	return ""
+}
+
+// The history of a label (or labels), oldest first.
+type LabelHistory struct {
+	PackageName          string        `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Events               []*LabelEvent `protobuf:"bytes,2,rep,name=Events,proto3" json:"Events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *LabelHistory) Reset()         { *m = LabelHistory{} }
+func (m *LabelHistory) String() string { return proto.CompactTextString(m) }
+func (*LabelHistory) ProtoMessage()    {}
+func (*LabelHistory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{6}
+}
+func (m *LabelHistory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LabelHistory.Unmarshal(m, b)
+}
+func (m *LabelHistory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LabelHistory.Marshal(b, m, deterministic)
+}
+func (dst *LabelHistory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LabelHistory.Merge(dst, src)
+}
+func (m *LabelHistory) XXX_Size() int {
+	return xxx_messageInfo_LabelHistory.Size(m)
+}
+func (m *LabelHistory) XXX_DiscardUnknown() {
+	xxx_messageInfo_LabelHistory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LabelHistory proto.InternalMessageInfo
+
+func (m *LabelHistory) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *LabelHistory) GetEvents() []*LabelEvent {
+	if m != nil {
+		return m.Events
+	}
 	return nil
 }
 
-type PackageInformationResponse struct {
-	PackageData          []*PackageInformation `protobuf:"bytes,1,rep,name=PackageData,proto3" json:"PackageData,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
-	XXX_unrecognized     []byte                `json:"-"`
-	XXX_sizecache        int32                 `json:"-"`
+// Move a label back to the version it was on before it last moved.
+type RollbackLabelRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Label                string   `protobuf:"bytes,2,opt,name=Label,proto3" json:"Label,omitempty"`
+	Force                bool     `protobuf:"varint,3,opt,name=Force,proto3" json:"Force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *PackageInformationResponse) Reset()         { *m = PackageInformationResponse{} }
-func (m *PackageInformationResponse) String() string { return proto.CompactTextString(m) }
-func (*PackageInformationResponse) ProtoMessage()    {}
-func (*PackageInformationResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{3}
+func (m *RollbackLabelRequest) Reset()         { *m = RollbackLabelRequest{} }
+func (m *RollbackLabelRequest) String() string { return proto.CompactTextString(m) }
+func (*RollbackLabelRequest) ProtoMessage()    {}
+func (*RollbackLabelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{7}
 }
-func (m *PackageInformationResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_PackageInformationResponse.Unmarshal(m, b)
+func (m *RollbackLabelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RollbackLabelRequest.Unmarshal(m, b)
 }
-func (m *PackageInformationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_PackageInformationResponse.Marshal(b, m, deterministic)
+func (m *RollbackLabelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RollbackLabelRequest.Marshal(b, m, deterministic)
 }
-func (dst *PackageInformationResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PackageInformationResponse.Merge(dst, src)
+func (dst *RollbackLabelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RollbackLabelRequest.Merge(dst, src)
 }
-func (m *PackageInformationResponse) XXX_Size() int {
-	return xxx_messageInfo_PackageInformationResponse.Size(m)
+func (m *RollbackLabelRequest) XXX_Size() int {
+	return xxx_messageInfo_RollbackLabelRequest.Size(m)
 }
-func (m *PackageInformationResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_PackageInformationResponse.DiscardUnknown(m)
+func (m *RollbackLabelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RollbackLabelRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PackageInformationResponse proto.InternalMessageInfo
+var xxx_messageInfo_RollbackLabelRequest proto.InternalMessageInfo
+
+func (m *RollbackLabelRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *RollbackLabelRequest) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *RollbackLabelRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+// Search the audit log. Empty fields match everything; From and To
+// are in seconds since the epoch, To is exclusive. At most Limit
+// entries are returned (the newest ones), if Limit is set.
+type AuditQuery struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	From                 int64    `protobuf:"varint,2,opt,name=From,proto3" json:"From,omitempty"`
+	To                   int64    `protobuf:"varint,3,opt,name=To,proto3" json:"To,omitempty"`
+	Limit                int32    `protobuf:"varint,4,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditQuery) Reset()         { *m = AuditQuery{} }
+func (m *AuditQuery) String() string { return proto.CompactTextString(m) }
+func (*AuditQuery) ProtoMessage()    {}
+func (*AuditQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{8}
+}
+func (m *AuditQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditQuery.Unmarshal(m, b)
+}
+func (m *AuditQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditQuery.Marshal(b, m, deterministic)
+}
+func (dst *AuditQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditQuery.Merge(dst, src)
+}
+func (m *AuditQuery) XXX_Size() int {
+	return xxx_messageInfo_AuditQuery.Size(m)
+}
+func (m *AuditQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditQuery proto.InternalMessageInfo
+
+func (m *AuditQuery) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetFrom() int64 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *AuditQuery) GetTo() int64 {
+	if m != nil {
+		return m.To
+	}
+	return 0
+}
+
+func (m *AuditQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// A record of a call that changed something. Time is in seconds
+// since the epoch. Result is "OK", or the error.
+type AuditEntry struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=Time,proto3" json:"Time,omitempty"`
+	Method               string   `protobuf:"bytes,2,opt,name=Method,proto3" json:"Method,omitempty"`
+	Identity             string   `protobuf:"bytes,3,opt,name=Identity,proto3" json:"Identity,omitempty"`
+	Peer                 string   `protobuf:"bytes,4,opt,name=Peer,proto3" json:"Peer,omitempty"`
+	PackageName          []string `protobuf:"bytes,5,rep,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Version              string   `protobuf:"bytes,6,opt,name=Version,proto3" json:"Version,omitempty"`
+	Label                []string `protobuf:"bytes,7,rep,name=Label,proto3" json:"Label,omitempty"`
+	Summary              string   `protobuf:"bytes,8,opt,name=Summary,proto3" json:"Summary,omitempty"`
+	Result               string   `protobuf:"bytes,9,opt,name=Result,proto3" json:"Result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEntry) Reset()         { *m = AuditEntry{} }
+func (m *AuditEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditEntry) ProtoMessage()    {}
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{9}
+}
+func (m *AuditEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEntry.Unmarshal(m, b)
+}
+func (m *AuditEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEntry.Marshal(b, m, deterministic)
+}
+func (dst *AuditEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntry.Merge(dst, src)
+}
+func (m *AuditEntry) XXX_Size() int {
+	return xxx_messageInfo_AuditEntry.Size(m)
+}
+func (m *AuditEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntry proto.InternalMessageInfo
+
+func (m *AuditEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetIdentity() string {
+	if m != nil {
+		return m.Identity
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetPackageName() []string {
+	if m != nil {
+		return m.PackageName
+	}
+	return nil
+}
+
+func (m *AuditEntry) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetLabel() []string {
+	if m != nil {
+		return m.Label
+	}
+	return nil
+}
+
+func (m *AuditEntry) GetSummary() string {
+	if m != nil {
+		return m.Summary
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+type AuditEntries struct {
+	Entries              []*AuditEntry `protobuf:"bytes,1,rep,name=Entries,proto3" json:"Entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *AuditEntries) Reset()         { *m = AuditEntries{} }
+func (m *AuditEntries) String() string { return proto.CompactTextString(m) }
+func (*AuditEntries) ProtoMessage()    {}
+func (*AuditEntries) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{10}
+}
+func (m *AuditEntries) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEntries.Unmarshal(m, b)
+}
+func (m *AuditEntries) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEntries.Marshal(b, m, deterministic)
+}
+func (dst *AuditEntries) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntries.Merge(dst, src)
+}
+func (m *AuditEntries) XXX_Size() int {
+	return xxx_messageInfo_AuditEntries.Size(m)
+}
+func (m *AuditEntries) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntries.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntries proto.InternalMessageInfo
+
+func (m *AuditEntries) GetEntries() []*AuditEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type PackageInformationRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PackageInformationRequest) Reset()         { *m = PackageInformationRequest{} }
+func (m *PackageInformationRequest) String() string { return proto.CompactTextString(m) }
+func (*PackageInformationRequest) ProtoMessage()    {}
+func (*PackageInformationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{11}
+}
+func (m *PackageInformationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageInformationRequest.Unmarshal(m, b)
+}
+func (m *PackageInformationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageInformationRequest.Marshal(b, m, deterministic)
+}
+func (dst *PackageInformationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageInformationRequest.Merge(dst, src)
+}
+func (m *PackageInformationRequest) XXX_Size() int {
+	return xxx_messageInfo_PackageInformationRequest.Size(m)
+}
+func (m *PackageInformationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageInformationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageInformationRequest proto.InternalMessageInfo
+
+func (m *PackageInformationRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+type PackageInformation struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
+	Label                []string `protobuf:"bytes,3,rep,name=Label,proto3" json:"Label,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PackageInformation) Reset()         { *m = PackageInformation{} }
+func (m *PackageInformation) String() string { return proto.CompactTextString(m) }
+func (*PackageInformation) ProtoMessage()    {}
+func (*PackageInformation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{12}
+}
+func (m *PackageInformation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageInformation.Unmarshal(m, b)
+}
+func (m *PackageInformation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageInformation.Marshal(b, m, deterministic)
+}
+func (dst *PackageInformation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageInformation.Merge(dst, src)
+}
+func (m *PackageInformation) XXX_Size() int {
+	return xxx_messageInfo_PackageInformation.Size(m)
+}
+func (m *PackageInformation) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageInformation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageInformation proto.InternalMessageInfo
+
+func (m *PackageInformation) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *PackageInformation) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *PackageInformation) GetLabel() []string {
+	if m != nil {
+		return m.Label
+	}
+	return nil
+}
+
+type PackageInformationResponse struct {
+	PackageData          []*PackageInformation `protobuf:"bytes,1,rep,name=PackageData,proto3" json:"PackageData,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *PackageInformationResponse) Reset()         { *m = PackageInformationResponse{} }
+func (m *PackageInformationResponse) String() string { return proto.CompactTextString(m) }
+func (*PackageInformationResponse) ProtoMessage()    {}
+func (*PackageInformationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{13}
+}
+func (m *PackageInformationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageInformationResponse.Unmarshal(m, b)
+}
+func (m *PackageInformationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageInformationResponse.Marshal(b, m, deterministic)
+}
+func (dst *PackageInformationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageInformationResponse.Merge(dst, src)
+}
+func (m *PackageInformationResponse) XXX_Size() int {
+	return xxx_messageInfo_PackageInformationResponse.Size(m)
+}
+func (m *PackageInformationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageInformationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageInformationResponse proto.InternalMessageInfo
+
+func (m *PackageInformationResponse) GetPackageData() []*PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+// List packages, sorted by name. Empty fields match everything.
+// Pattern is a glob (as in path.Match) the name must match, and Label
+// a label the package must have on one of its versions. At most
+// PageSize packages are returned per call; to get the next page, pass
+// the NextPageToken from the previous response.
+type ListPackagesRequest struct {
+	Prefix               string   `protobuf:"bytes,1,opt,name=Prefix,proto3" json:"Prefix,omitempty"`
+	Pattern              string   `protobuf:"bytes,2,opt,name=Pattern,proto3" json:"Pattern,omitempty"`
+	Label                string   `protobuf:"bytes,3,opt,name=Label,proto3" json:"Label,omitempty"`
+	PageSize             int32    `protobuf:"varint,4,opt,name=PageSize,proto3" json:"PageSize,omitempty"`
+	PageToken            string   `protobuf:"bytes,5,opt,name=PageToken,proto3" json:"PageToken,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListPackagesRequest) Reset()         { *m = ListPackagesRequest{} }
+func (m *ListPackagesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListPackagesRequest) ProtoMessage()    {}
+func (*ListPackagesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{14}
+}
+func (m *ListPackagesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListPackagesRequest.Unmarshal(m, b)
+}
+func (m *ListPackagesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListPackagesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListPackagesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListPackagesRequest.Merge(dst, src)
+}
+func (m *ListPackagesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListPackagesRequest.Size(m)
+}
+func (m *ListPackagesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListPackagesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListPackagesRequest proto.InternalMessageInfo
+
+func (m *ListPackagesRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *ListPackagesRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *ListPackagesRequest) GetLabel() string {
+	if m != nil {
+		return m.Label
+	}
+	return ""
+}
+
+func (m *ListPackagesRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListPackagesRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+// A package, the number of versions it has and which version each of
+// its labels is on.
+type PackageSummary struct {
+	PackageName          string            `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Versions             int32             `protobuf:"varint,2,opt,name=Versions,proto3" json:"Versions,omitempty"`
+	Labels               map[string]string `protobuf:"bytes,3,rep,name=Labels,proto3" json:"Labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *PackageSummary) Reset()         { *m = PackageSummary{} }
+func (m *PackageSummary) String() string { return proto.CompactTextString(m) }
+func (*PackageSummary) ProtoMessage()    {}
+func (*PackageSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{15}
+}
+func (m *PackageSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageSummary.Unmarshal(m, b)
+}
+func (m *PackageSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageSummary.Marshal(b, m, deterministic)
+}
+func (dst *PackageSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageSummary.Merge(dst, src)
+}
+func (m *PackageSummary) XXX_Size() int {
+	return xxx_messageInfo_PackageSummary.Size(m)
+}
+func (m *PackageSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageSummary proto.InternalMessageInfo
+
+func (m *PackageSummary) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *PackageSummary) GetVersions() int32 {
+	if m != nil {
+		return m.Versions
+	}
+	return 0
+}
+
+func (m *PackageSummary) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+// A page of packages. NextPageToken is empty on the last page.
+type PackageList struct {
+	Packages             []*PackageSummary `protobuf:"bytes,1,rep,name=Packages,proto3" json:"Packages,omitempty"`
+	NextPageToken        string            `protobuf:"bytes,2,opt,name=NextPageToken,proto3" json:"NextPageToken,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *PackageList) Reset()         { *m = PackageList{} }
+func (m *PackageList) String() string { return proto.CompactTextString(m) }
+func (*PackageList) ProtoMessage()    {}
+func (*PackageList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{16}
+}
+func (m *PackageList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageList.Unmarshal(m, b)
+}
+func (m *PackageList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageList.Marshal(b, m, deterministic)
+}
+func (dst *PackageList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageList.Merge(dst, src)
+}
+func (m *PackageList) XXX_Size() int {
+	return xxx_messageInfo_PackageList.Size(m)
+}
+func (m *PackageList) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageList proto.InternalMessageInfo
+
+func (m *PackageList) GetPackages() []*PackageSummary {
+	if m != nil {
+		return m.Packages
+	}
+	return nil
+}
+
+func (m *PackageList) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+// Delete a version of a package or, with no Version, the whole
+// package. Versions carrying protected labels are only deleted if
+// Force is set.
+type DeleteRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
+	Force                bool     `protobuf:"varint,3,opt,name=Force,proto3" json:"Force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{17}
+}
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
+}
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(dst, src)
+}
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
+}
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
+
+func (m *DeleteRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *DeleteRequest) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *DeleteRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+// Ask which versions the retention policy would delete, for one
+// package or (with no PackageName) all of them.
+type RetentionReportRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RetentionReportRequest) Reset()         { *m = RetentionReportRequest{} }
+func (m *RetentionReportRequest) String() string { return proto.CompactTextString(m) }
+func (*RetentionReportRequest) ProtoMessage()    {}
+func (*RetentionReportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{18}
+}
+func (m *RetentionReportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RetentionReportRequest.Unmarshal(m, b)
+}
+func (m *RetentionReportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RetentionReportRequest.Marshal(b, m, deterministic)
+}
+func (dst *RetentionReportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RetentionReportRequest.Merge(dst, src)
+}
+func (m *RetentionReportRequest) XXX_Size() int {
+	return xxx_messageInfo_RetentionReportRequest.Size(m)
+}
+func (m *RetentionReportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RetentionReportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RetentionReportRequest proto.InternalMessageInfo
+
+func (m *RetentionReportRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+// A version the retention policy would delete. Created is in seconds
+// since the epoch.
+type ExpiredVersion struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Version              string   `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
+	Created              int64    `protobuf:"varint,3,opt,name=Created,proto3" json:"Created,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExpiredVersion) Reset()         { *m = ExpiredVersion{} }
+func (m *ExpiredVersion) String() string { return proto.CompactTextString(m) }
+func (*ExpiredVersion) ProtoMessage()    {}
+func (*ExpiredVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{19}
+}
+func (m *ExpiredVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExpiredVersion.Unmarshal(m, b)
+}
+func (m *ExpiredVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExpiredVersion.Marshal(b, m, deterministic)
+}
+func (dst *ExpiredVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExpiredVersion.Merge(dst, src)
+}
+func (m *ExpiredVersion) XXX_Size() int {
+	return xxx_messageInfo_ExpiredVersion.Size(m)
+}
+func (m *ExpiredVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExpiredVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExpiredVersion proto.InternalMessageInfo
+
+func (m *ExpiredVersion) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *ExpiredVersion) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *ExpiredVersion) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+type RetentionReport struct {
+	Versions             []*ExpiredVersion `protobuf:"bytes,1,rep,name=Versions,proto3" json:"Versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *RetentionReport) Reset()         { *m = RetentionReport{} }
+func (m *RetentionReport) String() string { return proto.CompactTextString(m) }
+func (*RetentionReport) ProtoMessage()    {}
+func (*RetentionReport) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{20}
+}
+func (m *RetentionReport) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RetentionReport.Unmarshal(m, b)
+}
+func (m *RetentionReport) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RetentionReport.Marshal(b, m, deterministic)
+}
+func (dst *RetentionReport) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RetentionReport.Merge(dst, src)
+}
+func (m *RetentionReport) XXX_Size() int {
+	return xxx_messageInfo_RetentionReport.Size(m)
+}
+func (m *RetentionReport) XXX_DiscardUnknown() {
+	xxx_messageInfo_RetentionReport.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RetentionReport proto.InternalMessageInfo
+
+func (m *RetentionReport) GetVersions() []*ExpiredVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+// Ask for what changed between an installed version of a package and
+// the version a designator refers to.
+type DeltaRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	InstalledVersion     string   `protobuf:"bytes,2,opt,name=InstalledVersion,proto3" json:"InstalledVersion,omitempty"`
+	Designator           string   `protobuf:"bytes,3,opt,name=Designator,proto3" json:"Designator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeltaRequest) Reset()         { *m = DeltaRequest{} }
+func (m *DeltaRequest) String() string { return proto.CompactTextString(m) }
+func (*DeltaRequest) ProtoMessage()    {}
+func (*DeltaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{21}
+}
+func (m *DeltaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeltaRequest.Unmarshal(m, b)
+}
+func (m *DeltaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeltaRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeltaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeltaRequest.Merge(dst, src)
+}
+func (m *DeltaRequest) XXX_Size() int {
+	return xxx_messageInfo_DeltaRequest.Size(m)
+}
+func (m *DeltaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeltaRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeltaRequest proto.InternalMessageInfo
+
+func (m *DeltaRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *DeltaRequest) GetInstalledVersion() string {
+	if m != nil {
+		return m.InstalledVersion
+	}
+	return ""
+}
+
+func (m *DeltaRequest) GetDesignator() string {
+	if m != nil {
+		return m.Designator
+	}
+	return ""
+}
+
+// A file, or directory, in the version being installed. Hash is the
+// SHA-512 (in hex) of the contents, and empty for directories. If the
+// installed version has a file with the same contents, Installed is
+// its name there, otherwise the contents are sent.
+type DeltaFile struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Owner                string   `protobuf:"bytes,2,opt,name=Owner,proto3" json:"Owner,omitempty"`
+	Mode                 int32    `protobuf:"varint,3,opt,name=Mode,proto3" json:"Mode,omitempty"`
+	Size                 int64    `protobuf:"varint,4,opt,name=Size,proto3" json:"Size,omitempty"`
+	Hash                 string   `protobuf:"bytes,5,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	Installed            string   `protobuf:"bytes,6,opt,name=Installed,proto3" json:"Installed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeltaFile) Reset()         { *m = DeltaFile{} }
+func (m *DeltaFile) String() string { return proto.CompactTextString(m) }
+func (*DeltaFile) ProtoMessage()    {}
+func (*DeltaFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{22}
+}
+func (m *DeltaFile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeltaFile.Unmarshal(m, b)
+}
+func (m *DeltaFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeltaFile.Marshal(b, m, deterministic)
+}
+func (dst *DeltaFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeltaFile.Merge(dst, src)
+}
+func (m *DeltaFile) XXX_Size() int {
+	return xxx_messageInfo_DeltaFile.Size(m)
+}
+func (m *DeltaFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeltaFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeltaFile proto.InternalMessageInfo
+
+func (m *DeltaFile) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DeltaFile) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *DeltaFile) GetMode() int32 {
+	if m != nil {
+		return m.Mode
+	}
+	return 0
+}
+
+func (m *DeltaFile) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *DeltaFile) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *DeltaFile) GetInstalled() string {
+	if m != nil {
+		return m.Installed
+	}
+	return ""
+}
+
+// A delta download is a stream of these. The first message carries
+// the package information, every file in the new version and the
+// names of the files that are gone. After that come the contents of
+// the changed files, each message carrying (part of) the contents
+// with the hash in Hash.
+type DeltaChunk struct {
+	PackageData          *PackageInformation `protobuf:"bytes,1,opt,name=PackageData,proto3" json:"PackageData,omitempty"`
+	Files                []*DeltaFile        `protobuf:"bytes,2,rep,name=Files,proto3" json:"Files,omitempty"`
+	Removed              []string            `protobuf:"bytes,3,rep,name=Removed,proto3" json:"Removed,omitempty"`
+	Hash                 string              `protobuf:"bytes,4,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	Data                 []byte              `protobuf:"bytes,5,opt,name=Data,proto3" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *DeltaChunk) Reset()         { *m = DeltaChunk{} }
+func (m *DeltaChunk) String() string { return proto.CompactTextString(m) }
+func (*DeltaChunk) ProtoMessage()    {}
+func (*DeltaChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{23}
+}
+func (m *DeltaChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeltaChunk.Unmarshal(m, b)
+}
+func (m *DeltaChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeltaChunk.Marshal(b, m, deterministic)
+}
+func (dst *DeltaChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeltaChunk.Merge(dst, src)
+}
+func (m *DeltaChunk) XXX_Size() int {
+	return xxx_messageInfo_DeltaChunk.Size(m)
+}
+func (m *DeltaChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeltaChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeltaChunk proto.InternalMessageInfo
+
+func (m *DeltaChunk) GetPackageData() *PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+func (m *DeltaChunk) GetFiles() []*DeltaFile {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+func (m *DeltaChunk) GetRemoved() []string {
+	if m != nil {
+		return m.Removed
+	}
+	return nil
+}
+
+func (m *DeltaChunk) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *DeltaChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Ask for the files in a version of a package. The groups listed are
+// the ones the version was first uploaded with: the group is not part
+// of the version hash, so uploading the same contents with other
+// groups returns the existing version, groups unchanged.
+type ListFilesRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Designator           string   `protobuf:"bytes,2,opt,name=Designator,proto3" json:"Designator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFilesRequest) Reset()         { *m = ListFilesRequest{} }
+func (m *ListFilesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFilesRequest) ProtoMessage()    {}
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{24}
+}
+func (m *ListFilesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFilesRequest.Unmarshal(m, b)
+}
+func (m *ListFilesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFilesRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListFilesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFilesRequest.Merge(dst, src)
+}
+func (m *ListFilesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFilesRequest.Size(m)
+}
+func (m *ListFilesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFilesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFilesRequest proto.InternalMessageInfo
+
+func (m *ListFilesRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *ListFilesRequest) GetDesignator() string {
+	if m != nil {
+		return m.Designator
+	}
+	return ""
+}
+
+// A file, or directory, in a package version. Type is "file" or
+// "dir", directory names end in "/". Hash is the SHA-512 (in hex) of
+// the contents, and empty for directories. Group is not part of the
+// version.
+type FileEntry struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	Size                 int64    `protobuf:"varint,3,opt,name=Size,proto3" json:"Size,omitempty"`
+	Mode                 int32    `protobuf:"varint,4,opt,name=Mode,proto3" json:"Mode,omitempty"`
+	Owner                string   `protobuf:"bytes,5,opt,name=Owner,proto3" json:"Owner,omitempty"`
+	Group                string   `protobuf:"bytes,6,opt,name=Group,proto3" json:"Group,omitempty"`
+	Hash                 string   `protobuf:"bytes,7,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileEntry) Reset()         { *m = FileEntry{} }
+func (m *FileEntry) String() string { return proto.CompactTextString(m) }
+func (*FileEntry) ProtoMessage()    {}
+func (*FileEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{25}
+}
+func (m *FileEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileEntry.Unmarshal(m, b)
+}
+func (m *FileEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileEntry.Marshal(b, m, deterministic)
+}
+func (dst *FileEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileEntry.Merge(dst, src)
+}
+func (m *FileEntry) XXX_Size() int {
+	return xxx_messageInfo_FileEntry.Size(m)
+}
+func (m *FileEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileEntry proto.InternalMessageInfo
+
+func (m *FileEntry) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FileEntry) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *FileEntry) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *FileEntry) GetMode() int32 {
+	if m != nil {
+		return m.Mode
+	}
+	return 0
+}
+
+func (m *FileEntry) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *FileEntry) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *FileEntry) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+// The files in a package version, in the order they are hashed in.
+type FileList struct {
+	PackageData          *PackageInformation `protobuf:"bytes,1,opt,name=PackageData,proto3" json:"PackageData,omitempty"`
+	Files                []*FileEntry        `protobuf:"bytes,2,rep,name=Files,proto3" json:"Files,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *FileList) Reset()         { *m = FileList{} }
+func (m *FileList) String() string { return proto.CompactTextString(m) }
+func (*FileList) ProtoMessage()    {}
+func (*FileList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{26}
+}
+func (m *FileList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileList.Unmarshal(m, b)
+}
+func (m *FileList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileList.Marshal(b, m, deterministic)
+}
+func (dst *FileList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileList.Merge(dst, src)
+}
+func (m *FileList) XXX_Size() int {
+	return xxx_messageInfo_FileList.Size(m)
+}
+func (m *FileList) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileList proto.InternalMessageInfo
+
+func (m *FileList) GetPackageData() *PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+func (m *FileList) GetFiles() []*FileEntry {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+// Ask for the contents of a single file in a version of a package.
+type GetFileRequest struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Designator           string   `protobuf:"bytes,2,opt,name=Designator,proto3" json:"Designator,omitempty"`
+	Path                 string   `protobuf:"bytes,3,opt,name=Path,proto3" json:"Path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetFileRequest) Reset()         { *m = GetFileRequest{} }
+func (m *GetFileRequest) String() string { return proto.CompactTextString(m) }
+func (*GetFileRequest) ProtoMessage()    {}
+func (*GetFileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{27}
+}
+func (m *GetFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetFileRequest.Unmarshal(m, b)
+}
+func (m *GetFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetFileRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetFileRequest.Merge(dst, src)
+}
+func (m *GetFileRequest) XXX_Size() int {
+	return xxx_messageInfo_GetFileRequest.Size(m)
+}
+func (m *GetFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetFileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetFileRequest proto.InternalMessageInfo
+
+func (m *GetFileRequest) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *GetFileRequest) GetDesignator() string {
+	if m != nil {
+		return m.Designator
+	}
+	return ""
+}
+
+func (m *GetFileRequest) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+// The contents of a file are sent as a stream of these. The first
+// message carries the package information and a description of the
+// file; all messages carry data.
+type FileChunk struct {
+	PackageData          *PackageInformation `protobuf:"bytes,1,opt,name=PackageData,proto3" json:"PackageData,omitempty"`
+	File                 *FileEntry          `protobuf:"bytes,2,opt,name=File,proto3" json:"File,omitempty"`
+	Data                 []byte              `protobuf:"bytes,3,opt,name=Data,proto3" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *FileChunk) Reset()         { *m = FileChunk{} }
+func (m *FileChunk) String() string { return proto.CompactTextString(m) }
+func (*FileChunk) ProtoMessage()    {}
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{28}
+}
+func (m *FileChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileChunk.Unmarshal(m, b)
+}
+func (m *FileChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileChunk.Marshal(b, m, deterministic)
+}
+func (dst *FileChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileChunk.Merge(dst, src)
+}
+func (m *FileChunk) XXX_Size() int {
+	return xxx_messageInfo_FileChunk.Size(m)
+}
+func (m *FileChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileChunk proto.InternalMessageInfo
+
+func (m *FileChunk) GetPackageData() *PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+func (m *FileChunk) GetFile() *FileEntry {
+	if m != nil {
+		return m.File
+	}
+	return nil
+}
 
-func (m *PackageInformationResponse) GetPackageData() []*PackageInformation {
+func (m *FileChunk) GetData() []byte {
 	if m != nil {
-		return m.PackageData
+		return m.Data
 	}
 	return nil
 }
@@ -217,7 +1709,7 @@ func (m *File) Reset()         { *m = File{} }
 func (m *File) String() string { return proto.CompactTextString(m) }
 func (*File) ProtoMessage()    {}
 func (*File) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{4}
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{29}
 }
 func (m *File) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_File.Unmarshal(m, b)
@@ -284,7 +1776,7 @@ func (m *NewPackage) Reset()         { *m = NewPackage{} }
 func (m *NewPackage) String() string { return proto.CompactTextString(m) }
 func (*NewPackage) ProtoMessage()    {}
 func (*NewPackage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{5}
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{30}
 }
 func (m *NewPackage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NewPackage.Unmarshal(m, b)
@@ -318,6 +1810,454 @@ func (m *NewPackage) GetFiles() []*File {
 	return nil
 }
 
+// The first message of a streamed upload. Any labels are set on the
+// new version, in addition to "latest".
+type UploadHeader struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Label                []string `protobuf:"bytes,2,rep,name=Label,proto3" json:"Label,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadHeader) Reset()         { *m = UploadHeader{} }
+func (m *UploadHeader) String() string { return proto.CompactTextString(m) }
+func (*UploadHeader) ProtoMessage()    {}
+func (*UploadHeader) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{31}
+}
+func (m *UploadHeader) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UploadHeader.Unmarshal(m, b)
+}
+func (m *UploadHeader) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UploadHeader.Marshal(b, m, deterministic)
+}
+func (dst *UploadHeader) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadHeader.Merge(dst, src)
+}
+func (m *UploadHeader) XXX_Size() int {
+	return xxx_messageInfo_UploadHeader.Size(m)
+}
+func (m *UploadHeader) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadHeader.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UploadHeader proto.InternalMessageInfo
+
+func (m *UploadHeader) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *UploadHeader) GetLabel() []string {
+	if m != nil {
+		return m.Label
+	}
+	return nil
+}
+
+// A message in a streamed upload. The stream starts with a Header,
+// then each file is sent as a File (with empty Contents), followed by
+// any number of Data messages carrying its contents.
+type UploadRequest struct {
+	// Types that are valid to be assigned to Part:
+	//	*UploadRequest_Header
+	//	*UploadRequest_File
+	//	*UploadRequest_Data
+	Part                 isUploadRequest_Part `protobuf_oneof:"Part"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *UploadRequest) Reset()         { *m = UploadRequest{} }
+func (m *UploadRequest) String() string { return proto.CompactTextString(m) }
+func (*UploadRequest) ProtoMessage()    {}
+func (*UploadRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{32}
+}
+func (m *UploadRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UploadRequest.Unmarshal(m, b)
+}
+func (m *UploadRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UploadRequest.Marshal(b, m, deterministic)
+}
+func (dst *UploadRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadRequest.Merge(dst, src)
+}
+func (m *UploadRequest) XXX_Size() int {
+	return xxx_messageInfo_UploadRequest.Size(m)
+}
+func (m *UploadRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UploadRequest proto.InternalMessageInfo
+
+type isUploadRequest_Part interface {
+	isUploadRequest_Part()
+}
+
+type UploadRequest_Header struct {
+	Header *UploadHeader `protobuf:"bytes,1,opt,name=Header,proto3,oneof"`
+}
+
+type UploadRequest_File struct {
+	File *File `protobuf:"bytes,2,opt,name=File,proto3,oneof"`
+}
+
+type UploadRequest_Data struct {
+	Data []byte `protobuf:"bytes,3,opt,name=Data,proto3,oneof"`
+}
+
+func (*UploadRequest_Header) isUploadRequest_Part() {}
+
+func (*UploadRequest_File) isUploadRequest_Part() {}
+
+func (*UploadRequest_Data) isUploadRequest_Part() {}
+
+func (m *UploadRequest) GetPart() isUploadRequest_Part {
+	if m != nil {
+		return m.Part
+	}
+	return nil
+}
+
+func (m *UploadRequest) GetHeader() *UploadHeader {
+	if x, ok := m.GetPart().(*UploadRequest_Header); ok {
+		return x.Header
+	}
+	return nil
+}
+
+func (m *UploadRequest) GetFile() *File {
+	if x, ok := m.GetPart().(*UploadRequest_File); ok {
+		return x.File
+	}
+	return nil
+}
+
+func (m *UploadRequest) GetData() []byte {
+	if x, ok := m.GetPart().(*UploadRequest_Data); ok {
+		return x.Data
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*UploadRequest) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _UploadRequest_OneofMarshaler, _UploadRequest_OneofUnmarshaler, _UploadRequest_OneofSizer, []interface{}{
+		(*UploadRequest_Header)(nil),
+		(*UploadRequest_File)(nil),
+		(*UploadRequest_Data)(nil),
+	}
+}
+
+func _UploadRequest_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*UploadRequest)
+	// Part
+	switch x := m.Part.(type) {
+	case *UploadRequest_Header:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Header); err != nil {
+			return err
+		}
+	case *UploadRequest_File:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.File); err != nil {
+			return err
+		}
+	case *UploadRequest_Data:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.Data)
+	case nil:
+	default:
+		return fmt.Errorf("UploadRequest.Part has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _UploadRequest_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*UploadRequest)
+	switch tag {
+	case 1: // Part.Header
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(UploadHeader)
+		err := b.DecodeMessage(msg)
+		m.Part = &UploadRequest_Header{msg}
+		return true, err
+	case 2: // Part.File
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(File)
+		err := b.DecodeMessage(msg)
+		m.Part = &UploadRequest_File{msg}
+		return true, err
+	case 3: // Part.Data
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Part = &UploadRequest_Data{x}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _UploadRequest_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*UploadRequest)
+	// Part
+	switch x := m.Part.(type) {
+	case *UploadRequest_Header:
+		s := proto.Size(x.Header)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *UploadRequest_File:
+		s := proto.Size(x.File)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *UploadRequest_Data:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.Data)))
+		n += len(x.Data)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type UploadSessionRequest struct {
+	SessionId            string   `protobuf:"bytes,1,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UploadSessionRequest) Reset()         { *m = UploadSessionRequest{} }
+func (m *UploadSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*UploadSessionRequest) ProtoMessage()    {}
+func (*UploadSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{33}
+}
+func (m *UploadSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UploadSessionRequest.Unmarshal(m, b)
+}
+func (m *UploadSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UploadSessionRequest.Marshal(b, m, deterministic)
+}
+func (dst *UploadSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadSessionRequest.Merge(dst, src)
+}
+func (m *UploadSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_UploadSessionRequest.Size(m)
+}
+func (m *UploadSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UploadSessionRequest proto.InternalMessageInfo
+
+func (m *UploadSessionRequest) GetSessionId() string {
+	if m != nil {
+		return m.SessionId
+	}
+	return ""
+}
+
+// How much of a file the server has received in an upload session.
+type FileOffset struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Offset               int64    `protobuf:"varint,2,opt,name=Offset,proto3" json:"Offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileOffset) Reset()         { *m = FileOffset{} }
+func (m *FileOffset) String() string { return proto.CompactTextString(m) }
+func (*FileOffset) ProtoMessage()    {}
+func (*FileOffset) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{34}
+}
+func (m *FileOffset) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileOffset.Unmarshal(m, b)
+}
+func (m *FileOffset) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileOffset.Marshal(b, m, deterministic)
+}
+func (dst *FileOffset) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileOffset.Merge(dst, src)
+}
+func (m *FileOffset) XXX_Size() int {
+	return xxx_messageInfo_FileOffset.Size(m)
+}
+func (m *FileOffset) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileOffset.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileOffset proto.InternalMessageInfo
+
+func (m *FileOffset) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FileOffset) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+// The state of an upload session. ExpiresAt is in seconds since the
+// epoch, and is pushed forward every time the session is used.
+type UploadSessionStatus struct {
+	SessionId            string        `protobuf:"bytes,1,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
+	PackageName          string        `protobuf:"bytes,2,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	Files                []*FileOffset `protobuf:"bytes,3,rep,name=Files,proto3" json:"Files,omitempty"`
+	ExpiresAt            int64         `protobuf:"varint,4,opt,name=ExpiresAt,proto3" json:"ExpiresAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *UploadSessionStatus) Reset()         { *m = UploadSessionStatus{} }
+func (m *UploadSessionStatus) String() string { return proto.CompactTextString(m) }
+func (*UploadSessionStatus) ProtoMessage()    {}
+func (*UploadSessionStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{35}
+}
+func (m *UploadSessionStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UploadSessionStatus.Unmarshal(m, b)
+}
+func (m *UploadSessionStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UploadSessionStatus.Marshal(b, m, deterministic)
+}
+func (dst *UploadSessionStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UploadSessionStatus.Merge(dst, src)
+}
+func (m *UploadSessionStatus) XXX_Size() int {
+	return xxx_messageInfo_UploadSessionStatus.Size(m)
+}
+func (m *UploadSessionStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_UploadSessionStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UploadSessionStatus proto.InternalMessageInfo
+
+func (m *UploadSessionStatus) GetSessionId() string {
+	if m != nil {
+		return m.SessionId
+	}
+	return ""
+}
+
+func (m *UploadSessionStatus) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *UploadSessionStatus) GetFiles() []*FileOffset {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+func (m *UploadSessionStatus) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+// A message in a resumable upload. A File announces a file (resuming
+// clients announce files again, that is fine). Data is written to the
+// file called Name, at Offset, which must be where the server's
+// acknowledged data for that file ends.
+type SessionUploadRequest struct {
+	SessionId            string   `protobuf:"bytes,1,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
+	File                 *File    `protobuf:"bytes,2,opt,name=File,proto3" json:"File,omitempty"`
+	Name                 string   `protobuf:"bytes,3,opt,name=Name,proto3" json:"Name,omitempty"`
+	Offset               int64    `protobuf:"varint,4,opt,name=Offset,proto3" json:"Offset,omitempty"`
+	Data                 []byte   `protobuf:"bytes,5,opt,name=Data,proto3" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionUploadRequest) Reset()         { *m = SessionUploadRequest{} }
+func (m *SessionUploadRequest) String() string { return proto.CompactTextString(m) }
+func (*SessionUploadRequest) ProtoMessage()    {}
+func (*SessionUploadRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{36}
+}
+func (m *SessionUploadRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionUploadRequest.Unmarshal(m, b)
+}
+func (m *SessionUploadRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionUploadRequest.Marshal(b, m, deterministic)
+}
+func (dst *SessionUploadRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionUploadRequest.Merge(dst, src)
+}
+func (m *SessionUploadRequest) XXX_Size() int {
+	return xxx_messageInfo_SessionUploadRequest.Size(m)
+}
+func (m *SessionUploadRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionUploadRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionUploadRequest proto.InternalMessageInfo
+
+func (m *SessionUploadRequest) GetSessionId() string {
+	if m != nil {
+		return m.SessionId
+	}
+	return ""
+}
+
+func (m *SessionUploadRequest) GetFile() *File {
+	if m != nil {
+		return m.File
+	}
+	return nil
+}
+
+func (m *SessionUploadRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SessionUploadRequest) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *SessionUploadRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 type GetPackageRequest struct {
 	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
 	Designator           string   `protobuf:"bytes,2,opt,name=Designator,proto3" json:"Designator,omitempty"`
@@ -330,7 +2270,7 @@ func (m *GetPackageRequest) Reset()         { *m = GetPackageRequest{} }
 func (m *GetPackageRequest) String() string { return proto.CompactTextString(m) }
 func (*GetPackageRequest) ProtoMessage()    {}
 func (*GetPackageRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{6}
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{37}
 }
 func (m *GetPackageRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPackageRequest.Unmarshal(m, b)
@@ -376,7 +2316,7 @@ func (m *GetPackageResponse) Reset()         { *m = GetPackageResponse{} }
 func (m *GetPackageResponse) String() string { return proto.CompactTextString(m) }
 func (*GetPackageResponse) ProtoMessage()    {}
 func (*GetPackageResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_mspm_484a52839256b41d, []int{7}
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{38}
 }
 func (m *GetPackageResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetPackageResponse.Unmarshal(m, b)
@@ -410,47 +2350,281 @@ func (m *GetPackageResponse) GetData() []byte {
 	return nil
 }
 
+// A piece of a package tarball. The first message in a download
+// carries the package information, the total size of the tarball and
+// its SHA-512 checksum (in hex); all messages carry data.
+type PackageChunk struct {
+	PackageData          *PackageInformation `protobuf:"bytes,1,opt,name=PackageData,proto3" json:"PackageData,omitempty"`
+	Size                 int64               `protobuf:"varint,2,opt,name=Size,proto3" json:"Size,omitempty"`
+	Checksum             string              `protobuf:"bytes,3,opt,name=Checksum,proto3" json:"Checksum,omitempty"`
+	Data                 []byte              `protobuf:"bytes,4,opt,name=Data,proto3" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *PackageChunk) Reset()         { *m = PackageChunk{} }
+func (m *PackageChunk) String() string { return proto.CompactTextString(m) }
+func (*PackageChunk) ProtoMessage()    {}
+func (*PackageChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{39}
+}
+func (m *PackageChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageChunk.Unmarshal(m, b)
+}
+func (m *PackageChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageChunk.Marshal(b, m, deterministic)
+}
+func (dst *PackageChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageChunk.Merge(dst, src)
+}
+func (m *PackageChunk) XXX_Size() int {
+	return xxx_messageInfo_PackageChunk.Size(m)
+}
+func (m *PackageChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageChunk proto.InternalMessageInfo
+
+func (m *PackageChunk) GetPackageData() *PackageInformation {
+	if m != nil {
+		return m.PackageData
+	}
+	return nil
+}
+
+func (m *PackageChunk) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *PackageChunk) GetChecksum() string {
+	if m != nil {
+		return m.Checksum
+	}
+	return ""
+}
+
+func (m *PackageChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Per-package settings. If DisableLatest is set, uploading a new
+// version does not move the "latest" label to it.
+type PackageOptions struct {
+	PackageName          string   `protobuf:"bytes,1,opt,name=PackageName,proto3" json:"PackageName,omitempty"`
+	DisableLatest        bool     `protobuf:"varint,2,opt,name=DisableLatest,proto3" json:"DisableLatest,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PackageOptions) Reset()         { *m = PackageOptions{} }
+func (m *PackageOptions) String() string { return proto.CompactTextString(m) }
+func (*PackageOptions) ProtoMessage()    {}
+func (*PackageOptions) Descriptor() ([]byte, []int) {
+	return fileDescriptor_mspm_ef1e3457bd6059b4, []int{40}
+}
+func (m *PackageOptions) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PackageOptions.Unmarshal(m, b)
+}
+func (m *PackageOptions) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PackageOptions.Marshal(b, m, deterministic)
+}
+func (dst *PackageOptions) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PackageOptions.Merge(dst, src)
+}
+func (m *PackageOptions) XXX_Size() int {
+	return xxx_messageInfo_PackageOptions.Size(m)
+}
+func (m *PackageOptions) XXX_DiscardUnknown() {
+	xxx_messageInfo_PackageOptions.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PackageOptions proto.InternalMessageInfo
+
+func (m *PackageOptions) GetPackageName() string {
+	if m != nil {
+		return m.PackageName
+	}
+	return ""
+}
+
+func (m *PackageOptions) GetDisableLatest() bool {
+	if m != nil {
+		return m.DisableLatest
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*SetLabelRequest)(nil), "mspm.SetLabelRequest")
+	proto.RegisterMapType((map[string]string)(nil), "mspm.SetLabelRequest.ExpectedEntry")
+	proto.RegisterType((*LabelChange)(nil), "mspm.LabelChange")
+	proto.RegisterType((*LabelTransaction)(nil), "mspm.LabelTransaction")
+	proto.RegisterType((*LabelTransactionResponse)(nil), "mspm.LabelTransactionResponse")
+	proto.RegisterType((*LabelHistoryRequest)(nil), "mspm.LabelHistoryRequest")
+	proto.RegisterType((*LabelEvent)(nil), "mspm.LabelEvent")
+	proto.RegisterType((*LabelHistory)(nil), "mspm.LabelHistory")
+	proto.RegisterType((*RollbackLabelRequest)(nil), "mspm.RollbackLabelRequest")
+	proto.RegisterType((*AuditQuery)(nil), "mspm.AuditQuery")
+	proto.RegisterType((*AuditEntry)(nil), "mspm.AuditEntry")
+	proto.RegisterType((*AuditEntries)(nil), "mspm.AuditEntries")
 	proto.RegisterType((*PackageInformationRequest)(nil), "mspm.PackageInformationRequest")
 	proto.RegisterType((*PackageInformation)(nil), "mspm.PackageInformation")
 	proto.RegisterType((*PackageInformationResponse)(nil), "mspm.PackageInformationResponse")
+	proto.RegisterType((*ListPackagesRequest)(nil), "mspm.ListPackagesRequest")
+	proto.RegisterType((*PackageSummary)(nil), "mspm.PackageSummary")
+	proto.RegisterMapType((map[string]string)(nil), "mspm.PackageSummary.LabelsEntry")
+	proto.RegisterType((*PackageList)(nil), "mspm.PackageList")
+	proto.RegisterType((*DeleteRequest)(nil), "mspm.DeleteRequest")
+	proto.RegisterType((*RetentionReportRequest)(nil), "mspm.RetentionReportRequest")
+	proto.RegisterType((*ExpiredVersion)(nil), "mspm.ExpiredVersion")
+	proto.RegisterType((*RetentionReport)(nil), "mspm.RetentionReport")
+	proto.RegisterType((*DeltaRequest)(nil), "mspm.DeltaRequest")
+	proto.RegisterType((*DeltaFile)(nil), "mspm.DeltaFile")
+	proto.RegisterType((*DeltaChunk)(nil), "mspm.DeltaChunk")
+	proto.RegisterType((*ListFilesRequest)(nil), "mspm.ListFilesRequest")
+	proto.RegisterType((*FileEntry)(nil), "mspm.FileEntry")
+	proto.RegisterType((*FileList)(nil), "mspm.FileList")
+	proto.RegisterType((*GetFileRequest)(nil), "mspm.GetFileRequest")
+	proto.RegisterType((*FileChunk)(nil), "mspm.FileChunk")
 	proto.RegisterType((*File)(nil), "mspm.File")
 	proto.RegisterType((*NewPackage)(nil), "mspm.NewPackage")
+	proto.RegisterType((*UploadHeader)(nil), "mspm.UploadHeader")
+	proto.RegisterType((*UploadRequest)(nil), "mspm.UploadRequest")
+	proto.RegisterType((*UploadSessionRequest)(nil), "mspm.UploadSessionRequest")
+	proto.RegisterType((*FileOffset)(nil), "mspm.FileOffset")
+	proto.RegisterType((*UploadSessionStatus)(nil), "mspm.UploadSessionStatus")
+	proto.RegisterType((*SessionUploadRequest)(nil), "mspm.SessionUploadRequest")
 	proto.RegisterType((*GetPackageRequest)(nil), "mspm.GetPackageRequest")
 	proto.RegisterType((*GetPackageResponse)(nil), "mspm.GetPackageResponse")
+	proto.RegisterType((*PackageChunk)(nil), "mspm.PackageChunk")
+	proto.RegisterType((*PackageOptions)(nil), "mspm.PackageOptions")
 }
 
-func init() { proto.RegisterFile("mspm.proto", fileDescriptor_mspm_484a52839256b41d) }
-
-var fileDescriptor_mspm_484a52839256b41d = []byte{
-	// 435 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x54, 0x51, 0x6f, 0xd3, 0x30,
-	0x10, 0x6e, 0x9a, 0x04, 0xe8, 0x0d, 0x04, 0x58, 0x4c, 0x98, 0x3e, 0x80, 0x31, 0x2f, 0x79, 0x6a,
-	0xa4, 0xf1, 0x86, 0x34, 0x24, 0x60, 0x62, 0x42, 0x62, 0x63, 0x0a, 0x1a, 0x42, 0x88, 0x17, 0xb7,
-	0xbd, 0x85, 0x68, 0x8d, 0x1d, 0x62, 0x77, 0xfb, 0x47, 0xfc, 0x4e, 0x64, 0x3b, 0x59, 0x23, 0xda,
-	0xa0, 0xbc, 0xec, 0xa9, 0x77, 0xe7, 0xfb, 0xee, 0xbe, 0xfb, 0xee, 0x1a, 0x80, 0x52, 0x57, 0xe5,
-	0xac, 0xaa, 0x95, 0x51, 0x24, 0xb2, 0x36, 0x5f, 0xc0, 0xc3, 0xaf, 0x68, 0x3e, 0x8b, 0x39, 0xae,
-	0x32, 0xfc, 0xbd, 0x46, 0x6d, 0x08, 0x83, 0xbd, 0x33, 0xb1, 0xb8, 0x14, 0x39, 0x9e, 0x8a, 0x12,
-	0x69, 0xc0, 0x82, 0x64, 0x92, 0x75, 0x43, 0x84, 0xc2, 0xdd, 0x6f, 0x58, 0xeb, 0x42, 0x49, 0x3a,
-	0x76, 0xaf, 0xad, 0x4b, 0x9e, 0x40, 0xec, 0x6a, 0xd1, 0x90, 0x85, 0xc9, 0x24, 0xf3, 0x0e, 0x3f,
-	0x84, 0x67, 0x0d, 0xfc, 0x93, 0xbc, 0x50, 0x75, 0x29, 0x4c, 0xa1, 0xe4, 0xe0, 0x76, 0xfc, 0x02,
-	0xc8, 0x36, 0xfc, 0x16, 0x68, 0x7e, 0x87, 0xe9, 0x2e, 0x9a, 0xba, 0x52, 0x52, 0x23, 0x79, 0x73,
-	0xd3, 0xef, 0x48, 0x18, 0x41, 0x03, 0x16, 0x26, 0x7b, 0x07, 0x74, 0xe6, 0x14, 0xdd, 0x01, 0xeb,
-	0x26, 0xf3, 0x2b, 0x88, 0x3e, 0x16, 0x2b, 0x24, 0x04, 0xa2, 0x0e, 0x59, 0x67, 0x5b, 0x2e, 0x5f,
-	0xae, 0x25, 0xd6, 0x0d, 0x47, 0xef, 0xd8, 0xe8, 0x71, 0xad, 0xd6, 0x15, 0x0d, 0x7d, 0xd4, 0x39,
-	0x16, 0x7f, 0xa2, 0x96, 0x48, 0x23, 0x16, 0x24, 0x71, 0xe6, 0x6c, 0x32, 0x85, 0x7b, 0x1f, 0x94,
-	0x34, 0x28, 0x8d, 0xa6, 0x31, 0x0b, 0x92, 0xfb, 0xd9, 0x8d, 0xcf, 0xcf, 0x00, 0x4e, 0xf1, 0xba,
-	0x61, 0x32, 0x40, 0x31, 0x06, 0xb1, 0xe5, 0xa9, 0xe9, 0xd8, 0x4d, 0x07, 0x7e, 0x3a, 0x1b, 0xca,
-	0xfc, 0x03, 0x3f, 0x87, 0xc7, 0xc7, 0x68, 0x1a, 0xcc, 0xf0, 0x8b, 0x79, 0x0e, 0x70, 0x84, 0xba,
-	0xc8, 0xa5, 0x30, 0xaa, 0x9d, 0xb4, 0x13, 0xe1, 0x4b, 0x20, 0xdd, 0xb2, 0x7d, 0x92, 0x07, 0x83,
-	0x25, 0xb7, 0x52, 0x39, 0xd0, 0xd8, 0x49, 0xe2, 0xec, 0x83, 0x3f, 0x63, 0x88, 0x4e, 0x74, 0x55,
-	0x92, 0xb7, 0x30, 0x69, 0xaf, 0x5e, 0x93, 0x7d, 0x5f, 0xf0, 0x9f, 0xbf, 0xc1, 0xb4, 0xb7, 0x0f,
-	0x1f, 0x91, 0x9f, 0xb0, 0xbf, 0xa1, 0xdb, 0x3d, 0xca, 0x17, 0xbd, 0xe4, 0x9a, 0xaa, 0xac, 0x3f,
-	0xc1, 0x0f, 0xcd, 0x47, 0xe4, 0x10, 0x1e, 0x9c, 0x57, 0x2b, 0x25, 0x96, 0xed, 0xe2, 0x1e, 0x79,
-	0xd0, 0x66, 0x95, 0xff, 0x25, 0xf7, 0x0e, 0x60, 0x43, 0x8e, 0x3c, 0xf5, 0x99, 0x5b, 0x4b, 0x6b,
-	0x4b, 0x6c, 0xcb, 0xce, 0x47, 0xef, 0x5f, 0xfd, 0x78, 0x99, 0x17, 0xe6, 0xd7, 0x7a, 0x3e, 0x5b,
-	0xa8, 0x32, 0xbd, 0x12, 0xa6, 0x90, 0x98, 0xda, 0xf4, 0xb4, 0xba, 0xcc, 0x53, 0xf7, 0xfd, 0xd0,
-	0xf3, 0x3b, 0xee, 0xf7, 0xf5, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x64, 0xe1, 0x8a, 0x27, 0x55,
-	0x04, 0x00, 0x00,
+func init() { proto.RegisterFile("mspm.proto", fileDescriptor_mspm_ef1e3457bd6059b4) }
+
+var fileDescriptor_mspm_ef1e3457bd6059b4 = []byte{
+	// 1818 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0x5f, 0x6f, 0x1c, 0x49,
+	0x11, 0xdf, 0xd9, 0xff, 0x5b, 0xde, 0x4d, 0x9c, 0xb6, 0x63, 0x26, 0xa3, 0x28, 0x2c, 0x9d, 0x03,
+	0x59, 0x07, 0x4a, 0x4e, 0xe1, 0x10, 0x39, 0xa3, 0xcb, 0xe1, 0xf8, 0x3f, 0xd8, 0xb1, 0x99, 0x75,
+	0x4e, 0xa7, 0x88, 0x97, 0xb1, 0xb7, 0xed, 0x1d, 0x79, 0x67, 0x66, 0x99, 0xee, 0x75, 0x6c, 0x04,
+	0x12, 0x9f, 0x00, 0x24, 0x84, 0xc4, 0x03, 0x1f, 0x02, 0xf1, 0xc8, 0x33, 0x5f, 0x87, 0x77, 0x5e,
+	0x51, 0xff, 0x9d, 0x9e, 0xdd, 0x59, 0x7b, 0xad, 0xf8, 0x69, 0xbb, 0xaa, 0x7b, 0xaa, 0xab, 0xea,
+	0x57, 0x55, 0x5d, 0xdd, 0x0b, 0x10, 0xd1, 0x51, 0xf4, 0x62, 0x94, 0x26, 0x2c, 0x41, 0x55, 0x3e,
+	0xc6, 0xff, 0x73, 0xe0, 0x61, 0x8f, 0xb0, 0xfd, 0xe0, 0x84, 0x0c, 0x7d, 0xf2, 0xbb, 0x31, 0xa1,
+	0x0c, 0x75, 0x61, 0xe1, 0x28, 0x38, 0xbd, 0x08, 0xce, 0xc9, 0xbb, 0x20, 0x22, 0xae, 0xd3, 0x75,
+	0x56, 0x5b, 0xbe, 0xcd, 0x42, 0x2e, 0x34, 0xbe, 0x25, 0x29, 0x0d, 0x93, 0xd8, 0x2d, 0x8b, 0x59,
+	0x4d, 0xa2, 0x65, 0xa8, 0x09, 0x59, 0x6e, 0xa5, 0x5b, 0x59, 0x6d, 0xf9, 0x92, 0xe0, 0xdc, 0xed,
+	0x24, 0x3d, 0x25, 0x6e, 0xb5, 0xeb, 0xac, 0x36, 0x7d, 0x49, 0xa0, 0x6f, 0xa0, 0xb9, 0x75, 0x35,
+	0x22, 0xa7, 0x8c, 0xf4, 0xdd, 0x5a, 0xb7, 0xb2, 0xba, 0xf0, 0xea, 0xf9, 0x0b, 0xa1, 0xe0, 0x84,
+	0x42, 0x2f, 0xf4, 0xaa, 0xad, 0x98, 0xa5, 0xd7, 0xbe, 0xf9, 0xc8, 0xfb, 0x05, 0x74, 0x72, 0x53,
+	0x68, 0x11, 0x2a, 0x17, 0xe4, 0x5a, 0x69, 0xcc, 0x87, 0x7c, 0xe7, 0xcb, 0x60, 0x38, 0x26, 0x4a,
+	0x4f, 0x49, 0xac, 0x95, 0x5f, 0x3b, 0xf8, 0xdf, 0x0e, 0x2c, 0x88, 0x5d, 0x36, 0x06, 0x41, 0x7c,
+	0x4e, 0xe6, 0xb0, 0xfa, 0x19, 0xc0, 0x26, 0xa1, 0xe1, 0x79, 0x1c, 0xb0, 0x24, 0x55, 0x02, 0x2d,
+	0x8e, 0x6d, 0xbb, 0x73, 0x9b, 0xed, 0x5e, 0xce, 0x76, 0xbe, 0xdc, 0xd0, 0x5c, 0x13, 0x39, 0x7e,
+	0x1f, 0x53, 0xc2, 0xdc, 0xba, 0xf8, 0xce, 0x66, 0xe1, 0x6f, 0x60, 0x51, 0x08, 0x3f, 0x4e, 0x83,
+	0x98, 0x06, 0xa7, 0x8c, 0x7b, 0xfe, 0xc7, 0xd0, 0x90, 0x96, 0x50, 0xd7, 0x11, 0xce, 0x7c, 0x24,
+	0x9d, 0x69, 0xd9, 0xe8, 0xeb, 0x15, 0xf8, 0x5b, 0x70, 0x27, 0x05, 0xf8, 0x84, 0x8e, 0x92, 0x98,
+	0x12, 0xb4, 0x66, 0x1c, 0xb1, 0x19, 0xb0, 0x40, 0x09, 0x73, 0xa5, 0x30, 0x35, 0xb1, 0x17, 0x9f,
+	0x25, 0x69, 0x14, 0x88, 0xcf, 0xec, 0xc5, 0xf8, 0x00, 0x96, 0x84, 0xdc, 0xdd, 0x90, 0xb2, 0x24,
+	0xbd, 0x9e, 0x3f, 0xa2, 0x8c, 0xef, 0xca, 0x96, 0xef, 0xf0, 0x5f, 0x1d, 0x00, 0x31, 0xda, 0xba,
+	0x24, 0x31, 0xcb, 0x16, 0x39, 0xb6, 0x83, 0x11, 0x54, 0x8f, 0xc3, 0x48, 0x22, 0x5c, 0xf1, 0xc5,
+	0x98, 0x43, 0x75, 0x38, 0xec, 0xeb, 0x18, 0x95, 0x78, 0x58, 0x1c, 0x3e, 0xff, 0x8e, 0x7c, 0xd4,
+	0xf3, 0x55, 0x39, 0x9f, 0x71, 0x38, 0x3c, 0x7b, 0x7d, 0x12, 0xb3, 0x90, 0x5d, 0x6b, 0x78, 0x34,
+	0x8d, 0x3f, 0x40, 0xdb, 0xb6, 0x71, 0x0e, 0xe3, 0x56, 0xa1, 0x2e, 0x0c, 0xa0, 0x6e, 0x59, 0x38,
+	0x73, 0xd1, 0x42, 0x46, 0x4c, 0xf8, 0x6a, 0x1e, 0xf7, 0x61, 0xd9, 0x4f, 0x86, 0xc3, 0x93, 0xe0,
+	0xf4, 0xe2, 0x8e, 0x29, 0x59, 0xe8, 0xc0, 0x2c, 0xf8, 0x2a, 0x56, 0xf0, 0xe1, 0x01, 0xc0, 0xfa,
+	0xb8, 0x1f, 0xb2, 0xdf, 0x8c, 0xc9, 0x5c, 0xfa, 0x23, 0xa8, 0x6e, 0xa7, 0x49, 0xa4, 0x3d, 0xcc,
+	0xc7, 0xe8, 0x01, 0x94, 0x8f, 0x13, 0x21, 0xb6, 0xe2, 0x97, 0x8f, 0x13, 0xb1, 0x7f, 0x18, 0x85,
+	0x4c, 0x38, 0xb3, 0xe6, 0x4b, 0x02, 0xff, 0xd7, 0x51, 0x5b, 0xc9, 0xfc, 0xd4, 0x50, 0x39, 0x16,
+	0x54, 0x2b, 0x50, 0x3f, 0x20, 0x6c, 0x90, 0xf4, 0x95, 0xe6, 0x8a, 0xca, 0x41, 0x50, 0xc9, 0x43,
+	0xc0, 0xe5, 0x1c, 0x11, 0x92, 0x2a, 0xe0, 0xc4, 0x78, 0xd2, 0x8c, 0x9a, 0xa8, 0x3f, 0xb3, 0xaa,
+	0x56, 0x7d, 0x46, 0xd5, 0x6a, 0xd8, 0x55, 0xcb, 0x85, 0x46, 0x6f, 0x1c, 0x45, 0x41, 0x7a, 0xed,
+	0x36, 0xe5, 0x7a, 0x45, 0x72, 0x9d, 0x7d, 0x42, 0xc7, 0x43, 0xe6, 0xb6, 0xa4, 0xce, 0x92, 0xc2,
+	0x6b, 0xd0, 0x36, 0xd6, 0x86, 0x84, 0xa2, 0xcf, 0xa1, 0xa1, 0x86, 0xae, 0x63, 0x23, 0x9f, 0xb9,
+	0xc4, 0xd7, 0x0b, 0xf0, 0xd7, 0xf0, 0xa4, 0x20, 0xbb, 0xe6, 0xc5, 0x1f, 0x9f, 0x01, 0x9a, 0xfe,
+	0xfc, 0xfe, 0x4b, 0x39, 0xfe, 0x0e, 0xbc, 0x22, 0x35, 0xef, 0xa1, 0x76, 0xfc, 0xdd, 0x81, 0xa5,
+	0xfd, 0x90, 0x32, 0xc5, 0xa3, 0xda, 0xf6, 0x15, 0xa8, 0x1f, 0xa5, 0xe4, 0x2c, 0xbc, 0x52, 0xea,
+	0x2b, 0x8a, 0x6b, 0x7e, 0x14, 0x30, 0x46, 0x52, 0xa3, 0xb9, 0x22, 0x67, 0x14, 0x62, 0x0f, 0x9a,
+	0x47, 0xc1, 0x39, 0xe9, 0x85, 0xbf, 0x27, 0x2a, 0x48, 0x0d, 0x8d, 0x9e, 0x42, 0x8b, 0x8f, 0x8f,
+	0x93, 0x0b, 0x12, 0xab, 0x84, 0xcf, 0x18, 0xf8, 0x3f, 0x0e, 0x3c, 0x50, 0x5a, 0xe9, 0x08, 0xb8,
+	0xdd, 0xb1, 0x1e, 0x34, 0x95, 0x27, 0xa9, 0xd0, 0xaf, 0xe6, 0x1b, 0x1a, 0xbd, 0x86, 0xba, 0xd0,
+	0x89, 0x0a, 0xdf, 0x2e, 0xbc, 0xea, 0xe6, 0x3c, 0xa4, 0xf6, 0x90, 0xf5, 0x81, 0xca, 0x30, 0x51,
+	0xeb, 0xbd, 0xaf, 0x60, 0xc1, 0x62, 0xdf, 0xe9, 0xc0, 0x23, 0x46, 0x65, 0xee, 0x65, 0xf4, 0x05,
+	0x34, 0x15, 0xa9, 0x83, 0x73, 0xb9, 0x48, 0x0b, 0xdf, 0xac, 0x42, 0x9f, 0x41, 0xe7, 0x1d, 0xb9,
+	0x62, 0x99, 0xa3, 0xe4, 0x16, 0x79, 0x26, 0x0e, 0xa0, 0xb3, 0x49, 0x86, 0x84, 0x91, 0x7b, 0x6a,
+	0x27, 0x0a, 0xea, 0xd7, 0x1a, 0xac, 0xf8, 0x84, 0x91, 0x58, 0x86, 0xde, 0x28, 0x49, 0xd9, 0x5d,
+	0xf2, 0xe4, 0xc1, 0xd6, 0xd5, 0x28, 0x4c, 0x89, 0x39, 0x0b, 0x3e, 0x45, 0x3f, 0x17, 0x1a, 0x1b,
+	0x29, 0x09, 0xf8, 0x29, 0x2e, 0x4b, 0xa1, 0x26, 0xf1, 0x06, 0x3c, 0x9c, 0xd0, 0x91, 0x7b, 0xdc,
+	0x44, 0x44, 0xce, 0xe3, 0x79, 0x85, 0xb2, 0x38, 0xc1, 0x7f, 0x80, 0xf6, 0x26, 0x19, 0xb2, 0x60,
+	0x7e, 0x57, 0x7e, 0x0e, 0x8b, 0x7b, 0x31, 0x65, 0xc1, 0x70, 0x48, 0xfa, 0x79, 0x9d, 0xa7, 0xf8,
+	0x13, 0xfd, 0x4c, 0x65, 0xb2, 0x9f, 0xc1, 0x7f, 0x71, 0xa0, 0x25, 0xb6, 0xdf, 0x0e, 0x87, 0xe2,
+	0x10, 0xb0, 0x36, 0xad, 0xea, 0x43, 0xe7, 0xf0, 0x63, 0x4c, 0x74, 0x33, 0x24, 0x09, 0xbe, 0xf2,
+	0x20, 0xe9, 0x4b, 0xcc, 0x6a, 0xbe, 0x18, 0x73, 0x9e, 0x49, 0xbc, 0x8a, 0x2f, 0xc6, 0x9c, 0xb7,
+	0x1b, 0xd0, 0x81, 0xca, 0x37, 0x31, 0xe6, 0x89, 0x68, 0xf4, 0x54, 0x55, 0x3a, 0x63, 0xe0, 0x7f,
+	0x39, 0x5c, 0xe5, 0x21, 0x0b, 0x36, 0x06, 0xe3, 0xf8, 0x62, 0xba, 0xda, 0x38, 0x73, 0x57, 0x1b,
+	0xf4, 0x43, 0xa8, 0x71, 0xb3, 0xf4, 0x91, 0xfc, 0x50, 0x7e, 0x65, 0xcc, 0xf5, 0xe5, 0x2c, 0x07,
+	0xd8, 0x27, 0x51, 0x72, 0x49, 0xfa, 0xaa, 0x0c, 0x6a, 0xd2, 0x68, 0x5f, 0xb5, 0xb4, 0x47, 0x50,
+	0x15, 0x9a, 0x70, 0x8b, 0xda, 0xbe, 0x18, 0xe3, 0x63, 0x58, 0xe4, 0xf9, 0x26, 0xc4, 0xcd, 0x8f,
+	0xe3, 0x2d, 0xbd, 0x26, 0xfe, 0x87, 0x03, 0x2d, 0x2e, 0xd2, 0x9c, 0xab, 0x53, 0xd8, 0xf0, 0xb3,
+	0xf6, 0x7a, 0xa4, 0xeb, 0x80, 0x18, 0x1b, 0x14, 0x2a, 0x79, 0x14, 0x04, 0x5a, 0x55, 0x0b, 0x2d,
+	0x83, 0x6b, 0xcd, 0xc6, 0x75, 0x19, 0x6a, 0x3b, 0x69, 0x32, 0x1e, 0x29, 0x5c, 0x24, 0x61, 0xfc,
+	0xd0, 0xc8, 0xfc, 0x80, 0x23, 0x68, 0x72, 0xe5, 0x44, 0x9d, 0xb9, 0x7f, 0x90, 0x8c, 0xdd, 0x0a,
+	0x24, 0x9e, 0xd3, 0x3b, 0x44, 0x78, 0xf8, 0xde, 0x1c, 0x2c, 0x5a, 0x8c, 0x80, 0x0d, 0x54, 0x5a,
+	0x88, 0x31, 0xfe, 0x93, 0x72, 0xfa, 0xa7, 0x47, 0xdf, 0x73, 0xa8, 0x72, 0x41, 0x62, 0xdf, 0x02,
+	0xbb, 0xaa, 0x3a, 0xe3, 0x84, 0xe4, 0x8a, 0x15, 0x4d, 0x97, 0x50, 0xbd, 0x63, 0x36, 0x1a, 0xd4,
+	0x2a, 0x13, 0xa8, 0x4d, 0xa1, 0xee, 0x41, 0x73, 0x23, 0x89, 0x99, 0x68, 0x54, 0x65, 0x04, 0x1b,
+	0x1a, 0x1f, 0x89, 0x86, 0x59, 0x99, 0x30, 0x87, 0x7b, 0xbb, 0x79, 0xe4, 0x20, 0xb3, 0x50, 0x83,
+	0xb6, 0x0d, 0xed, 0xf7, 0xa3, 0x61, 0x12, 0xf4, 0x77, 0x49, 0xd0, 0x27, 0xe9, 0x1c, 0x32, 0xad,
+	0x16, 0xd7, 0x6a, 0x48, 0xfe, 0x08, 0x1d, 0x29, 0x47, 0x63, 0xff, 0x13, 0xa8, 0x4b, 0x91, 0x0a,
+	0x12, 0x24, 0xf7, 0xb6, 0x37, 0xdb, 0x2d, 0xf9, 0x75, 0xb3, 0xad, 0x8d, 0x84, 0xa5, 0xe7, 0x6e,
+	0x49, 0xc1, 0xb0, 0x6c, 0xc3, 0xc0, 0xb9, 0x9c, 0x7a, 0x5b, 0xe7, 0xf1, 0x91, 0x32, 0xfc, 0x25,
+	0x2c, 0x4b, 0xc9, 0x3d, 0x42, 0xa9, 0xd5, 0xb1, 0x3d, 0x85, 0x96, 0xe2, 0xec, 0xf5, 0x95, 0x31,
+	0x19, 0x03, 0xbf, 0x06, 0xe0, 0xb2, 0x0f, 0xcf, 0xce, 0x28, 0x61, 0x85, 0x60, 0xae, 0x40, 0x5d,
+	0xce, 0xaa, 0xae, 0x5b, 0x51, 0x3c, 0xf1, 0x97, 0x72, 0x1b, 0xf6, 0x58, 0xc0, 0xc6, 0xf4, 0xe6,
+	0xfd, 0x26, 0x9d, 0x5b, 0x9e, 0x76, 0xee, 0x8f, 0x34, 0x60, 0x15, 0xbb, 0x51, 0xcd, 0x94, 0xd4,
+	0x05, 0xf1, 0x29, 0xb4, 0xe4, 0x71, 0x45, 0xd7, 0x99, 0xaa, 0xe6, 0x19, 0x03, 0xff, 0xcd, 0x81,
+	0x65, 0xb5, 0x6b, 0x1e, 0x94, 0x9b, 0xd5, 0x7b, 0x36, 0x0b, 0x04, 0x3f, 0x1f, 0xed, 0x95, 0x42,
+	0x07, 0x55, 0x6d, 0x07, 0x15, 0xd6, 0xe0, 0xf7, 0xf0, 0x68, 0x87, 0xe8, 0xc6, 0xf2, 0xfe, 0x8a,
+	0x70, 0x1f, 0x90, 0x2d, 0x76, 0x56, 0x0f, 0x7c, 0x87, 0xba, 0xa0, 0x95, 0x2f, 0x5b, 0xca, 0xff,
+	0xd9, 0x81, 0xb6, 0x5a, 0xf3, 0xe9, 0x85, 0x47, 0x9f, 0x00, 0x65, 0xeb, 0x04, 0xe0, 0x79, 0x3f,
+	0x20, 0xa7, 0x17, 0x74, 0x1c, 0xe9, 0x9b, 0x96, 0xa6, 0x8d, 0x42, 0x55, 0x4b, 0xa1, 0xef, 0x4c,
+	0x37, 0x7c, 0x38, 0x62, 0xa2, 0x9f, 0xbd, 0xdd, 0x95, 0x9f, 0x41, 0x67, 0x33, 0xa4, 0xc1, 0xc9,
+	0x90, 0xec, 0x07, 0x8c, 0x50, 0x19, 0xd5, 0x4d, 0x3f, 0xcf, 0x7c, 0xf5, 0xcf, 0x36, 0x54, 0x0f,
+	0xe8, 0x28, 0x42, 0x6f, 0xa0, 0xa5, 0x1f, 0x81, 0x28, 0x7a, 0x5c, 0xf8, 0x2a, 0xe4, 0xcd, 0xb4,
+	0x18, 0x97, 0xd0, 0x2e, 0xb4, 0xe5, 0x53, 0x87, 0x12, 0xb1, 0x62, 0xdd, 0xb8, 0xad, 0x37, 0x0f,
+	0xef, 0x59, 0x31, 0x5f, 0x63, 0x89, 0x4b, 0xe8, 0x2d, 0x3c, 0xdc, 0x21, 0x2c, 0x77, 0xe1, 0x7f,
+	0x62, 0x7d, 0x94, 0x7f, 0xe8, 0xf0, 0xd0, 0xf4, 0x14, 0x2e, 0xa1, 0x1d, 0xe8, 0xe4, 0x6e, 0xf5,
+	0xc8, 0x93, 0xcb, 0x8a, 0xae, 0xfa, 0x37, 0x9a, 0xf5, 0x5b, 0x78, 0x9c, 0x05, 0x9c, 0x35, 0x85,
+	0xbe, 0x3f, 0x13, 0x7d, 0x25, 0xb5, 0x3b, 0x7b, 0x81, 0x31, 0xf5, 0x6b, 0x5d, 0x49, 0xd5, 0x2a,
+	0xa4, 0x8a, 0x40, 0x56, 0xf8, 0x6f, 0xf1, 0xf9, 0x52, 0xee, 0xf3, 0x1e, 0x4b, 0x49, 0x10, 0xa1,
+	0x25, 0xbb, 0xfc, 0xce, 0x61, 0xe4, 0xaa, 0x83, 0xde, 0xc0, 0x42, 0x8f, 0x05, 0x29, 0x93, 0xdf,
+	0xa0, 0x82, 0x02, 0xee, 0x3d, 0xb1, 0x79, 0xb9, 0x4a, 0x88, 0x4b, 0xe8, 0x57, 0x02, 0x33, 0x35,
+	0x27, 0x98, 0xda, 0xe3, 0x45, 0xa5, 0xfa, 0x66, 0x59, 0x7b, 0xd0, 0xe6, 0x97, 0xfb, 0x88, 0x28,
+	0x65, 0x3c, 0x1d, 0x8c, 0xd3, 0x45, 0xee, 0x46, 0x41, 0xab, 0x0e, 0xda, 0x86, 0xf6, 0x76, 0x18,
+	0x87, 0x74, 0x90, 0x17, 0x55, 0xa8, 0xd3, 0x4d, 0x8e, 0xde, 0x86, 0x85, 0xf5, 0x93, 0x24, 0x65,
+	0x73, 0x88, 0xb9, 0xd1, 0xb4, 0x75, 0x80, 0x2c, 0x9a, 0xd0, 0xf7, 0xe4, 0xd2, 0xa9, 0x3a, 0xe9,
+	0xb9, 0xd3, 0x13, 0x76, 0x76, 0x6c, 0x26, 0x1f, 0x63, 0x3b, 0x68, 0x66, 0xca, 0x41, 0x39, 0x93,
+	0x44, 0x29, 0xc3, 0xa5, 0x2f, 0x1c, 0xb4, 0x0e, 0x8f, 0x7a, 0x84, 0x4d, 0x54, 0x94, 0xfc, 0x5d,
+	0x54, 0x71, 0xbd, 0x42, 0x2e, 0x2e, 0xa1, 0x9f, 0x43, 0x47, 0xbc, 0x65, 0x89, 0x77, 0x95, 0xfd,
+	0xe4, 0x1c, 0xd9, 0xef, 0x2c, 0x62, 0xc6, 0x43, 0x16, 0x47, 0x3f, 0xb9, 0x94, 0xd0, 0x1b, 0x68,
+	0xdb, 0x4f, 0x0e, 0x26, 0xb5, 0xa7, 0x9f, 0x21, 0xbc, 0x47, 0xb9, 0xbd, 0xf9, 0x0a, 0x5c, 0x42,
+	0xbf, 0xd4, 0x97, 0x5d, 0x7d, 0xa7, 0x5a, 0x32, 0xf7, 0x08, 0xc2, 0x26, 0x3d, 0x38, 0x23, 0x6b,
+	0x94, 0x04, 0xed, 0xbf, 0x42, 0x09, 0xf3, 0xa4, 0xef, 0xaf, 0xc5, 0x69, 0x34, 0x79, 0xe9, 0x7c,
+	0x2a, 0xbf, 0x2c, 0xbe, 0x2f, 0x7b, 0x8f, 0x0b, 0x67, 0x71, 0x09, 0x7d, 0x05, 0x1d, 0x0d, 0xac,
+	0xb8, 0x13, 0xe9, 0x24, 0xb4, 0xaf, 0xa3, 0xde, 0xa2, 0xc5, 0xcb, 0xf0, 0xfc, 0x19, 0xb4, 0xcc,
+	0x85, 0xc7, 0x14, 0xde, 0x89, 0x1b, 0x90, 0xf7, 0x20, 0x3b, 0xe3, 0x95, 0x2b, 0xbf, 0x84, 0x86,
+	0x6a, 0xe2, 0x35, 0xf8, 0xf9, 0x9e, 0xde, 0xb3, 0xba, 0x64, 0xb3, 0xd9, 0xdb, 0xe7, 0x1f, 0x7e,
+	0x70, 0x1e, 0xb2, 0xc1, 0xf8, 0xe4, 0xc5, 0x69, 0x12, 0xbd, 0xbc, 0x0c, 0x58, 0x18, 0x93, 0x97,
+	0x7c, 0xdd, 0xcb, 0xd1, 0xc5, 0xf9, 0x4b, 0xf1, 0x4f, 0x07, 0x3d, 0xa9, 0x8b, 0xdf, 0x9f, 0xfe,
+	0x7f, 0x00, 0x98, 0xb4, 0xbd, 0xef, 0xff, 0x18, 0x00, 0x00,
 }
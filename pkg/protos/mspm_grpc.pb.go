@@ -18,9 +18,28 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MspmClient interface {
 	SetLabels(ctx context.Context, in *SetLabelRequest, opts ...grpc.CallOption) (*PackageInformation, error)
+	ChangeLabels(ctx context.Context, in *LabelTransaction, opts ...grpc.CallOption) (*LabelTransactionResponse, error)
+	GetLabelHistory(ctx context.Context, in *LabelHistoryRequest, opts ...grpc.CallOption) (*LabelHistory, error)
+	RollbackLabel(ctx context.Context, in *RollbackLabelRequest, opts ...grpc.CallOption) (*PackageInformation, error)
 	GetPackageInformation(ctx context.Context, in *PackageInformationRequest, opts ...grpc.CallOption) (*PackageInformationResponse, error)
 	UploadPackage(ctx context.Context, in *NewPackage, opts ...grpc.CallOption) (*PackageInformation, error)
+	UploadPackageStream(ctx context.Context, opts ...grpc.CallOption) (Mspm_UploadPackageStreamClient, error)
+	StartUpload(ctx context.Context, in *UploadHeader, opts ...grpc.CallOption) (*UploadSessionStatus, error)
+	GetUploadStatus(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionStatus, error)
+	ResumeUpload(ctx context.Context, opts ...grpc.CallOption) (Mspm_ResumeUploadClient, error)
+	FinishUpload(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*PackageInformation, error)
+	AbortUpload(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionStatus, error)
 	GetPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (*GetPackageResponse, error)
+	DownloadPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (Mspm_DownloadPackageClient, error)
+	SetPackageOptions(ctx context.Context, in *PackageOptions, opts ...grpc.CallOption) (*PackageOptions, error)
+	QueryAuditLog(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditEntries, error)
+	ListPackages(ctx context.Context, in *ListPackagesRequest, opts ...grpc.CallOption) (*PackageList, error)
+	DeleteVersion(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PackageInformation, error)
+	DeletePackage(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PackageInformationResponse, error)
+	GetRetentionReport(ctx context.Context, in *RetentionReportRequest, opts ...grpc.CallOption) (*RetentionReport, error)
+	DownloadDelta(ctx context.Context, in *DeltaRequest, opts ...grpc.CallOption) (Mspm_DownloadDeltaClient, error)
+	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*FileList, error)
+	GetFile(ctx context.Context, in *GetFileRequest, opts ...grpc.CallOption) (Mspm_GetFileClient, error)
 }
 
 type mspmClient struct {
@@ -40,6 +59,33 @@ func (c *mspmClient) SetLabels(ctx context.Context, in *SetLabelRequest, opts ..
 	return out, nil
 }
 
+func (c *mspmClient) ChangeLabels(ctx context.Context, in *LabelTransaction, opts ...grpc.CallOption) (*LabelTransactionResponse, error) {
+	out := new(LabelTransactionResponse)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/ChangeLabels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) GetLabelHistory(ctx context.Context, in *LabelHistoryRequest, opts ...grpc.CallOption) (*LabelHistory, error) {
+	out := new(LabelHistory)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/GetLabelHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) RollbackLabel(ctx context.Context, in *RollbackLabelRequest, opts ...grpc.CallOption) (*PackageInformation, error) {
+	out := new(PackageInformation)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/RollbackLabel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mspmClient) GetPackageInformation(ctx context.Context, in *PackageInformationRequest, opts ...grpc.CallOption) (*PackageInformationResponse, error) {
 	out := new(PackageInformationResponse)
 	err := c.cc.Invoke(ctx, "/mspm.Mspm/GetPackageInformation", in, out, opts...)
@@ -58,6 +104,110 @@ func (c *mspmClient) UploadPackage(ctx context.Context, in *NewPackage, opts ...
 	return out, nil
 }
 
+func (c *mspmClient) UploadPackageStream(ctx context.Context, opts ...grpc.CallOption) (Mspm_UploadPackageStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mspm_serviceDesc.Streams[0], "/mspm.Mspm/UploadPackageStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mspmUploadPackageStreamClient{stream}
+	return x, nil
+}
+
+type Mspm_UploadPackageStreamClient interface {
+	Send(*UploadRequest) error
+	CloseAndRecv() (*PackageInformation, error)
+	grpc.ClientStream
+}
+
+type mspmUploadPackageStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *mspmUploadPackageStreamClient) Send(m *UploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *mspmUploadPackageStreamClient) CloseAndRecv() (*PackageInformation, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PackageInformation)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mspmClient) StartUpload(ctx context.Context, in *UploadHeader, opts ...grpc.CallOption) (*UploadSessionStatus, error) {
+	out := new(UploadSessionStatus)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/StartUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) GetUploadStatus(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionStatus, error) {
+	out := new(UploadSessionStatus)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/GetUploadStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) ResumeUpload(ctx context.Context, opts ...grpc.CallOption) (Mspm_ResumeUploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mspm_serviceDesc.Streams[1], "/mspm.Mspm/ResumeUpload", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mspmResumeUploadClient{stream}
+	return x, nil
+}
+
+type Mspm_ResumeUploadClient interface {
+	Send(*SessionUploadRequest) error
+	CloseAndRecv() (*UploadSessionStatus, error)
+	grpc.ClientStream
+}
+
+type mspmResumeUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *mspmResumeUploadClient) Send(m *SessionUploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *mspmResumeUploadClient) CloseAndRecv() (*UploadSessionStatus, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadSessionStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mspmClient) FinishUpload(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*PackageInformation, error) {
+	out := new(PackageInformation)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/FinishUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) AbortUpload(ctx context.Context, in *UploadSessionRequest, opts ...grpc.CallOption) (*UploadSessionStatus, error) {
+	out := new(UploadSessionStatus)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/AbortUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mspmClient) GetPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (*GetPackageResponse, error) {
 	out := new(GetPackageResponse)
 	err := c.cc.Invoke(ctx, "/mspm.Mspm/GetPackage", in, out, opts...)
@@ -67,14 +217,192 @@ func (c *mspmClient) GetPackage(ctx context.Context, in *GetPackageRequest, opts
 	return out, nil
 }
 
+func (c *mspmClient) DownloadPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (Mspm_DownloadPackageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mspm_serviceDesc.Streams[2], "/mspm.Mspm/DownloadPackage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mspmDownloadPackageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Mspm_DownloadPackageClient interface {
+	Recv() (*PackageChunk, error)
+	grpc.ClientStream
+}
+
+type mspmDownloadPackageClient struct {
+	grpc.ClientStream
+}
+
+func (x *mspmDownloadPackageClient) Recv() (*PackageChunk, error) {
+	m := new(PackageChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mspmClient) SetPackageOptions(ctx context.Context, in *PackageOptions, opts ...grpc.CallOption) (*PackageOptions, error) {
+	out := new(PackageOptions)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/SetPackageOptions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) QueryAuditLog(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditEntries, error) {
+	out := new(AuditEntries)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/QueryAuditLog", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) ListPackages(ctx context.Context, in *ListPackagesRequest, opts ...grpc.CallOption) (*PackageList, error) {
+	out := new(PackageList)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/ListPackages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) DeleteVersion(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PackageInformation, error) {
+	out := new(PackageInformation)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/DeleteVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) DeletePackage(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*PackageInformationResponse, error) {
+	out := new(PackageInformationResponse)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/DeletePackage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) GetRetentionReport(ctx context.Context, in *RetentionReportRequest, opts ...grpc.CallOption) (*RetentionReport, error) {
+	out := new(RetentionReport)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/GetRetentionReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) DownloadDelta(ctx context.Context, in *DeltaRequest, opts ...grpc.CallOption) (Mspm_DownloadDeltaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mspm_serviceDesc.Streams[3], "/mspm.Mspm/DownloadDelta", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mspmDownloadDeltaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Mspm_DownloadDeltaClient interface {
+	Recv() (*DeltaChunk, error)
+	grpc.ClientStream
+}
+
+type mspmDownloadDeltaClient struct {
+	grpc.ClientStream
+}
+
+func (x *mspmDownloadDeltaClient) Recv() (*DeltaChunk, error) {
+	m := new(DeltaChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mspmClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*FileList, error) {
+	out := new(FileList)
+	err := c.cc.Invoke(ctx, "/mspm.Mspm/ListFiles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mspmClient) GetFile(ctx context.Context, in *GetFileRequest, opts ...grpc.CallOption) (Mspm_GetFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mspm_serviceDesc.Streams[4], "/mspm.Mspm/GetFile", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mspmGetFileClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Mspm_GetFileClient interface {
+	Recv() (*FileChunk, error)
+	grpc.ClientStream
+}
+
+type mspmGetFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *mspmGetFileClient) Recv() (*FileChunk, error) {
+	m := new(FileChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MspmServer is the server API for Mspm service.
 // All implementations must embed UnimplementedMspmServer
 // for forward compatibility
 type MspmServer interface {
 	SetLabels(context.Context, *SetLabelRequest) (*PackageInformation, error)
+	ChangeLabels(context.Context, *LabelTransaction) (*LabelTransactionResponse, error)
+	GetLabelHistory(context.Context, *LabelHistoryRequest) (*LabelHistory, error)
+	RollbackLabel(context.Context, *RollbackLabelRequest) (*PackageInformation, error)
 	GetPackageInformation(context.Context, *PackageInformationRequest) (*PackageInformationResponse, error)
 	UploadPackage(context.Context, *NewPackage) (*PackageInformation, error)
+	UploadPackageStream(Mspm_UploadPackageStreamServer) error
+	StartUpload(context.Context, *UploadHeader) (*UploadSessionStatus, error)
+	GetUploadStatus(context.Context, *UploadSessionRequest) (*UploadSessionStatus, error)
+	ResumeUpload(Mspm_ResumeUploadServer) error
+	FinishUpload(context.Context, *UploadSessionRequest) (*PackageInformation, error)
+	AbortUpload(context.Context, *UploadSessionRequest) (*UploadSessionStatus, error)
 	GetPackage(context.Context, *GetPackageRequest) (*GetPackageResponse, error)
+	DownloadPackage(*GetPackageRequest, Mspm_DownloadPackageServer) error
+	SetPackageOptions(context.Context, *PackageOptions) (*PackageOptions, error)
+	QueryAuditLog(context.Context, *AuditQuery) (*AuditEntries, error)
+	ListPackages(context.Context, *ListPackagesRequest) (*PackageList, error)
+	DeleteVersion(context.Context, *DeleteRequest) (*PackageInformation, error)
+	DeletePackage(context.Context, *DeleteRequest) (*PackageInformationResponse, error)
+	GetRetentionReport(context.Context, *RetentionReportRequest) (*RetentionReport, error)
+	DownloadDelta(*DeltaRequest, Mspm_DownloadDeltaServer) error
+	ListFiles(context.Context, *ListFilesRequest) (*FileList, error)
+	GetFile(*GetFileRequest, Mspm_GetFileServer) error
 	mustEmbedUnimplementedMspmServer()
 }
 
@@ -85,15 +413,72 @@ type UnimplementedMspmServer struct {
 func (UnimplementedMspmServer) SetLabels(context.Context, *SetLabelRequest) (*PackageInformation, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetLabels not implemented")
 }
+func (UnimplementedMspmServer) ChangeLabels(context.Context, *LabelTransaction) (*LabelTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeLabels not implemented")
+}
+func (UnimplementedMspmServer) GetLabelHistory(context.Context, *LabelHistoryRequest) (*LabelHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLabelHistory not implemented")
+}
+func (UnimplementedMspmServer) RollbackLabel(context.Context, *RollbackLabelRequest) (*PackageInformation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackLabel not implemented")
+}
 func (UnimplementedMspmServer) GetPackageInformation(context.Context, *PackageInformationRequest) (*PackageInformationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPackageInformation not implemented")
 }
 func (UnimplementedMspmServer) UploadPackage(context.Context, *NewPackage) (*PackageInformation, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UploadPackage not implemented")
 }
+func (UnimplementedMspmServer) UploadPackageStream(Mspm_UploadPackageStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadPackageStream not implemented")
+}
+func (UnimplementedMspmServer) StartUpload(context.Context, *UploadHeader) (*UploadSessionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartUpload not implemented")
+}
+func (UnimplementedMspmServer) GetUploadStatus(context.Context, *UploadSessionRequest) (*UploadSessionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUploadStatus not implemented")
+}
+func (UnimplementedMspmServer) ResumeUpload(Mspm_ResumeUploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method ResumeUpload not implemented")
+}
+func (UnimplementedMspmServer) FinishUpload(context.Context, *UploadSessionRequest) (*PackageInformation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishUpload not implemented")
+}
+func (UnimplementedMspmServer) AbortUpload(context.Context, *UploadSessionRequest) (*UploadSessionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbortUpload not implemented")
+}
 func (UnimplementedMspmServer) GetPackage(context.Context, *GetPackageRequest) (*GetPackageResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetPackage not implemented")
 }
+func (UnimplementedMspmServer) DownloadPackage(*GetPackageRequest, Mspm_DownloadPackageServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadPackage not implemented")
+}
+func (UnimplementedMspmServer) SetPackageOptions(context.Context, *PackageOptions) (*PackageOptions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPackageOptions not implemented")
+}
+func (UnimplementedMspmServer) QueryAuditLog(context.Context, *AuditQuery) (*AuditEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAuditLog not implemented")
+}
+func (UnimplementedMspmServer) ListPackages(context.Context, *ListPackagesRequest) (*PackageList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPackages not implemented")
+}
+func (UnimplementedMspmServer) DeleteVersion(context.Context, *DeleteRequest) (*PackageInformation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVersion not implemented")
+}
+func (UnimplementedMspmServer) DeletePackage(context.Context, *DeleteRequest) (*PackageInformationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePackage not implemented")
+}
+func (UnimplementedMspmServer) GetRetentionReport(context.Context, *RetentionReportRequest) (*RetentionReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRetentionReport not implemented")
+}
+func (UnimplementedMspmServer) DownloadDelta(*DeltaRequest, Mspm_DownloadDeltaServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadDelta not implemented")
+}
+func (UnimplementedMspmServer) ListFiles(context.Context, *ListFilesRequest) (*FileList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedMspmServer) GetFile(*GetFileRequest, Mspm_GetFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetFile not implemented")
+}
 func (UnimplementedMspmServer) mustEmbedUnimplementedMspmServer() {}
 
 // UnsafeMspmServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +510,60 @@ func _Mspm_SetLabels_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mspm_ChangeLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelTransaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).ChangeLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/ChangeLabels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).ChangeLabels(ctx, req.(*LabelTransaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_GetLabelHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).GetLabelHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/GetLabelHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).GetLabelHistory(ctx, req.(*LabelHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_RollbackLabel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackLabelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).RollbackLabel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/RollbackLabel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).RollbackLabel(ctx, req.(*RollbackLabelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Mspm_GetPackageInformation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PackageInformationRequest)
 	if err := dec(in); err != nil {
@@ -161,6 +600,130 @@ func _Mspm_UploadPackage_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mspm_UploadPackageStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MspmServer).UploadPackageStream(&mspmUploadPackageStreamServer{stream})
+}
+
+type Mspm_UploadPackageStreamServer interface {
+	SendAndClose(*PackageInformation) error
+	Recv() (*UploadRequest, error)
+	grpc.ServerStream
+}
+
+type mspmUploadPackageStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *mspmUploadPackageStreamServer) SendAndClose(m *PackageInformation) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *mspmUploadPackageStreamServer) Recv() (*UploadRequest, error) {
+	m := new(UploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Mspm_StartUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadHeader)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).StartUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/StartUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).StartUpload(ctx, req.(*UploadHeader))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_GetUploadStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).GetUploadStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/GetUploadStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).GetUploadStatus(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_ResumeUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MspmServer).ResumeUpload(&mspmResumeUploadServer{stream})
+}
+
+type Mspm_ResumeUploadServer interface {
+	SendAndClose(*UploadSessionStatus) error
+	Recv() (*SessionUploadRequest, error)
+	grpc.ServerStream
+}
+
+type mspmResumeUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *mspmResumeUploadServer) SendAndClose(m *UploadSessionStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *mspmResumeUploadServer) Recv() (*SessionUploadRequest, error) {
+	m := new(SessionUploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Mspm_FinishUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).FinishUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/FinishUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).FinishUpload(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_AbortUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).AbortUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/AbortUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).AbortUpload(ctx, req.(*UploadSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Mspm_GetPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetPackageRequest)
 	if err := dec(in); err != nil {
@@ -179,6 +742,195 @@ func _Mspm_GetPackage_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mspm_DownloadPackage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetPackageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MspmServer).DownloadPackage(m, &mspmDownloadPackageServer{stream})
+}
+
+type Mspm_DownloadPackageServer interface {
+	Send(*PackageChunk) error
+	grpc.ServerStream
+}
+
+type mspmDownloadPackageServer struct {
+	grpc.ServerStream
+}
+
+func (x *mspmDownloadPackageServer) Send(m *PackageChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Mspm_SetPackageOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PackageOptions)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).SetPackageOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/SetPackageOptions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).SetPackageOptions(ctx, req.(*PackageOptions))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_QueryAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).QueryAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/QueryAuditLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).QueryAuditLog(ctx, req.(*AuditQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_ListPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).ListPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/ListPackages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).ListPackages(ctx, req.(*ListPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_DeleteVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).DeleteVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/DeleteVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).DeleteVersion(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_DeletePackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).DeletePackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/DeletePackage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).DeletePackage(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_GetRetentionReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetentionReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).GetRetentionReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/GetRetentionReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).GetRetentionReport(ctx, req.(*RetentionReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_DownloadDelta_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeltaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MspmServer).DownloadDelta(m, &mspmDownloadDeltaServer{stream})
+}
+
+type Mspm_DownloadDeltaServer interface {
+	Send(*DeltaChunk) error
+	grpc.ServerStream
+}
+
+type mspmDownloadDeltaServer struct {
+	grpc.ServerStream
+}
+
+func (x *mspmDownloadDeltaServer) Send(m *DeltaChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Mspm_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MspmServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mspm.Mspm/ListFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MspmServer).ListFiles(ctx, req.(*ListFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mspm_GetFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MspmServer).GetFile(m, &mspmGetFileServer{stream})
+}
+
+type Mspm_GetFileServer interface {
+	Send(*FileChunk) error
+	grpc.ServerStream
+}
+
+type mspmGetFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *mspmGetFileServer) Send(m *FileChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Mspm_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "mspm.Mspm",
 	HandlerType: (*MspmServer)(nil),
@@ -187,6 +939,18 @@ var _Mspm_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetLabels",
 			Handler:    _Mspm_SetLabels_Handler,
 		},
+		{
+			MethodName: "ChangeLabels",
+			Handler:    _Mspm_ChangeLabels_Handler,
+		},
+		{
+			MethodName: "GetLabelHistory",
+			Handler:    _Mspm_GetLabelHistory_Handler,
+		},
+		{
+			MethodName: "RollbackLabel",
+			Handler:    _Mspm_RollbackLabel_Handler,
+		},
 		{
 			MethodName: "GetPackageInformation",
 			Handler:    _Mspm_GetPackageInformation_Handler,
@@ -195,11 +959,81 @@ var _Mspm_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UploadPackage",
 			Handler:    _Mspm_UploadPackage_Handler,
 		},
+		{
+			MethodName: "StartUpload",
+			Handler:    _Mspm_StartUpload_Handler,
+		},
+		{
+			MethodName: "GetUploadStatus",
+			Handler:    _Mspm_GetUploadStatus_Handler,
+		},
+		{
+			MethodName: "FinishUpload",
+			Handler:    _Mspm_FinishUpload_Handler,
+		},
+		{
+			MethodName: "AbortUpload",
+			Handler:    _Mspm_AbortUpload_Handler,
+		},
 		{
 			MethodName: "GetPackage",
 			Handler:    _Mspm_GetPackage_Handler,
 		},
+		{
+			MethodName: "SetPackageOptions",
+			Handler:    _Mspm_SetPackageOptions_Handler,
+		},
+		{
+			MethodName: "QueryAuditLog",
+			Handler:    _Mspm_QueryAuditLog_Handler,
+		},
+		{
+			MethodName: "ListPackages",
+			Handler:    _Mspm_ListPackages_Handler,
+		},
+		{
+			MethodName: "DeleteVersion",
+			Handler:    _Mspm_DeleteVersion_Handler,
+		},
+		{
+			MethodName: "DeletePackage",
+			Handler:    _Mspm_DeletePackage_Handler,
+		},
+		{
+			MethodName: "GetRetentionReport",
+			Handler:    _Mspm_GetRetentionReport_Handler,
+		},
+		{
+			MethodName: "ListFiles",
+			Handler:    _Mspm_ListFiles_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadPackageStream",
+			Handler:       _Mspm_UploadPackageStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ResumeUpload",
+			Handler:       _Mspm_ResumeUpload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadPackage",
+			Handler:       _Mspm_DownloadPackage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadDelta",
+			Handler:       _Mspm_DownloadDelta_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetFile",
+			Handler:       _Mspm_GetFile_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "mspm.proto",
 }
@@ -0,0 +1,414 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Calls that do not change anything, and are not audited. Everything
+// else is.
+var unauditedMethods = map[string]bool{
+	"GetPackageInformation": true,
+	"GetPackage":            true,
+	"DownloadPackage":       true,
+	"GetUploadStatus":       true,
+	"GetLabelHistory":       true,
+	"QueryAuditLog":         true,
+	"ListPackages":          true,
+	"GetRetentionReport":    true,
+	"DownloadDelta":         true,
+	"ListFiles":             true,
+	"GetFile":               true,
+}
+
+// A record in the audit log.
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	Method   string    `json:"method"`
+	Identity string    `json:"identity"`
+	Peer     string    `json:"peer,omitempty"`
+	Packages []string  `json:"packages,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Labels   []string  `json:"labels,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Result   string    `json:"result"`
+}
+
+// An AuditLog is an append-only JSON-lines file. When it grows beyond
+// maxSize bytes, it is rotated: the file is renamed to <path>.1 (and
+// <path>.1 to <path>.2, and so on), keeping at most maxFiles old
+// files.
+type AuditLog struct {
+	lock     sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+}
+
+// Open (or create) an audit log. A log that is rotated has to keep at
+// least one rotated file, or records would be thrown away.
+func NewAuditLog(path string, maxSize int64, maxFiles int) (*AuditLog, error) {
+	if maxSize > 0 && maxFiles < 1 {
+		return nil, fmt.Errorf("audit log rotation needs to keep at least one file, not %d", maxFiles)
+	}
+	a := &AuditLog{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	err := a.open()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AuditLog) open() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	a.f = f
+	a.size = fi.Size()
+	return nil
+}
+
+// The name of the n:th rotated file, 0 being the current one.
+func (a *AuditLog) rotatedName(n int) string {
+	if n == 0 {
+		return a.path
+	}
+	return fmt.Sprintf("%s.%d", a.path, n)
+}
+
+// Move the current file out of the way and start a new one. Expects
+// to be called with the lock held.
+func (a *AuditLog) rotate() error {
+	err := a.f.Close()
+	if err != nil {
+		return err
+	}
+
+	os.Remove(a.rotatedName(a.maxFiles))
+	for n := a.maxFiles - 1; n >= 0; n-- {
+		err = os.Rename(a.rotatedName(n), a.rotatedName(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return a.open()
+}
+
+// Append a record to the log, rotating it first if needed.
+func (a *AuditLog) Record(r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		err = a.rotate()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  a.path,
+			}).Error("rotating audit log")
+			return err
+		}
+	}
+
+	n, err := a.f.Write(line)
+	a.size += int64(n)
+	if err == nil {
+		err = a.f.Sync()
+	}
+	return err
+}
+
+// Close the log.
+func (a *AuditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.f.Close()
+}
+
+// Return the records about a package (or any package, if pkg is
+// empty), made from (inclusive) to (exclusive). A zero time leaves
+// that end of the range open. Records are returned oldest first.
+func (a *AuditLog) Query(pkg string, from, to time.Time) ([]AuditRecord, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	var rv []AuditRecord
+	for n := a.maxFiles; n >= 0; n-- {
+		records, err := readAuditFile(a.rotatedName(n), pkg, from, to)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, records...)
+	}
+
+	return rv, nil
+}
+
+func readAuditFile(path, pkg string, from, to time.Time) ([]AuditRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rv []AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"path":  path,
+			}).Warning("skipping bad audit record")
+			continue
+		}
+		if !from.IsZero() && r.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !r.Time.Before(to) {
+			continue
+		}
+		if pkg != "" && !containsString(r.Packages, pkg) {
+			continue
+		}
+		rv = append(rv, r)
+	}
+
+	return rv, scanner.Err()
+}
+
+func containsString(ss []string, s string) bool {
+	for _, candidate := range ss {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Fill in what a record says about a call, from a request or a
+// response message.
+func (r *AuditRecord) describe(msg interface{}) {
+	switch m := msg.(type) {
+	case *pb.LabelTransaction:
+		var changes []string
+		for _, c := range m.GetChanges() {
+			r.addPackage(c.GetPackageName())
+			changes = append(changes, fmt.Sprintf("%s:%s=%s", c.GetPackageName(), c.GetLabel(), c.GetDesignator()))
+		}
+		r.Summary = strings.Join(changes, ", ")
+		return
+	case *pb.UploadRequest:
+		if h := m.GetHeader(); h != nil {
+			r.addPackage(h.GetPackageName())
+			r.Labels = h.GetLabel()
+		}
+		return
+	case *pb.NewPackage:
+		r.Summary = fmt.Sprintf("%d files", len(m.GetFiles()))
+	case *pb.SetLabelRequest:
+		r.Summary = fmt.Sprintf("designator=%s force=%v", m.GetVersion(), m.GetForce())
+	case *pb.RollbackLabelRequest:
+		r.Labels = []string{m.GetLabel()}
+		r.Summary = fmt.Sprintf("force=%v", m.GetForce())
+	case *pb.DeleteRequest:
+		r.Version = m.GetVersion()
+		r.Summary = fmt.Sprintf("force=%v", m.GetForce())
+	case *pb.PackageOptions:
+		r.Summary = fmt.Sprintf("disableLatest=%v", m.GetDisableLatest())
+	case *pb.UploadSessionStatus:
+		r.Summary = "session " + m.GetSessionId()
+	case *pb.UploadSessionRequest:
+		r.Summary = "session " + m.GetSessionId()
+	}
+
+	if named, ok := msg.(interface{ GetPackageName() string }); ok {
+		r.addPackage(named.GetPackageName())
+	}
+	if labelled, ok := msg.(interface{ GetLabel() []string }); ok && len(r.Labels) == 0 {
+		r.Labels = labelled.GetLabel()
+	}
+	if info, ok := msg.(*pb.PackageInformation); ok {
+		r.Version = info.GetVersion()
+	}
+}
+
+func (r *AuditRecord) addPackage(name string) {
+	if name != "" && !containsString(r.Packages, name) {
+		r.Packages = append(r.Packages, name)
+	}
+}
+
+// Start a record for a call.
+func newAuditRecord(ctx context.Context, fullMethod string) AuditRecord {
+	r := AuditRecord{
+		Time:     time.Now().UTC(),
+		Method:   strings.TrimPrefix(fullMethod, mspmService),
+		Identity: peerIdentity(ctx),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.Peer = p.Addr.String()
+	}
+	return r
+}
+
+// Finish a record with the result of the call, and write it.
+func (a *AuditLog) finish(r AuditRecord, err error) {
+	r.Result = "OK"
+	if err != nil {
+		st, _ := status.FromError(err)
+		r.Result = fmt.Sprintf("%s: %s", st.Code(), st.Message())
+	}
+
+	if err := a.Record(r); err != nil {
+		log.WithFields(log.Fields{
+			"error":  err,
+			"method": r.Method,
+		}).Error("writing audit record")
+	}
+}
+
+func audited(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, mspmService) && !unauditedMethods[strings.TrimPrefix(fullMethod, mspmService)]
+}
+
+// Return a unary server interceptor recording calls in the audit log.
+// It needs to run after any authenticating interceptor, to see the
+// caller identity.
+func (a *AuditLog) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		r := newAuditRecord(ctx, info.FullMethod)
+		r.describe(req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			r.describe(resp)
+		}
+		a.finish(r, err)
+		return resp, err
+	}
+}
+
+// A server stream that notes what passes through it in an audit
+// record.
+type auditedStream struct {
+	grpc.ServerStream
+	record *AuditRecord
+}
+
+func (as *auditedStream) RecvMsg(m interface{}) error {
+	err := as.ServerStream.RecvMsg(m)
+	if err == nil {
+		as.record.describe(m)
+	}
+	return err
+}
+
+func (as *auditedStream) SendMsg(m interface{}) error {
+	as.record.describe(m)
+	return as.ServerStream.SendMsg(m)
+}
+
+// Return a stream server interceptor recording calls in the audit
+// log. It needs to run after any authenticating interceptor, to see
+// the caller identity.
+func (a *AuditLog) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !audited(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		r := newAuditRecord(ss.Context(), info.FullMethod)
+		err := handler(srv, &auditedStream{ServerStream: ss, record: &r})
+		a.finish(r, err)
+		return err
+	}
+}
+
+// Set the audit log the server searches in QueryAuditLog.
+func (s *Server) SetAuditLog(a *AuditLog) {
+	s.auditLog = a
+}
+
+// Search the audit log.
+func (s *Server) QueryAuditLog(ctx context.Context, in *pb.AuditQuery) (*pb.AuditEntries, error) {
+	if err := s.authorize(ctx, "QueryAuditLog", in.GetPackageName()); err != nil {
+		return nil, err
+	}
+	if s.auditLog == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no audit log configured")
+	}
+
+	var from, to time.Time
+	if in.GetFrom() != 0 {
+		from = time.Unix(in.GetFrom(), 0)
+	}
+	if in.GetTo() != 0 {
+		to = time.Unix(in.GetTo(), 0)
+	}
+	records, err := s.auditLog.Query(in.GetPackageName(), from, to)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("QueryAuditLog")
+		return nil, err
+	}
+	if limit := int(in.GetLimit()); limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+
+	rv := new(pb.AuditEntries)
+	for _, r := range records {
+		rv.Entries = append(rv.Entries, &pb.AuditEntry{
+			Time:        r.Time.Unix(),
+			Method:      r.Method,
+			Identity:    r.Identity,
+			Peer:        r.Peer,
+			PackageName: r.Packages,
+			Version:     r.Version,
+			Label:       r.Labels,
+			Summary:     r.Summary,
+			Result:      r.Result,
+		})
+	}
+
+	return rv, nil
+}
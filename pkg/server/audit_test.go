@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-audit")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	if _, err := NewAuditLog(path, 400, 0); err == nil {
+		t.Errorf("Expected an error rotating without keeping any files")
+	}
+	a, err := NewAuditLog(path, 400, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer a.Close()
+
+	start := time.Now().UTC()
+	for ix := 0; ix < 20; ix++ {
+		r := AuditRecord{
+			Time:     start.Add(time.Duration(ix) * time.Second),
+			Method:   "SetLabels",
+			Identity: "alice",
+			Packages: []string{fmt.Sprintf("pkg%d", ix%2)},
+			Summary:  fmt.Sprintf("record %d", ix),
+			Result:   "OK",
+		}
+		if err := a.Record(r); err != nil {
+			t.Fatalf("Unexpected error recording #%d: %v", ix, err)
+		}
+	}
+
+	for _, name := range []string{"audit.log", "audit.log.1", "audit.log.2"} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		} else if fi.Size() > 400 {
+			t.Errorf("Expected %s to be rotated before 400 bytes, is %d", name, fi.Size())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "audit.log.3")); err == nil {
+		t.Errorf("Expected at most 2 rotated files")
+	}
+
+	// What is left should be the newest records, in order.
+	records, err := a.Query("", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) == 0 || records[len(records)-1].Summary != "record 19" {
+		t.Fatalf("Expected the newest record last, saw %v", records)
+	}
+	for ix := 1; ix < len(records); ix++ {
+		if records[ix].Time.Before(records[ix-1].Time) {
+			t.Errorf("Records out of order at %d", ix)
+		}
+	}
+
+	records, _ = a.Query("pkg1", start.Add(17*time.Second), start.Add(19*time.Second))
+	if len(records) != 1 || records[0].Summary != "record 17" {
+		t.Errorf("Expected only record 17, saw %v", records)
+	}
+}
+
+func TestAuditInterceptors(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAuditLog(filepath.Join(dir, "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer a.Close()
+	s.SetAuditLog(a)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	gs := grpc.NewServer(grpc.UnaryInterceptor(a.UnaryInterceptor()), grpc.StreamInterceptor(a.StreamInterceptor()))
+	pb.RegisterMspmServer(gs, s)
+	go gs.Serve(listener)
+	defer gs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewMspmClient(conn)
+
+	info, err := c.UploadPackage(ctx, testPackage("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	stream, err := c.UploadPackageStream(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+	stream.Send(headerPart("foo", "staging"))
+	stream.Send(filePart("hello", 0644))
+	stream.Send(dataPart("streamed"))
+	streamed, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	c.SetLabels(ctx, &pb.SetLabelRequest{PackageName: "foo", Version: "nonesuch", Label: []string{"prod"}})
+	c.GetPackageInformation(ctx, &pb.PackageInformationRequest{PackageName: "foo"})
+
+	resp, err := s.QueryAuditLog(ctx, &pb.AuditQuery{PackageName: "foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error querying: %v", err)
+	}
+	entries := resp.GetEntries()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audited calls, saw %v", entries)
+	}
+
+	expected := []struct {
+		method  string
+		version string
+		ok      bool
+	}{
+		{"UploadPackage", info.GetVersion(), true},
+		{"UploadPackageStream", streamed.GetVersion(), true},
+		{"SetLabels", "", false},
+	}
+	for ix, e := range expected {
+		seen := entries[ix]
+		if seen.GetMethod() != e.method || seen.GetVersion() != e.version {
+			t.Errorf("Entry #%d, expected %s of %s, saw %v", ix, e.method, e.version, seen)
+		}
+		if (seen.GetResult() == "OK") != e.ok {
+			t.Errorf("Entry #%d, unexpected result %s", ix, seen.GetResult())
+		}
+		if seen.GetIdentity() != anonymous || !strings.HasPrefix(seen.GetPeer(), "127.0.0.1:") {
+			t.Errorf("Entry #%d, unexpected caller %s at %s", ix, seen.GetIdentity(), seen.GetPeer())
+		}
+	}
+	if labels := entries[1].GetLabel(); len(labels) != 1 || labels[0] != "staging" {
+		t.Errorf("Expected the streamed upload to record label staging, saw %v", labels)
+	}
+
+	resp, _ = s.QueryAuditLog(ctx, &pb.AuditQuery{PackageName: "foo", Limit: 1})
+	if len(resp.GetEntries()) != 1 || resp.GetEntries()[0].GetMethod() != "SetLabels" {
+		t.Errorf("Expected only the newest entry, saw %v", resp.GetEntries())
+	}
+}
+
+func TestAudited(t *testing.T) {
+	cases := map[string]bool{
+		"StartUpload":        true,
+		"ResumeUpload":       true,
+		"FinishUpload":       true,
+		"GetUploadStatus":    false,
+		"DownloadPackage":    false,
+		"/grpc.health/Check": false,
+	}
+	for method, expected := range cases {
+		fullMethod := method
+		if !strings.HasPrefix(method, "/") {
+			fullMethod = mspmService + method
+		}
+		if seen := audited(fullMethod); seen != expected {
+			t.Errorf("%s, expected audited=%v, saw %v", method, expected, seen)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Check that the caller may delete from a package, and may force
+// protected labels off it if asked to.
+func (s *Server) authorizeDelete(ctx context.Context, method string, in *pb.DeleteRequest) error {
+	if err := s.authorize(ctx, method, in.GetPackageName()); err != nil {
+		return err
+	}
+	if in.GetForce() {
+		return s.authorize(ctx, "ForceLabel", in.GetPackageName())
+	}
+	return nil
+}
+
+// Delete a version of a package, returning what it looked like just
+// before it was deleted.
+func (s *Server) DeleteVersion(ctx context.Context, in *pb.DeleteRequest) (*pb.PackageInformation, error) {
+	name := in.GetPackageName()
+	version := in.GetVersion()
+	if name == "" || version == "" {
+		return nil, fmt.Errorf("Deleting a version needs a package name and a version")
+	}
+	if err := s.authorizeDelete(ctx, "DeleteVersion", in); err != nil {
+		return nil, err
+	}
+
+	pv, err := s.dataStore.DeleteVersion(name, version, peerIdentity(ctx), in.GetForce())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    name,
+			"version": version,
+		}).Error("DeleteVersion")
+		return nil, grpcError(err)
+	}
+
+	return packageInformationFromPackageVersion(pv), nil
+}
+
+// Delete a package and all its versions, returning the versions that
+// were deleted.
+func (s *Server) DeletePackage(ctx context.Context, in *pb.DeleteRequest) (*pb.PackageInformationResponse, error) {
+	name := in.GetPackageName()
+	if name == "" {
+		return nil, fmt.Errorf("No package name specified.")
+	}
+	if in.GetVersion() != "" {
+		return nil, fmt.Errorf("DeletePackage deletes all versions, use DeleteVersion for %s", in.GetVersion())
+	}
+	if err := s.authorizeDelete(ctx, "DeletePackage", in); err != nil {
+		return nil, err
+	}
+
+	pvs, err := s.dataStore.DeletePackage(name, peerIdentity(ctx), in.GetForce())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("DeletePackage")
+		return nil, grpcError(err)
+	}
+
+	rv := new(pb.PackageInformationResponse)
+	for _, pv := range pvs {
+		rv.PackageData = append(rv.PackageData, packageInformationFromPackageVersion(pv))
+	}
+	return rv, nil
+}
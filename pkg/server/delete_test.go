@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestDelete(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	s.SetProtectedLabels([]string{"prod"})
+
+	ctx := context.Background()
+	first, err := s.UploadPackage(ctx, testPackage("first"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	second, err := s.UploadPackage(ctx, testPackage("second"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	s.SetLabels(ctx, &pb.SetLabelRequest{PackageName: "foo", Version: first.GetVersion(), Label: []string{"prod"}})
+
+	req := &pb.DeleteRequest{PackageName: "foo", Version: first.GetVersion()}
+	if _, err := s.DeleteVersion(ctx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition deleting a prod version, saw %v", err)
+	}
+
+	// Forcing needs ForceLabel in the policy.
+	req.Force = true
+	s.SetPolicy(&Policy{Rules: []PolicyRule{
+		{Identities: []string{"*"}, Methods: []string{"DeleteVersion", "DeletePackage"}, Packages: []string{"*"}},
+	}})
+	if _, err := s.DeleteVersion(ctx, req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied forcing a delete, saw %v", err)
+	}
+	s.SetPolicy(nil)
+
+	info, err := s.DeleteVersion(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error deleting: %v", err)
+	}
+	if info.GetVersion() != first.GetVersion() || len(info.GetLabel()) != 1 {
+		t.Errorf("Expected %s with prod deleted, saw %v", first.GetVersion(), info)
+	}
+	if _, err := s.DeleteVersion(ctx, req); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound deleting again, saw %v", err)
+	}
+
+	resp, err := s.DeletePackage(ctx, &pb.DeleteRequest{PackageName: "foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error deleting the package: %v", err)
+	}
+	if len(resp.GetPackageData()) != 1 || resp.GetPackageData()[0].GetVersion() != second.GetVersion() {
+		t.Errorf("Expected only %s deleted, saw %v", second.GetVersion(), resp.GetPackageData())
+	}
+	if files := filesUnder(dir); len(files) != 1 {
+		t.Errorf("Expected only the catalog left, saw %v", files)
+	}
+}
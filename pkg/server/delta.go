@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Send what changed between the version of a package a client has
+// installed and the version a designator refers to. The first message
+// describes every file in the new version, followed by the contents
+// of the files that are not in the installed version.
+func (s *Server) DownloadDelta(in *pb.DeltaRequest, stream pb.Mspm_DownloadDeltaServer) error {
+	name := in.GetPackageName()
+	if name == "" || in.GetInstalledVersion() == "" || in.GetDesignator() == "" {
+		return fmt.Errorf("A delta needs a package name, an installed version and a designator")
+	}
+	if err := s.authorize(stream.Context(), "DownloadDelta", name); err != nil {
+		return err
+	}
+
+	installed, err := s.dataStore.GetPackageVersion(name, in.GetInstalledVersion())
+	if err != nil || installed.Version != in.GetInstalledVersion() {
+		return status.Errorf(codes.NotFound, "package %s has no version %s", name, in.GetInstalledVersion())
+	}
+	target, err := s.dataStore.GetPackageVersion(name, in.GetDesignator())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"name":       name,
+			"designator": in.GetDesignator(),
+		}).Error("DownloadDelta fetching packageversion")
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	delta, err := s.dataStore.PackageDelta(installed, target)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":     err,
+			"name":      name,
+			"installed": installed.Version,
+			"version":   target.Version,
+		}).Error("DownloadDelta computing delta")
+		return grpcError(err)
+	}
+
+	first := &pb.DeltaChunk{
+		PackageData: packageInformationFromPackageVersion(target),
+		Removed:     delta.Removed,
+	}
+	for _, f := range delta.Files {
+		first.Files = append(first.Files, &pb.DeltaFile{
+			Name:      f.Name,
+			Owner:     f.Owner,
+			Mode:      f.Mode,
+			Size:      f.Size,
+			Hash:      f.Hash,
+			Installed: f.Installed,
+		})
+	}
+	if err := stream.Send(first); err != nil {
+		return err
+	}
+
+	changed := delta.Changed()
+	log.WithFields(log.Fields{
+		"name":      name,
+		"installed": installed.Version,
+		"version":   target.Version,
+		"files":     len(delta.Files),
+		"changed":   len(changed),
+	}).Debug("DownloadDelta")
+
+	for _, hash := range changed {
+		err := func() error {
+			contents, err := s.dataStore.OpenFileContents(hash)
+			if err != nil {
+				return grpcError(err)
+			}
+			defer contents.Close()
+			return sendChunks(contents, func(buf []byte) error {
+				return stream.Send(&pb.DeltaChunk{Hash: hash, Data: buf})
+			})
+		}()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"name":  name,
+				"hash":  hash,
+			}).Error("DownloadDelta sending contents")
+			return err
+		}
+	}
+
+	return nil
+}
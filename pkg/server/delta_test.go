@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Collects everything sent on a delta download stream.
+type fakeDeltaStream struct {
+	grpc.ServerStream
+	chunks []*pb.DeltaChunk
+}
+
+func (f *fakeDeltaStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeDeltaStream) Send(c *pb.DeltaChunk) error {
+	f.chunks = append(f.chunks, c)
+	return nil
+}
+
+func TestDownloadDelta(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	v1, err := s.UploadPackage(context.Background(), testPackage("#!/bin/sh\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading v1: %v", err)
+	}
+	pkg := testPackage("#!/bin/sh\necho v2\n")
+	pkg.Files = append(pkg.Files, &pb.File{Name: "NOTES", Owner: "root", Mode: 0644, Contents: []byte("read me")})
+	v2, err := s.UploadPackage(context.Background(), pkg)
+	if err != nil {
+		t.Fatalf("Unexpected error uploading v2: %v", err)
+	}
+
+	stream := &fakeDeltaStream{}
+	req := &pb.DeltaRequest{PackageName: "foo", InstalledVersion: v1.GetVersion(), Designator: "latest"}
+	if err := s.DownloadDelta(req, stream); err != nil {
+		t.Fatalf("Unexpected error downloading delta: %v", err)
+	}
+	if len(stream.chunks) != 2 {
+		t.Fatalf("Saw %d chunks, expected the file list and one changed file", len(stream.chunks))
+	}
+
+	first := stream.chunks[0]
+	if first.GetPackageData().GetVersion() != v2.GetVersion() {
+		t.Errorf("Saw version %s, want %s", first.GetPackageData().GetVersion(), v2.GetVersion())
+	}
+	installed := make(map[string]string)
+	for _, f := range first.GetFiles() {
+		installed[f.GetName()] = f.GetInstalled()
+	}
+	want := map[string]string{"bin/": "", "bin/start": "", "README": "README", "NOTES": "README"}
+	if len(installed) != len(want) {
+		t.Errorf("Saw files %v, want %v", installed, want)
+	}
+	for name, from := range want {
+		if seen, ok := installed[name]; !ok || seen != from {
+			t.Errorf("File %s taken from %q, want %q", name, seen, from)
+		}
+	}
+
+	contents := stream.chunks[1]
+	if string(contents.GetData()) != "#!/bin/sh\necho v2\n" {
+		t.Errorf("Saw contents %q", contents.GetData())
+	}
+	if sum := fmt.Sprintf("%x", sha512.Sum512(contents.GetData())); sum != contents.GetHash() {
+		t.Errorf("Contents sent with hash %s, expected %s", contents.GetHash(), sum)
+	}
+
+	req.InstalledVersion = "nope"
+	err = s.DownloadDelta(req, &fakeDeltaStream{})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown installed version, saw %v", err)
+	}
+}
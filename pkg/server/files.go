@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// List the files in a version of a package, without the caller
+// having to download it.
+func (s *Server) ListFiles(ctx context.Context, in *pb.ListFilesRequest) (*pb.FileList, error) {
+	name := in.GetPackageName()
+	if name == "" || in.GetDesignator() == "" {
+		return nil, fmt.Errorf("Listing files needs a package name and a designator")
+	}
+	if err := s.authorize(ctx, "ListFiles", name); err != nil {
+		return nil, err
+	}
+
+	pv, err := s.dataStore.GetPackageVersion(name, in.GetDesignator())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	entries, err := s.dataStore.ListFiles(pv)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    name,
+			"version": pv.Version,
+		}).Error("ListFiles")
+		return nil, grpcError(err)
+	}
+
+	rv := &pb.FileList{PackageData: packageInformationFromPackageVersion(pv)}
+	for _, e := range entries {
+		rv.Files = append(rv.Files, fileEntry(e))
+	}
+	return rv, nil
+}
+
+// Stream the contents of a single file in a version of a package,
+// without sending the rest of the version.
+func (s *Server) GetFile(in *pb.GetFileRequest, stream pb.Mspm_GetFileServer) error {
+	name := in.GetPackageName()
+	if name == "" || in.GetDesignator() == "" || in.GetPath() == "" {
+		return fmt.Errorf("Getting a file needs a package name, a designator and a path")
+	}
+	if err := s.authorize(stream.Context(), "GetFile", name); err != nil {
+		return err
+	}
+
+	pv, err := s.dataStore.GetPackageVersion(name, in.GetDesignator())
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	entry, contents, err := s.dataStore.OpenFile(pv, in.GetPath())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    name,
+			"version": pv.Version,
+			"path":    in.GetPath(),
+		}).Error("GetFile")
+		return grpcError(err)
+	}
+	defer contents.Close()
+
+	chunk := &pb.FileChunk{
+		PackageData: packageInformationFromPackageVersion(pv),
+		File:        fileEntry(entry),
+	}
+	return sendChunks(contents, func(buf []byte) error {
+		chunk.Data = buf
+		err := stream.Send(chunk)
+		chunk = &pb.FileChunk{}
+		return err
+	})
+}
+
+// Convert a manifest entry to its protobuf form.
+func fileEntry(e data.ManifestEntry) *pb.FileEntry {
+	return &pb.FileEntry{
+		Name:  e.Name,
+		Type:  e.Type(),
+		Size:  e.Size,
+		Mode:  e.Mode,
+		Owner: e.Owner,
+		Group: e.Group,
+		Hash:  e.Hash,
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestListFiles(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	pkg := testPackage("#!/bin/sh\n")
+	pkg.Files[1].Group = "staff"
+	info, err := s.UploadPackage(context.Background(), pkg)
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	list, err := s.ListFiles(context.Background(), &pb.ListFilesRequest{PackageName: "foo", Designator: "latest"})
+	if err != nil {
+		t.Fatalf("Unexpected error listing files: %v", err)
+	}
+	if list.GetPackageData().GetVersion() != info.GetVersion() {
+		t.Errorf("Saw version %s, want %s", list.GetPackageData().GetVersion(), info.GetVersion())
+	}
+
+	want := []*pb.FileEntry{
+		{Name: "README", Type: "file", Size: 7, Mode: 0644, Owner: "root"},
+		{Name: "bin/", Type: "dir", Mode: 0755, Owner: "root"},
+		{Name: "bin/start", Type: "file", Size: 10, Mode: 0755, Owner: "root", Group: "staff"},
+	}
+	if len(list.GetFiles()) != len(want) {
+		t.Fatalf("Saw %d files, want %d", len(list.GetFiles()), len(want))
+	}
+	for ix, w := range want {
+		f := list.GetFiles()[ix]
+		if f.GetName() != w.Name || f.GetType() != w.Type || f.GetSize() != w.Size || f.GetMode() != w.Mode || f.GetOwner() != w.Owner || f.GetGroup() != w.Group {
+			t.Errorf("Saw %v, want %v", f, w)
+		}
+		if (f.GetHash() == "") != (w.Type == "dir") {
+			t.Errorf("Unexpected hash %q for %s", f.GetHash(), f.GetName())
+		}
+	}
+
+	// The group is not part of the version, so uploading the same
+	// contents with another group gets the version as it was.
+	pkg.Files[1].Group = "wheel"
+	again, err := s.UploadPackage(context.Background(), pkg)
+	if err != nil || again.GetVersion() != info.GetVersion() {
+		t.Errorf("Expected version %s again, saw %s (%v)", info.GetVersion(), again.GetVersion(), err)
+	}
+	list, err = s.ListFiles(context.Background(), &pb.ListFilesRequest{PackageName: "foo", Designator: "latest"})
+	if err != nil || list.GetFiles()[2].GetGroup() != "staff" {
+		t.Errorf("Expected bin/start to keep group staff, saw %v (%v)", list.GetFiles(), err)
+	}
+
+	_, err = s.ListFiles(context.Background(), &pb.ListFilesRequest{PackageName: "foo", Designator: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown version, saw %v", err)
+	}
+}
+
+// Collects everything sent on a file download stream.
+type fakeFileStream struct {
+	grpc.ServerStream
+	chunks []*pb.FileChunk
+}
+
+func (f *fakeFileStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeFileStream) Send(c *pb.FileChunk) error {
+	f.chunks = append(f.chunks, c)
+	return nil
+}
+
+func TestGetFile(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	big := make([]byte, 2*chunkSize+1)
+	rand.Read(big)
+	pkg := testPackage("#!/bin/sh\n")
+	pkg.Files = append(pkg.Files, &pb.File{Name: "bin/big", Owner: "root", Mode: 0644, Contents: big})
+	if _, err := s.UploadPackage(context.Background(), pkg); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	stream := &fakeFileStream{}
+	req := &pb.GetFileRequest{PackageName: "foo", Designator: "latest", Path: "bin/big"}
+	if err := s.GetFile(req, stream); err != nil {
+		t.Fatalf("Unexpected error getting file: %v", err)
+	}
+	if len(stream.chunks) != 3 {
+		t.Fatalf("Saw %d chunks, expected 3", len(stream.chunks))
+	}
+	first := stream.chunks[0]
+	if first.GetFile().GetName() != "bin/big" || first.GetFile().GetSize() != int64(len(big)) {
+		t.Errorf("Saw file %v", first.GetFile())
+	}
+	var buf bytes.Buffer
+	for _, c := range stream.chunks {
+		buf.Write(c.GetData())
+	}
+	if !bytes.Equal(buf.Bytes(), big) {
+		t.Errorf("Received contents differ from what was uploaded")
+	}
+
+	testcases := []struct {
+		path string
+		code codes.Code
+	}{
+		{"bin", codes.InvalidArgument},
+		{"bin/nope", codes.NotFound},
+	}
+	for _, tc := range testcases {
+		req.Path = tc.path
+		err := s.GetFile(req, &fakeFileStream{})
+		if status.Code(err) != tc.code {
+			t.Errorf("Getting %s, expected %v, saw %v", tc.path, tc.code, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Apply a batch of label changes, possibly across several packages,
+// all or nothing. The caller needs to be allowed ChangeLabels (and,
+// for forced changes, ForceLabel) for every change. The response has the
+// version each change put its label on, one per change.
+func (s *Server) ChangeLabels(ctx context.Context, in *pb.LabelTransaction) (*pb.LabelTransactionResponse, error) {
+	var changes []data.LabelChange
+
+	for ix, c := range in.GetChanges() {
+		if c.GetPackageName() == "" || c.GetDesignator() == "" || c.GetLabel() == "" {
+			return nil, fmt.Errorf("Change #%d needs a package name, designator and label", ix)
+		}
+		if err := s.authorize(ctx, "ChangeLabels", c.GetPackageName(), c.GetLabel()); err != nil {
+			return nil, err
+		}
+		if c.GetForce() {
+			if err := s.authorize(ctx, "ForceLabel", c.GetPackageName(), c.GetLabel()); err != nil {
+				return nil, err
+			}
+		}
+
+		lc := data.LabelChange{
+			Package:    c.GetPackageName(),
+			Designator: c.GetDesignator(),
+			Label:      c.GetLabel(),
+			Force:      c.GetForce(),
+			Expected:   c.GetExpected(),
+			Identity:   peerIdentity(ctx),
+		}
+		if c.GetExpectUnset() {
+			lc.Expected = data.ExpectUnset
+		}
+		changes = append(changes, lc)
+	}
+
+	targets, err := s.dataStore.ChangeLabels(changes)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"changes": len(changes),
+		}).Error("ChangeLabels")
+		return nil, grpcError(err)
+	}
+
+	rv := new(pb.LabelTransactionResponse)
+	for _, pv := range targets {
+		rv.PackageData = append(rv.PackageData, packageInformationFromPackageVersion(pv))
+	}
+
+	return rv, nil
+}
+
+// Return the history of a label on a package, or of all its labels if
+// no label is given.
+func (s *Server) GetLabelHistory(ctx context.Context, in *pb.LabelHistoryRequest) (*pb.LabelHistory, error) {
+	name := in.GetPackageName()
+	if name == "" {
+		return nil, fmt.Errorf("No package name specified.")
+	}
+	if err := s.authorize(ctx, "GetLabelHistory", name); err != nil {
+		return nil, err
+	}
+
+	events, err := s.dataStore.LabelHistory(name, in.GetLabel())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+			"label": in.GetLabel(),
+		}).Error("GetLabelHistory")
+		return nil, err
+	}
+
+	rv := &pb.LabelHistory{PackageName: name}
+	for _, e := range events {
+		rv.Events = append(rv.Events, &pb.LabelEvent{
+			Label:      e.Label,
+			Time:       e.Time.Unix(),
+			OldVersion: e.Old,
+			NewVersion: e.New,
+			Identity:   e.Identity,
+		})
+	}
+
+	return rv, nil
+}
+
+// Move a label back to the version it was on before it last moved.
+func (s *Server) RollbackLabel(ctx context.Context, in *pb.RollbackLabelRequest) (*pb.PackageInformation, error) {
+	name := in.GetPackageName()
+	label := in.GetLabel()
+	if name == "" || label == "" {
+		return nil, fmt.Errorf("Rollback needs a package name and a label")
+	}
+	if err := s.authorize(ctx, "RollbackLabel", name, label); err != nil {
+		return nil, err
+	}
+	if in.GetForce() {
+		if err := s.authorize(ctx, "ForceLabel", name, label); err != nil {
+			return nil, err
+		}
+	}
+
+	version, err := s.dataStore.RollbackLabel(name, label, peerIdentity(ctx), in.GetForce())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+			"label": label,
+		}).Error("RollbackLabel")
+		return nil, grpcError(err)
+	}
+	log.WithFields(log.Fields{
+		"name":    name,
+		"label":   label,
+		"version": version,
+	}).Info("RollbackLabel - label rolled back")
+
+	pv, err := s.dataStore.GetPackageVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return packageInformationFromPackageVersion(pv), nil
+}
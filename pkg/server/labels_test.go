@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestSetLabelsProtected(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	s.SetProtectedLabels([]string{"prod"})
+
+	ctx := context.Background()
+	first, err := s.UploadPackage(ctx, testPackage("first"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	second, err := s.UploadPackage(ctx, testPackage("second"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	req := &pb.SetLabelRequest{PackageName: "foo", Version: first.GetVersion(), Label: []string{"prod"}}
+	if _, err := s.SetLabels(ctx, req); err != nil {
+		t.Fatalf("Unexpected error setting prod: %v", err)
+	}
+
+	req.Version = second.GetVersion()
+	_, err = s.SetLabels(ctx, req)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition moving prod, saw %v", err)
+	}
+
+	// Forcing needs ForceLabel in the policy.
+	req.Force = true
+	s.SetPolicy(&Policy{Rules: []PolicyRule{
+		{Identities: []string{"*"}, Methods: []string{"SetLabels"}, Packages: []string{"*"}, Labels: []string{"*"}},
+	}})
+	_, err = s.SetLabels(ctx, req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied forcing prod, saw %v", err)
+	}
+
+	s.SetPolicy(nil)
+	info, err := s.SetLabels(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error forcing prod: %v", err)
+	}
+	if info.GetVersion() != second.GetVersion() {
+		t.Errorf("Expected prod on %s, saw %s", second.GetVersion(), info.GetVersion())
+	}
+}
+
+func TestSetPackageOptions(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	first, err := s.UploadPackage(ctx, testPackage("first"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	opts, err := s.SetPackageOptions(ctx, &pb.PackageOptions{PackageName: "foo", DisableLatest: true})
+	if err != nil || !opts.GetDisableLatest() {
+		t.Fatalf("Unexpected result setting options: %v, %v", opts, err)
+	}
+	if _, err := s.UploadPackage(ctx, testPackage("second")); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	resp, err := s.GetPackage(ctx, &pb.GetPackageRequest{PackageName: "foo", Designator: "latest"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.GetPackageData().GetVersion() != first.GetVersion() {
+		t.Errorf("Expected latest to stay on the first upload")
+	}
+
+	if _, err := s.SetPackageOptions(ctx, &pb.PackageOptions{}); err == nil {
+		t.Errorf("Expected an error without a package name")
+	}
+}
+
+func TestSetLabelsExpected(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	first, err := s.UploadPackage(ctx, testPackage("first"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	second, err := s.UploadPackage(ctx, testPackage("second"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	req := &pb.SetLabelRequest{
+		PackageName: "foo",
+		Version:     first.GetVersion(),
+		Label:       []string{"prod", "stable"},
+		Expected:    map[string]string{"prod": ""},
+	}
+	if _, err := s.SetLabels(ctx, req); err != nil {
+		t.Fatalf("Unexpected error setting prod: %v", err)
+	}
+
+	// A pipeline that thinks prod is still unset loses, and does
+	// not get stable moved either.
+	req.Version = second.GetVersion()
+	_, err = s.SetLabels(ctx, req)
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("Expected Aborted, saw %v", err)
+	}
+	resp, err := s.GetPackage(ctx, &pb.GetPackageRequest{PackageName: "foo", Designator: "stable"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.GetPackageData().GetVersion() != first.GetVersion() {
+		t.Errorf("Expected stable to stay on the first version")
+	}
+
+	// An expectation on a label the request does not set is
+	// refused, rather than silently ignored.
+	staging := &pb.SetLabelRequest{
+		PackageName: "foo",
+		Version:     second.GetVersion(),
+		Label:       []string{"staging"},
+		Expected:    map[string]string{"prod": second.GetVersion()},
+	}
+	if _, err := s.SetLabels(ctx, staging); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, saw %v", err)
+	}
+	if _, err := s.GetPackage(ctx, &pb.GetPackageRequest{PackageName: "foo", Designator: "staging"}); err == nil {
+		t.Errorf("Expected staging not to be set")
+	}
+
+	req.Expected = map[string]string{"prod": first.GetVersion()}
+	info, err := s.SetLabels(ctx, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.GetVersion() != second.GetVersion() || len(info.GetLabel()) != 3 {
+		t.Errorf("Expected latest, prod and stable on the second version, saw %v", info)
+	}
+}
+
+func TestChangeLabels(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	foo, err := s.UploadPackage(ctx, testPackage("foo"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	barPackage := testPackage("bar")
+	barPackage.PackageName = "bar"
+	bar, err := s.UploadPackage(ctx, barPackage)
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	// The second change fails, so the first must not happen.
+	txn := &pb.LabelTransaction{Changes: []*pb.LabelChange{
+		{PackageName: "foo", Designator: "latest", Label: "prod", ExpectUnset: true},
+		{PackageName: "bar", Designator: "latest", Label: "prod", Expected: foo.GetVersion()},
+	}}
+	_, err = s.ChangeLabels(ctx, txn)
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("Expected Aborted, saw %v", err)
+	}
+	if _, err := s.GetPackage(ctx, &pb.GetPackageRequest{PackageName: "foo", Designator: "prod"}); err == nil {
+		t.Errorf("Expected prod not to be set on foo after a failed transaction")
+	}
+
+	txn.Changes[1].Expected = ""
+	txn.Changes[1].ExpectUnset = true
+	resp, err := s.ChangeLabels(ctx, txn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.GetPackageData()) != 2 {
+		t.Fatalf("Expected 2 results, saw %d", len(resp.GetPackageData()))
+	}
+	if resp.GetPackageData()[0].GetVersion() != foo.GetVersion() || resp.GetPackageData()[1].GetVersion() != bar.GetVersion() {
+		t.Errorf("Unexpected result %v", resp.GetPackageData())
+	}
+
+	// Changes on unknown packages, or with missing fields, fail.
+	for _, c := range []*pb.LabelChange{
+		{PackageName: "baz", Designator: "latest", Label: "prod"},
+		{PackageName: "foo", Label: "prod"},
+	} {
+		_, err = s.ChangeLabels(ctx, &pb.LabelTransaction{Changes: []*pb.LabelChange{c}})
+		if err == nil {
+			t.Errorf("Expected an error for %v", c)
+		}
+	}
+}
+
+func TestLabelHistoryAndRollback(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	first, err := s.UploadPackage(ctx, testPackage("first"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	second, err := s.UploadPackage(ctx, testPackage("second"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	history, err := s.GetLabelHistory(ctx, &pb.LabelHistoryRequest{PackageName: "foo", Label: "latest"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	events := history.GetEvents()
+	if len(events) != 2 || events[1].GetOldVersion() != first.GetVersion() || events[1].GetNewVersion() != second.GetVersion() {
+		t.Errorf("Unexpected history %v", events)
+	}
+	if events[1].GetIdentity() != anonymous {
+		t.Errorf("Expected the upload by %s, saw %s", anonymous, events[1].GetIdentity())
+	}
+
+	info, err := s.RollbackLabel(ctx, &pb.RollbackLabelRequest{PackageName: "foo", Label: "latest"})
+	if err != nil {
+		t.Fatalf("Unexpected error rolling back: %v", err)
+	}
+	if info.GetVersion() != first.GetVersion() {
+		t.Errorf("Expected latest rolled back to the first version, saw %s", info.GetVersion())
+	}
+
+	_, err = s.RollbackLabel(ctx, &pb.RollbackLabelRequest{PackageName: "foo", Label: "prod"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition rolling back an unused label, saw %v", err)
+	}
+}
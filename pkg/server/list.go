@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// How many packages a listing returns per page, unless asked for
+// fewer, and the most it ever returns.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// List the packages matching a filter, a page at a time. Packages the
+// caller may not list are left out, rather than failing the call. The
+// page token is the name of the last package on the previous page.
+func (s *Server) ListPackages(ctx context.Context, in *pb.ListPackagesRequest) (*pb.PackageList, error) {
+	filter := data.PackageFilter{
+		Prefix:  in.GetPrefix(),
+		Pattern: in.GetPattern(),
+		Label:   in.GetLabel(),
+	}
+	pageSize := int(in.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	summaries, err := s.dataStore.ListPackages(filter, in.GetPageToken())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"pattern": filter.Pattern,
+		}).Error("ListPackages")
+		return nil, grpcError(err)
+	}
+
+	rv := new(pb.PackageList)
+	for _, summary := range summaries {
+		if !s.allowed(ctx, "ListPackages", summary.Name) {
+			continue
+		}
+		if len(rv.Packages) == pageSize {
+			rv.NextPageToken = rv.Packages[pageSize-1].GetPackageName()
+			break
+		}
+		rv.Packages = append(rv.Packages, &pb.PackageSummary{
+			PackageName: summary.Name,
+			Versions:    int32(summary.Versions),
+			Labels:      summary.Labels,
+		})
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// List all pages of a listing, returning the package names.
+func listAll(t *testing.T, s *Server, req *pb.ListPackagesRequest) []string {
+	var rv []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("Listing does not end")
+		}
+		resp, err := s.ListPackages(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Unexpected error listing: %v", err)
+		}
+		for _, p := range resp.GetPackages() {
+			rv = append(rv, p.GetPackageName())
+		}
+		if resp.GetNextPageToken() == "" {
+			return rv
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ix := range a {
+		if a[ix] != b[ix] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListPackages(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	for _, name := range []string{"web-frontend", "web-backend", "db", "web-proxy", "tools"} {
+		pkg := testPackage(name)
+		pkg.PackageName = name
+		if _, err := s.UploadPackage(ctx, pkg); err != nil {
+			t.Fatalf("Unexpected error uploading %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"db", "web-proxy"} {
+		req := &pb.SetLabelRequest{PackageName: name, Version: "latest", Label: []string{"prod"}}
+		if _, err := s.SetLabels(ctx, req); err != nil {
+			t.Fatalf("Unexpected error labelling %s: %v", name, err)
+		}
+	}
+
+	cases := []struct {
+		req      *pb.ListPackagesRequest
+		expected []string
+	}{
+		{&pb.ListPackagesRequest{}, []string{"db", "tools", "web-backend", "web-frontend", "web-proxy"}},
+		{&pb.ListPackagesRequest{PageSize: 2}, []string{"db", "tools", "web-backend", "web-frontend", "web-proxy"}},
+		{&pb.ListPackagesRequest{Prefix: "web-", PageSize: 1}, []string{"web-backend", "web-frontend", "web-proxy"}},
+		{&pb.ListPackagesRequest{Pattern: "*end"}, []string{"web-backend", "web-frontend"}},
+		{&pb.ListPackagesRequest{Label: "prod"}, []string{"db", "web-proxy"}},
+		{&pb.ListPackagesRequest{Prefix: "web-", Label: "prod"}, []string{"web-proxy"}},
+		{&pb.ListPackagesRequest{Label: "staging"}, nil},
+	}
+	for ix, c := range cases {
+		seen := listAll(t, s, c.req)
+		if !sameNames(seen, c.expected) {
+			t.Errorf("Case #%d, expected %v, saw %v", ix, c.expected, seen)
+		}
+	}
+
+	resp, _ := s.ListPackages(ctx, &pb.ListPackagesRequest{Label: "prod", PageSize: 1})
+	if len(resp.GetPackages()) != 1 || resp.GetPackages()[0].GetVersions() != 1 || resp.GetPackages()[0].GetLabels()["prod"] == "" {
+		t.Errorf("Expected a summary of db with prod set, saw %v", resp.GetPackages())
+	}
+
+	_, err := s.ListPackages(ctx, &pb.ListPackagesRequest{Pattern: "[web"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a bad pattern, saw %v", err)
+	}
+
+	// Packages the caller may not list are left out.
+	s.SetPolicy(&Policy{Rules: []PolicyRule{
+		{Identities: []string{"*"}, Methods: []string{"ListPackages"}, Packages: []string{"web-*"}},
+	}})
+	seen := listAll(t, s, &pb.ListPackagesRequest{PageSize: 2})
+	if expected := []string{"web-backend", "web-frontend", "web-proxy"}; !sameNames(seen, expected) {
+		t.Errorf("Expected only %v, saw %v", expected, seen)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The identity of callers that did not present a client certificate.
+const anonymous = "anonymous"
+
+// A Policy decides which identities may call which RPCs, on which
+// packages. A call is allowed if any rule allows it. All fields of a
+// rule are lists of path.Match patterns, and a rule applies if the
+// identity, RPC method name and package name each match one of
+// them. Any labels the call sets must also all match Labels, so a
+// rule without Labels only allows calls that set no labels. The
+// "latest" label an upload sets is not checked. Forcing a protected
+// label to move also needs the method "ForceLabel" to be allowed.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+type PolicyRule struct {
+	Identities []string `json:"identities"`
+	Methods    []string `json:"methods"`
+	Packages   []string `json:"packages"`
+	Labels     []string `json:"labels,omitempty"`
+}
+
+// Read a policy from a JSON file.
+func LoadPolicy(filename string) (*Policy, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv Policy
+	err = json.Unmarshal(buf, &rv)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rv.Rules {
+		for _, patterns := range [][]string{rule.Identities, rule.Methods, rule.Packages, rule.Labels} {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &rv, nil
+}
+
+// Check if s matches any of the patterns.
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r PolicyRule) allows(identity, method, pkg string, labels []string) bool {
+	if !matchAny(r.Identities, identity) || !matchAny(r.Methods, method) || !matchAny(r.Packages, pkg) {
+		return false
+	}
+	for _, label := range labels {
+		if !matchAny(r.Labels, label) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if identity may call method on pkg, setting labels.
+func (p *Policy) Allowed(identity, method, pkg string, labels []string) bool {
+	for _, rule := range p.Rules {
+		if rule.allows(identity, method, pkg, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the identity of the caller. This is the identity of the token
+// the call was made with, if any, otherwise it comes from the verified
+// client certificate: the certificate's common name or, if that is
+// empty, its first DNS name.
+func peerIdentity(ctx context.Context) string {
+	if identity, ok := tokenIdentity(ctx); ok {
+		return identity
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return anonymous
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return anonymous
+	}
+
+	leaf := info.State.VerifiedChains[0][0]
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0]
+	}
+	return anonymous
+}
+
+// Set the policy the server enforces. A nil policy allows everything.
+func (s *Server) SetPolicy(p *Policy) {
+	s.policyLock.Lock()
+	defer s.policyLock.Unlock()
+
+	s.policy = p
+}
+
+// Check that the caller may call method on pkg, setting labels.
+func (s *Server) authorize(ctx context.Context, method, pkg string, labels ...string) error {
+	if s.allowed(ctx, method, pkg, labels...) {
+		return nil
+	}
+
+	identity := peerIdentity(ctx)
+	log.WithFields(log.Fields{
+		"identity": identity,
+		"method":   method,
+		"name":     pkg,
+		"labels":   labels,
+	}).Warning("permission denied")
+	return status.Errorf(codes.PermissionDenied, "%s may not call %s on %s", identity, method, pkg)
+}
+
+// Check the policy like authorize, but without logging a refusal. For
+// filtering what a caller gets to see. A caller with a token also
+// needs the scope for the method, which matters for pseudo-methods
+// like "ForceLabel" that the token interceptors never see.
+func (s *Server) allowed(ctx context.Context, method, pkg string, labels ...string) bool {
+	if te, ok := callToken(ctx); ok && !te.allows(method) {
+		return false
+	}
+
+	s.policyLock.RLock()
+	p := s.policy
+	s.policyLock.RUnlock()
+	if p == nil {
+		return true
+	}
+
+	return p.Allowed(peerIdentity(ctx), method, pkg, labels)
+}
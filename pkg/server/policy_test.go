@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+var testPolicy = &Policy{
+	Rules: []PolicyRule{
+		{
+			Identities: []string{"*"},
+			Methods:    []string{"Get*", "DownloadPackage"},
+			Packages:   []string{"*"},
+		},
+		{
+			Identities: []string{"ci-*"},
+			Methods:    []string{"UploadPackage*", "SetLabels"},
+			Packages:   []string{"foo", "bar-*"},
+			Labels:     []string{"latest", "staging"},
+		},
+		{
+			Identities: []string{"release"},
+			Methods:    []string{"SetLabels"},
+			Packages:   []string{"*"},
+			Labels:     []string{"*"},
+		},
+		{
+			Identities: []string{"ops"},
+			Methods:    []string{"SetLabels", "UploadPackage*"},
+			Packages:   []string{"*"},
+		},
+	},
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	cases := []struct {
+		identity string
+		method   string
+		pkg      string
+		labels   []string
+		want     bool
+	}{
+		{anonymous, "GetPackage", "foo", nil, true},
+		{anonymous, "SetLabels", "foo", []string{"staging"}, false},
+		{"ci-build", "UploadPackage", "foo", nil, true},
+		{"ci-build", "UploadPackageStream", "bar-baz", []string{"staging"}, true},
+		{"ci-build", "UploadPackageStream", "baz", nil, false},
+		{"ci-build", "SetLabels", "foo", []string{"staging"}, true},
+		{"ci-build", "SetLabels", "foo", []string{"staging", "prod"}, false},
+		{"release", "SetLabels", "foo", []string{"prod"}, true},
+		{"release", "UploadPackage", "foo", nil, false},
+		{"ops", "UploadPackage", "foo", nil, true},
+		{"ops", "UploadPackageStream", "foo", []string{"staging"}, false},
+		{"ops", "SetLabels", "foo", []string{"prod"}, false},
+		{"ops", "SetLabels", "foo", []string{"latest"}, false},
+	}
+
+	for _, c := range cases {
+		seen := testPolicy.Allowed(c.identity, c.method, c.pkg, c.labels)
+		if seen != c.want {
+			t.Errorf("Allowed(%s, %s, %s, %v), expected %v, saw %v", c.identity, c.method, c.pkg, c.labels, c.want, seen)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-policy")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.json")
+	ioutil.WriteFile(good, []byte(`{"rules": [{"identities": ["release"], "methods": ["*"], "packages": ["*"], "labels": ["prod"]}]}`), 0644)
+	p, err := LoadPolicy(good)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !p.Allowed("release", "SetLabels", "foo", []string{"prod"}) {
+		t.Errorf("Expected release to be allowed to set prod")
+	}
+
+	for name, contents := range map[string]string{
+		"syntax.json":  `{"rules": [`,
+		"pattern.json": `{"rules": [{"identities": ["["], "methods": ["*"], "packages": ["*"]}]}`,
+	} {
+		bad := filepath.Join(dir, name)
+		ioutil.WriteFile(bad, []byte(contents), 0644)
+		if _, err := LoadPolicy(bad); err == nil {
+			t.Errorf("Expected an error loading %s", name)
+		}
+	}
+}
+
+// Dial addr, presenting the certificate in certFile and keyFile.
+func dialMTLS(t *testing.T, addr string, ca *testCA, certFile, keyFile string) *grpc.ClientConn {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	config := &tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{cert}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(credentials.NewTLS(config)), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	return conn
+}
+
+func TestAuthorizeMTLS(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	s.SetPolicy(testPolicy)
+
+	ca := newTestCA(t)
+	cr, err := NewCertReloader(ca.issue(t, dir, "server", "server"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	addr, stop := serveTLS(t, s, cr.TLSConfig(tls.VersionTLS12, ca.pool()))
+	defer stop()
+
+	ctx := context.Background()
+	ciCert, ciKey := ca.issue(t, dir, "ci", "ci-build")
+	ci := pb.NewMspmClient(dialMTLS(t, addr, ca, ciCert, ciKey))
+	info, err := ci.UploadPackage(ctx, testPackage("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	req := &pb.SetLabelRequest{PackageName: "foo", Version: info.GetVersion(), Label: []string{"staging"}}
+	if _, err := ci.SetLabels(ctx, req); err != nil {
+		t.Errorf("Unexpected error setting staging: %v", err)
+	}
+	req.Label = []string{"prod"}
+	_, err = ci.SetLabels(ctx, req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied setting prod as ci-build, saw %v", err)
+	}
+
+	relCert, relKey := ca.issue(t, dir, "release", "release")
+	rel := pb.NewMspmClient(dialMTLS(t, addr, ca, relCert, relKey))
+	if _, err := rel.SetLabels(ctx, req); err != nil {
+		t.Errorf("Unexpected error setting prod as release: %v", err)
+	}
+
+	// Without a client certificate, the handshake fails.
+	plain, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool()})
+	if err == nil {
+		_, err = plain.Read(make([]byte, 1))
+		plain.Close()
+	}
+	if err == nil {
+		t.Errorf("Expected a connection without a client certificate to fail")
+	}
+}
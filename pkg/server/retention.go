@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// The identity garbage collection deletes versions as, in the label
+// history and the catalog.
+const retentionIdentity = "retention"
+
+// Set the retention policy garbage collection enforces. With a nil
+// policy, nothing is collected.
+func (s *Server) SetRetentionPolicy(p *data.RetentionPolicy) {
+	s.policyLock.Lock()
+	defer s.policyLock.Unlock()
+
+	s.retention = p
+}
+
+func (s *Server) retentionPolicy() *data.RetentionPolicy {
+	s.policyLock.RLock()
+	defer s.policyLock.RUnlock()
+
+	return s.retention
+}
+
+// Delete the versions the retention policy does not keep every
+// interval. This never returns, so is expected to be run in a
+// goroutine of its own.
+func (s *Server) CollectGarbage(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.collectGarbage(time.Now())
+	}
+}
+
+// Run one round of garbage collection, as of now. Each deleted version
+// is logged, and recorded in the audit log if there is one.
+func (s *Server) collectGarbage(now time.Time) {
+	policy := s.retentionPolicy()
+	if policy == nil {
+		return
+	}
+	deleted, err := s.dataStore.CollectGarbage(policy, now, retentionIdentity)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("CollectGarbage")
+		return
+	}
+	for _, pv := range deleted {
+		log.WithFields(log.Fields{
+			"name":    pv.Name,
+			"version": pv.Version,
+			"created": pv.Created,
+		}).Info("CollectGarbage - deleted")
+		if s.auditLog != nil {
+			s.auditLog.finish(AuditRecord{
+				Time:     now.UTC(),
+				Method:   "CollectGarbage",
+				Identity: retentionIdentity,
+				Packages: []string{pv.Name},
+				Version:  pv.Version,
+				Summary:  fmt.Sprintf("created=%s", pv.Created.UTC().Format(time.RFC3339)),
+			}, nil)
+		}
+	}
+}
+
+// Report which versions garbage collection would delete right now,
+// without deleting anything. Packages the caller may not see are left
+// out.
+func (s *Server) GetRetentionReport(ctx context.Context, in *pb.RetentionReportRequest) (*pb.RetentionReport, error) {
+	name := in.GetPackageName()
+	if name != "" {
+		if err := s.authorize(ctx, "GetRetentionReport", name); err != nil {
+			return nil, err
+		}
+	}
+	policy := s.retentionPolicy()
+	if policy == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no retention policy configured")
+	}
+
+	rv := new(pb.RetentionReport)
+	for _, pv := range s.dataStore.ExpiredVersions(policy, time.Now()) {
+		if name != "" && pv.Name != name {
+			continue
+		}
+		if !s.allowed(ctx, "GetRetentionReport", pv.Name) {
+			continue
+		}
+		rv.Versions = append(rv.Versions, &pb.ExpiredVersion{
+			PackageName: pv.Name,
+			Version:     pv.Version,
+			Created:     pv.Created.Unix(),
+		})
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func TestGetRetentionReport(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	_, err := s.GetRetentionReport(ctx, &pb.RetentionReportRequest{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without a policy, saw %v", err)
+	}
+
+	var versions []string
+	for _, contents := range []string{"first", "second", "third"} {
+		info, err := s.UploadPackage(ctx, testPackage(contents))
+		if err != nil {
+			t.Fatalf("Unexpected error uploading: %v", err)
+		}
+		versions = append(versions, info.GetVersion())
+	}
+	s.SetRetentionPolicy(&data.RetentionPolicy{Rules: []data.RetentionRule{
+		{Packages: []string{"foo"}, KeepLast: 1},
+	}})
+
+	resp, err := s.GetRetentionReport(ctx, &pb.RetentionReportRequest{PackageName: "foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.GetVersions()) != 2 {
+		t.Fatalf("Expected two expired versions, saw %v", resp.GetVersions())
+	}
+	for _, e := range resp.GetVersions() {
+		if e.GetVersion() == versions[2] || e.GetCreated() == 0 {
+			t.Errorf("Unexpected expired version %v", e)
+		}
+	}
+
+	// The report deletes nothing.
+	if pvs, _ := s.dataStore.GetPackageVersions("foo"); len(pvs) != 3 {
+		t.Errorf("Expected all three versions to remain, saw %d", len(pvs))
+	}
+
+	s.SetPolicy(&Policy{Rules: []PolicyRule{
+		{Identities: []string{"*"}, Methods: []string{"GetRetentionReport"}, Packages: []string{"bar"}},
+	}})
+	resp, err = s.GetRetentionReport(ctx, &pb.RetentionReportRequest{})
+	if err != nil || len(resp.GetVersions()) != 0 {
+		t.Errorf("Expected foo to be left out, saw %v (%v)", resp.GetVersions(), err)
+	}
+}
+
+func TestCollectGarbageAudited(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	a, err := NewAuditLog(filepath.Join(dir, "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer a.Close()
+	s.SetAuditLog(a)
+
+	ctx := context.Background()
+	var versions []string
+	for _, contents := range []string{"first", "second"} {
+		info, err := s.UploadPackage(ctx, testPackage(contents))
+		if err != nil {
+			t.Fatalf("Unexpected error uploading: %v", err)
+		}
+		versions = append(versions, info.GetVersion())
+	}
+
+	// Without a policy, nothing is collected.
+	s.collectGarbage(time.Now())
+	s.SetRetentionPolicy(&data.RetentionPolicy{Rules: []data.RetentionRule{
+		{Packages: []string{"foo"}, KeepLast: 1},
+	}})
+	s.collectGarbage(time.Now())
+
+	records, err := a.Query("foo", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error querying: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected one audit record, saw %v", records)
+	}
+	r := records[0]
+	if r.Method != "CollectGarbage" || r.Identity != retentionIdentity || r.Version != versions[0] || r.Result != "OK" {
+		t.Errorf("Unexpected audit record %v", r)
+	}
+}
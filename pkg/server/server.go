@@ -2,18 +2,60 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/vatine/mspm/pkg/data"
 	pb "github.com/vatine/mspm/pkg/protos"
 )
 
+// How much package data we send in each message of a download.
+const chunkSize = 64 * 1024
+
 type Server struct {
 	pb.UnimplementedMspmServer
-	dataStore *data.DataStore
+	dataStore      *data.DataStore
+	sessionMaxIdle time.Duration
+	policyLock     sync.RWMutex
+	policy         *Policy
+	retention      *data.RetentionPolicy
+	auditLog       *AuditLog
+}
+
+// Translate errors from the data store to gRPC status errors, so
+// clients can tell them apart.
+func grpcError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, data.ErrNoSession):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, data.ErrOffsetMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrLabelProtected):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrLabelMoved):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, data.ErrNoHistory):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, data.ErrBadPattern):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, data.ErrNoManifest):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, data.ErrIsDirectory):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
 }
 
 // Convert a data.PackageVersion to a pb.PackageInformation, as this
@@ -31,14 +73,45 @@ func packageInformationFromPackageVersion(pv data.PackageVersion) *pb.PackageInf
 }
 
 // Create a new Server data structure, populate it with paths to the
-// playground (temp storage) and primary storage directories.
-func NewServer(playground, store string) *Server {
-	ds := data.NewDataStore(playground, store)
+// playground (temp storage) and primary storage directories. Any
+// catalog already in the primary storage is loaded.
+func NewServer(playground, store string) (*Server, error) {
+	return NewServerWithBlobStore(playground, store, data.NewFileBlobStore(store))
+}
+
+// Create a server like NewServer, keeping package tarballs in a blob
+// store of their own.
+func NewServerWithBlobStore(playground, store string, blobs data.BlobStore) (*Server, error) {
+	ds, err := data.NewDataStoreWithBlobStore(playground, store, blobs)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"playground": playground,
+			"store":      store,
+		}).Error("NewServer - creating data store")
+		return nil, err
+	}
+
+	return &Server{dataStore: ds, sessionMaxIdle: defaultSessionMaxIdle}, nil
+}
+
+// Set the labels that can only be moved by a forced SetLabels.
+func (s *Server) SetProtectedLabels(labels []string) {
+	s.dataStore.SetProtectedLabels(labels)
+}
 
-	return &Server{dataStore: ds}
+// Rebuild the data store catalog from the tarballs in primary
+// storage, labelling everything recovered with defaultLabel.
+func (s *Server) Recover(defaultLabel string) error {
+	return s.dataStore.Recover(defaultLabel)
 }
 
-// Set labels on a specific version of a package.
+// Set labels on a specific version of a package. Moving a protected
+// label needs Force, and the caller must be allowed to
+// "ForceLabel". If any label is not where the request expects it to
+// be, nothing is changed and Aborted is returned. Expectations can only
+// be given for labels the request sets, anything else is
+// InvalidArgument.
 func (s *Server) SetLabels(ctx context.Context, in *pb.SetLabelRequest) (*pb.PackageInformation, error) {
 	pkgName := in.GetPackageName()
 	version := in.GetVersion()
@@ -59,38 +132,62 @@ func (s *Server) SetLabels(ctx context.Context, in *pb.SetLabelRequest) (*pb.Pac
 		}).Error("SetLabels - missing version designator")
 		return nil, fmt.Errorf("No version designator specified")
 	}
-
-	var rErr error
-
-	for ix, label := range in.GetLabel() {
-		log.WithFields(log.Fields{
-			"ix":      ix,
-			"name":    pkgName,
-			"version": version,
-			"label":   label,
-		}).Debug("SetLabels - setting label")
-		err := s.dataStore.SetLabel(pkgName, version, label)
-		if err != nil {
-			rErr = err
+	for label := range in.GetExpected() {
+		if !containsString(in.GetLabel(), label) {
 			log.WithFields(log.Fields{
-				"err":     err,
-				"name":    pkgName,
-				"version": version,
-				"label":   label,
-			}).Error("SetLabels - setting label")
+				"name":  pkgName,
+				"label": label,
+			}).Error("SetLabels - expectation for a label not being set")
+			return nil, status.Errorf(codes.InvalidArgument, "expected version given for %s, which is not being set", label)
+		}
+	}
+	if err := s.authorize(ctx, "SetLabels", pkgName, in.GetLabel()...); err != nil {
+		return nil, err
+	}
+	if in.GetForce() {
+		if err := s.authorize(ctx, "ForceLabel", pkgName, in.GetLabel()...); err != nil {
+			return nil, err
 		}
 	}
 
-	pv, err := s.dataStore.GetPackageVersion(pkgName, version)
+	var changes []data.LabelChange
+	for _, label := range in.GetLabel() {
+		lc := data.LabelChange{
+			Package:    pkgName,
+			Designator: version,
+			Label:      label,
+			Force:      in.GetForce(),
+			Identity:   peerIdentity(ctx),
+		}
+		if expected, ok := in.GetExpected()[label]; ok {
+			lc.Expected = expected
+			if expected == "" {
+				lc.Expected = data.ExpectUnset
+			}
+		}
+		changes = append(changes, lc)
+	}
+
+	targets, err := s.dataStore.ChangeLabels(changes)
 	if err != nil {
 		log.WithFields(log.Fields{
-			"error":   err,
-			"package": pkgName,
+			"err":     err,
+			"name":    pkgName,
 			"version": version,
-		}).Error("SetLabels - unexpected missing")
-		return nil, err
+			"labels":  in.GetLabel(),
+		}).Error("SetLabels - setting labels")
+		return nil, grpcError(err)
+	}
+
+	if len(targets) == 0 {
+		// No labels to set, just report on the version.
+		pv, err := s.dataStore.GetPackageVersion(pkgName, version)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		return packageInformationFromPackageVersion(pv), nil
 	}
-	return packageInformationFromPackageVersion(pv), rErr
+	return packageInformationFromPackageVersion(targets[len(targets)-1]), nil
 }
 
 // Get information on a specific package.
@@ -101,6 +198,9 @@ func (s *Server) GetPackageInformation(ctx context.Context, in *pb.PackageInform
 	if name == "" {
 		return rv, fmt.Errorf("No package named %s", name)
 	}
+	if err := s.authorize(ctx, "GetPackageInformation", name); err != nil {
+		return nil, err
+	}
 	pvs, ok := s.dataStore.GetPackageVersions(name)
 	if !ok {
 		log.WithFields(log.Fields{
@@ -115,12 +215,20 @@ func (s *Server) GetPackageInformation(ctx context.Context, in *pb.PackageInform
 	return rv, nil
 }
 
-// Receive a new version of a package.
+// Receive a new version of a package. The files are written to a
+// working directory in the playground, hashed and archived, and the
+// resulting tarball is moved to primary storage and labelled
+// "latest". If anything fails along the way, the working directory
+// is removed. Uploading a version that already exists is not an
+// error, the existing version is returned as-is.
 func (s *Server) UploadPackage(ctx context.Context, in *pb.NewPackage) (*pb.PackageInformation, error) {
 	name := in.GetPackageName()
 	if name == "" {
 		return nil, fmt.Errorf("No package name specified.")
 	}
+	if err := s.authorize(ctx, "UploadPackage", name); err != nil {
+		return nil, err
+	}
 
 	pv, err := s.dataStore.NewPackageVersion(name)
 	if err != nil {
@@ -133,14 +241,97 @@ func (s *Server) UploadPackage(ctx context.Context, in *pb.NewPackage) (*pb.Pack
 
 	for _, file := range in.GetFiles() {
 		if strings.HasSuffix(file.GetName(), "/") {
-			pv.AddDir(file)
+			err = pv.AddDir(file)
+		} else {
+			err = pv.AddFile(file)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+				"name":  name,
+				"file":  file.GetName(),
+			}).Error("UploadPackage - adding file")
+			s.dataStore.DiscardPackageVersion(pv)
+			return nil, err
 		}
 	}
 
-	return packageInformationFromPackageVersion(pv), nil
+	pv.UploadedBy = peerIdentity(ctx)
+	return s.finishUpload(ctx, pv, nil)
+}
+
+// Finish a package version that has had all its files added, and add
+// it to the data store. The package version is discarded on failure.
+func (s *Server) finishUpload(ctx context.Context, pv data.PackageVersion, labels []string) (*pb.PackageInformation, error) {
+	err := pv.Finish()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  pv.Name,
+		}).Error("UploadPackage - finishing package version")
+		s.dataStore.DiscardPackageVersion(pv)
+		return nil, err
+	}
+
+	return s.storeUpload(ctx, pv, labels)
+}
+
+// Add a finished package version to the data store, with any labels
+// requested at upload time, returning its package information. The
+// package version is discarded on failure. If the version is already
+// stored, the labels are put on it instead.
+func (s *Server) storeUpload(ctx context.Context, pv data.PackageVersion, labels []string) (*pb.PackageInformation, error) {
+	var changes []data.LabelChange
+	for _, label := range labels {
+		changes = append(changes, data.LabelChange{
+			Package:    pv.Name,
+			Designator: pv.Version,
+			Label:      label,
+			Identity:   peerIdentity(ctx),
+		})
+	}
+
+	err := s.dataStore.AddLabelledPackageVersion(pv, changes)
+	if errors.Is(err, data.ErrVersionExists) {
+		log.WithFields(log.Fields{
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Info("UploadPackage - version already exists")
+		s.dataStore.DiscardPackageVersion(pv)
+		if len(changes) > 0 {
+			_, err = s.dataStore.ChangeLabels(changes)
+		} else {
+			err = nil
+		}
+	} else if err != nil {
+		s.dataStore.DiscardPackageVersion(pv)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+			"labels":  labels,
+		}).Error("UploadPackage - storing package version")
+		return nil, grpcError(err)
+	}
+
+	stored, err := s.dataStore.GetPackageVersion(pv.Name, pv.Version)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"name":    pv.Name,
+			"version": pv.Version,
+		}).Error("UploadPackage - unexpected missing")
+		return nil, err
+	}
+
+	return packageInformationFromPackageVersion(stored), nil
 }
 
-// Send a specific version of a package to a client.
+// Send information on a specific version of a package to a
+// client. The package contents are not included, they are sent by
+// DownloadPackage.
 func (s *Server) GetPackage(ctx context.Context, in *pb.GetPackageRequest) (*pb.GetPackageResponse, error) {
 	name := in.GetPackageName()
 	labelDes := in.GetDesignator()
@@ -155,6 +346,9 @@ func (s *Server) GetPackage(ctx context.Context, in *pb.GetPackageRequest) (*pb.
 		}).Error("GetPackage, blank version designator")
 		return nil, fmt.Errorf("No designator specified")
 	}
+	if err := s.authorize(ctx, "GetPackage", name); err != nil {
+		return nil, err
+	}
 
 	pv, err := s.dataStore.GetPackageVersion(name, labelDes)
 	if err != nil {
@@ -172,3 +366,110 @@ func (s *Server) GetPackage(ctx context.Context, in *pb.GetPackageRequest) (*pb.
 
 	return &resp, nil
 }
+
+// Stream a specific version of a package to a client, in chunks. The
+// first chunk carries the package information, as well as the size
+// and checksum of the whole tarball.
+func (s *Server) DownloadPackage(in *pb.GetPackageRequest, stream pb.Mspm_DownloadPackageServer) error {
+	name := in.GetPackageName()
+	labelDes := in.GetDesignator()
+
+	if name == "" {
+		log.Error("DownloadPackage called with empty name")
+		return fmt.Errorf("No name specified")
+	}
+	if labelDes == "" {
+		log.WithFields(log.Fields{
+			"name": name,
+		}).Error("DownloadPackage, blank version designator")
+		return fmt.Errorf("No designator specified")
+	}
+	if err := s.authorize(stream.Context(), "DownloadPackage", name); err != nil {
+		return err
+	}
+
+	pv, err := s.dataStore.GetPackageVersion(name, labelDes)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":      err,
+			"name":       name,
+			"designator": labelDes,
+		}).Error("DownloadPackage fetching packageversion")
+		return err
+	}
+
+	size, checksum, err := s.dataStore.PackageChecksum(pv)
+	if err != nil {
+		return err
+	}
+
+	tarball, err := s.dataStore.OpenPackageData(pv)
+	if err != nil {
+		return err
+	}
+	defer tarball.Close()
+
+	chunk := &pb.PackageChunk{
+		PackageData: packageInformationFromPackageVersion(pv),
+		Size:        size,
+		Checksum:    checksum,
+	}
+	return sendChunks(tarball, func(buf []byte) error {
+		chunk.Data = buf
+		err := stream.Send(chunk)
+		chunk = &pb.PackageChunk{}
+		return err
+	})
+}
+
+// Read everything from in, calling send with up to chunkSize bytes at
+// a time. Send is always called at least once, even if there is no
+// data. Each call gets a buffer of its own.
+func sendChunks(in io.Reader, send func([]byte) error) error {
+	sent := false
+	for {
+		buf := make([]byte, chunkSize)
+		n, err := io.ReadFull(in, buf)
+		if n > 0 || !sent {
+			if sendErr := send(buf[:n]); sendErr != nil {
+				log.WithFields(log.Fields{
+					"error": sendErr,
+				}).Error("sending chunk")
+				return sendErr
+			}
+			sent = true
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("reading data to send")
+			return err
+		}
+	}
+}
+
+// Change the settings of a package.
+func (s *Server) SetPackageOptions(ctx context.Context, in *pb.PackageOptions) (*pb.PackageOptions, error) {
+	name := in.GetPackageName()
+	if name == "" {
+		return nil, fmt.Errorf("No package name specified.")
+	}
+	if err := s.authorize(ctx, "SetPackageOptions", name); err != nil {
+		return nil, err
+	}
+
+	opts := data.PackageOptions{DisableLatest: in.GetDisableLatest()}
+	err := s.dataStore.SetPackageOptions(name, opts)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("SetPackageOptions")
+		return nil, err
+	}
+
+	return &pb.PackageOptions{PackageName: name, DisableLatest: opts.DisableLatest}, nil
+}
@@ -1,8 +1,19 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"google.golang.org/grpc"
+
 	pb "github.com/vatine/mspm/pkg/protos"
 )
 
@@ -14,3 +25,184 @@ func TestAssignment(t *testing.T) {
 		t.Errorf("Failed to convert")
 	}
 }
+
+func newTestServer(t *testing.T) (*Server, string) {
+	dir, err := ioutil.TempDir("", "mspm-server")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	for _, sub := range []string{"playground", "store"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", sub, err)
+		}
+	}
+
+	s, err := NewServer(filepath.Join(dir, "playground"), filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return s, dir
+}
+
+// Return the names of all non-directories under root.
+func filesUnder(root string) []string {
+	var rv []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rv = append(rv, path)
+		}
+		return nil
+	})
+	return rv
+}
+
+func testPackage(contents string) *pb.NewPackage {
+	return &pb.NewPackage{
+		PackageName: "foo",
+		Files: []*pb.File{
+			{Name: "bin/", Owner: "root", Mode: 0755},
+			{Name: "bin/start", Owner: "root", Mode: 0755, Contents: []byte(contents)},
+			{Name: "README", Owner: "root", Mode: 0644, Contents: []byte("read me")},
+		},
+	}
+}
+
+func TestUploadPackage(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	info, err := s.UploadPackage(context.Background(), testPackage("#!/bin/sh\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	if len(info.GetVersion()) != 128 {
+		t.Errorf("Unexpected version %q", info.GetVersion())
+	}
+	if len(info.GetLabel()) != 1 || info.GetLabel()[0] != "latest" {
+		t.Errorf("Saw labels %v, want [latest]", info.GetLabel())
+	}
+
+	want := filepath.Join(dir, "store", fmt.Sprintf("foo-%s.manifest", info.GetVersion()))
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("Expected manifest %s in store: %v", want, err)
+	}
+	if blobs := fileBlobs(dir); len(blobs) != 2 {
+		t.Errorf("Expected file blobs for bin/start and README, saw %v", blobs)
+	}
+	if left := filesUnder(filepath.Join(dir, "playground")); len(left) != 0 {
+		t.Errorf("Playground not cleaned up, saw %v", left)
+	}
+
+	// Uploading the same contents again gives us the same version.
+	again, err := s.UploadPackage(context.Background(), testPackage("#!/bin/sh\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error re-uploading: %v", err)
+	}
+	if again.GetVersion() != info.GetVersion() {
+		t.Errorf("Re-upload saw version %s, want %s", again.GetVersion(), info.GetVersion())
+	}
+
+	// A new version only adds the file that changed.
+	_, err = s.UploadPackage(context.Background(), testPackage("#!/bin/sh\necho v2\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading v2: %v", err)
+	}
+	if blobs := fileBlobs(dir); len(blobs) != 3 {
+		t.Errorf("Expected three file blobs, saw %v", blobs)
+	}
+}
+
+// The file contents stored under a test server's store.
+func fileBlobs(dir string) []string {
+	var rv []string
+	for _, path := range filesUnder(filepath.Join(dir, "store")) {
+		if strings.HasPrefix(filepath.Base(path), "sha512-") {
+			rv = append(rv, path)
+		}
+	}
+	return rv
+}
+
+func TestUploadPackageFailureCleansUp(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	testcases := []*pb.NewPackage{
+		{PackageName: "foo", Files: []*pb.File{{Name: "missing/file", Contents: []byte("x")}}},
+		{PackageName: "foo", Files: []*pb.File{{Name: "../escape", Contents: []byte("x")}}},
+		{PackageName: "../foo", Files: []*pb.File{{Name: "file", Contents: []byte("x")}}},
+		{PackageName: "", Files: []*pb.File{{Name: "file", Contents: []byte("x")}}},
+	}
+
+	for ix, tc := range testcases {
+		if _, err := s.UploadPackage(context.Background(), tc); err == nil {
+			t.Errorf("Case #%d, expected error, saw none", ix)
+		}
+	}
+
+	if left := filesUnder(dir); len(left) != 0 {
+		t.Errorf("Failed uploads left files behind: %v", left)
+	}
+	if _, ok := s.dataStore.GetPackageVersions("foo"); ok {
+		t.Errorf("Failed uploads registered a package")
+	}
+}
+
+// Collects everything sent on a download stream.
+type fakeDownloadStream struct {
+	grpc.ServerStream
+	chunks []*pb.PackageChunk
+}
+
+func (f *fakeDownloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeDownloadStream) Send(c *pb.PackageChunk) error {
+	f.chunks = append(f.chunks, c)
+	return nil
+}
+
+func TestDownloadPackage(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	big := make([]byte, 3*chunkSize)
+	rand.Read(big)
+	pkg := testPackage("#!/bin/sh\n")
+	pkg.Files = append(pkg.Files, &pb.File{Name: "blob", Owner: "root", Mode: 0644, Contents: big})
+	info, err := s.UploadPackage(context.Background(), pkg)
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	stream := &fakeDownloadStream{}
+	err = s.DownloadPackage(&pb.GetPackageRequest{PackageName: "foo", Designator: "latest"}, stream)
+	if err != nil {
+		t.Fatalf("Unexpected error downloading: %v", err)
+	}
+	if len(stream.chunks) < 3 {
+		t.Fatalf("Saw %d chunks, expected at least 3", len(stream.chunks))
+	}
+
+	first := stream.chunks[0]
+	if first.GetPackageData().GetVersion() != info.GetVersion() {
+		t.Errorf("Saw version %s, want %s", first.GetPackageData().GetVersion(), info.GetVersion())
+	}
+
+	var buf bytes.Buffer
+	for _, c := range stream.chunks {
+		buf.Write(c.GetData())
+	}
+	if int64(buf.Len()) != first.GetSize() {
+		t.Errorf("Saw %d bytes, want %d", buf.Len(), first.GetSize())
+	}
+	if sum := fmt.Sprintf("%x", sha512.Sum512(buf.Bytes())); sum != first.GetChecksum() {
+		t.Errorf("Saw checksum %s, want %s", sum, first.GetChecksum())
+	}
+
+	err = s.DownloadPackage(&pb.GetPackageRequest{PackageName: "foo", Designator: "nope"}, &fakeDownloadStream{})
+	if err == nil {
+		t.Errorf("Expected error downloading unknown version, saw none")
+	}
+}
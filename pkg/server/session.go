@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// How long an upload session can sit idle before it is thrown away,
+// unless told otherwise.
+const defaultSessionMaxIdle = 24 * time.Hour
+
+// Set how long upload sessions may be idle before they expire. This
+// should be called before the server starts serving.
+func (s *Server) SetSessionMaxIdle(maxIdle time.Duration) {
+	s.sessionMaxIdle = maxIdle
+}
+
+// Throw away expired upload sessions every interval. This never
+// returns, so is expected to be run in a goroutine of its own.
+func (s *Server) ExpireUploadSessions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n := s.dataStore.ExpireUploadSessions(s.sessionMaxIdle)
+		if n > 0 {
+			log.WithFields(log.Fields{
+				"expired": n,
+			}).Info("expired upload sessions")
+		}
+	}
+}
+
+// Build the status message for an upload session.
+func (s *Server) sessionStatus(us *data.UploadSession) *pb.UploadSessionStatus {
+	rv := &pb.UploadSessionStatus{
+		SessionId:   us.ID,
+		PackageName: us.PackageName(),
+		ExpiresAt:   us.LastActive().Add(s.sessionMaxIdle).Unix(),
+	}
+	for _, fo := range us.Offsets() {
+		rv.Files = append(rv.Files, &pb.FileOffset{Name: fo.Name, Offset: fo.Offset})
+	}
+
+	return rv
+}
+
+// Start a resumable upload of a new version of a package.
+func (s *Server) StartUpload(ctx context.Context, in *pb.UploadHeader) (*pb.UploadSessionStatus, error) {
+	name := in.GetPackageName()
+	if name == "" {
+		return nil, fmt.Errorf("No package name specified.")
+	}
+	if err := s.authorize(ctx, "StartUpload", name, in.GetLabel()...); err != nil {
+		return nil, err
+	}
+
+	us, err := s.dataStore.NewUploadSession(name, in.GetLabel())
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("StartUpload")
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"name":    name,
+		"session": us.ID,
+	}).Debug("StartUpload - session created")
+
+	return s.sessionStatus(us), nil
+}
+
+// Return how far an upload session has gotten, so the client knows
+// where to resume from.
+func (s *Server) GetUploadStatus(ctx context.Context, in *pb.UploadSessionRequest) (*pb.UploadSessionStatus, error) {
+	us, err := s.dataStore.GetUploadSession(in.GetSessionId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := s.authorize(ctx, "GetUploadStatus", us.PackageName()); err != nil {
+		return nil, err
+	}
+
+	return s.sessionStatus(us), nil
+}
+
+// Receive (more of) the files in an upload session. When the client
+// closes the stream, the current state of the session is returned.
+func (s *Server) ResumeUpload(stream pb.Mspm_ResumeUploadServer) error {
+	var us *data.UploadSession
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error": err,
+			}).Error("ResumeUpload - receiving")
+			return err
+		}
+
+		if us == nil || us.ID != req.GetSessionId() {
+			us, err = s.dataStore.GetUploadSession(req.GetSessionId())
+			if err != nil {
+				return grpcError(err)
+			}
+			err = s.authorize(stream.Context(), "ResumeUpload", us.PackageName())
+			if err != nil {
+				return err
+			}
+		}
+
+		if file := req.GetFile(); file != nil {
+			err = us.AddFile(file)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"session": us.ID,
+					"file":    file.GetName(),
+				}).Error("ResumeUpload - adding file")
+				return grpcError(err)
+			}
+		}
+
+		if len(req.GetData()) > 0 {
+			_, err = us.Write(req.GetName(), req.GetOffset(), req.GetData())
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error":   err,
+					"session": us.ID,
+					"file":    req.GetName(),
+					"offset":  req.GetOffset(),
+				}).Error("ResumeUpload - writing data")
+				return grpcError(err)
+			}
+		}
+	}
+
+	if us == nil {
+		return fmt.Errorf("No session specified")
+	}
+	return stream.SendAndClose(s.sessionStatus(us))
+}
+
+// Finish an upload session, storing the uploaded package version.
+func (s *Server) FinishUpload(ctx context.Context, in *pb.UploadSessionRequest) (*pb.PackageInformation, error) {
+	us, err := s.dataStore.GetUploadSession(in.GetSessionId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	labels := us.Labels
+	if err := s.authorize(ctx, "FinishUpload", us.PackageName(), labels...); err != nil {
+		return nil, err
+	}
+
+	pv, err := s.dataStore.FinishUploadSession(us.ID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":   err,
+			"session": us.ID,
+		}).Error("FinishUpload")
+		return nil, grpcError(err)
+	}
+
+	pv.UploadedBy = peerIdentity(ctx)
+	return s.storeUpload(ctx, pv, labels)
+}
+
+// Abort an upload session, throwing away everything uploaded so far.
+func (s *Server) AbortUpload(ctx context.Context, in *pb.UploadSessionRequest) (*pb.UploadSessionStatus, error) {
+	us, err := s.dataStore.GetUploadSession(in.GetSessionId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if err := s.authorize(ctx, "AbortUpload", us.PackageName()); err != nil {
+		return nil, err
+	}
+	rv := s.sessionStatus(us)
+
+	err = s.dataStore.AbortUploadSession(us.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return rv, nil
+}
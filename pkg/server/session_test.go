@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Feeds a fixed sequence of messages to a resumable upload.
+type fakeResumeStream struct {
+	grpc.ServerStream
+	reqs []*pb.SessionUploadRequest
+	resp *pb.UploadSessionStatus
+}
+
+func (f *fakeResumeStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeResumeStream) Recv() (*pb.SessionUploadRequest, error) {
+	if len(f.reqs) == 0 {
+		return nil, io.EOF
+	}
+	rv := f.reqs[0]
+	f.reqs = f.reqs[1:]
+	return rv, nil
+}
+
+func (f *fakeResumeStream) SendAndClose(st *pb.UploadSessionStatus) error {
+	f.resp = st
+	return nil
+}
+
+func TestResumableUpload(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	st, err := s.StartUpload(ctx, &pb.UploadHeader{PackageName: "foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error starting upload: %v", err)
+	}
+	id := st.GetSessionId()
+
+	// First connection gets part of bin/start across, then drops.
+	first := &fakeResumeStream{reqs: []*pb.SessionUploadRequest{
+		{SessionId: id, File: &pb.File{Name: "bin/", Owner: "root", Mode: 0755}},
+		{SessionId: id, File: &pb.File{Name: "bin/start", Owner: "root", Mode: 0755}, Name: "bin/start", Data: []byte("#!/bin")},
+	}}
+	if err := s.ResumeUpload(first); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	st, err = s.GetUploadStatus(ctx, &pb.UploadSessionRequest{SessionId: id})
+	if err != nil {
+		t.Fatalf("Unexpected error getting status: %v", err)
+	}
+	var offset int64 = -1
+	for _, fo := range st.GetFiles() {
+		if fo.GetName() == "bin/start" {
+			offset = fo.GetOffset()
+		}
+	}
+	if offset != 6 {
+		t.Fatalf("Saw offset %d for bin/start, want 6", offset)
+	}
+
+	// Writing from the wrong place is refused.
+	bad := &fakeResumeStream{reqs: []*pb.SessionUploadRequest{
+		{SessionId: id, Name: "bin/start", Offset: 0, Data: []byte("#!/bin/sh\n")},
+	}}
+	if err := s.ResumeUpload(bad); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, saw %v", err)
+	}
+
+	second := &fakeResumeStream{reqs: []*pb.SessionUploadRequest{
+		{SessionId: id, Name: "bin/start", Offset: offset, Data: []byte("/sh\n")},
+		{SessionId: id, File: &pb.File{Name: "README", Owner: "root", Mode: 0644}, Name: "README", Data: []byte("read me")},
+	}}
+	if err := s.ResumeUpload(second); err != nil {
+		t.Fatalf("Unexpected error resuming: %v", err)
+	}
+
+	info, err := s.FinishUpload(ctx, &pb.UploadSessionRequest{SessionId: id})
+	if err != nil {
+		t.Fatalf("Unexpected error finishing: %v", err)
+	}
+
+	unary, err := s.UploadPackage(ctx, testPackage("#!/bin/sh\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	if info.GetVersion() != unary.GetVersion() {
+		t.Errorf("Resumed version %s differs from unary %s", info.GetVersion(), unary.GetVersion())
+	}
+
+	_, err = s.GetUploadStatus(ctx, &pb.UploadSessionRequest{SessionId: id})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for finished session, saw %v", err)
+	}
+}
+
+func TestAbortUpload(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	st, err := s.StartUpload(ctx, &pb.UploadHeader{PackageName: "foo"})
+	if err != nil {
+		t.Fatalf("Unexpected error starting upload: %v", err)
+	}
+	stream := &fakeResumeStream{reqs: []*pb.SessionUploadRequest{
+		{SessionId: st.GetSessionId(), File: &pb.File{Name: "README", Owner: "root", Mode: 0644}, Name: "README", Data: []byte("x")},
+	}}
+	if err := s.ResumeUpload(stream); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	if _, err := s.AbortUpload(ctx, &pb.UploadSessionRequest{SessionId: st.GetSessionId()}); err != nil {
+		t.Fatalf("Unexpected error aborting: %v", err)
+	}
+	if left := filesUnder(dir); len(left) != 0 {
+		t.Errorf("Aborted upload left files behind: %v", left)
+	}
+}
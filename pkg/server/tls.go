@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Names accepted for the minimum TLS version.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Translate a TLS version name ("1.2", "1.3", ...) to the constant
+// crypto/tls uses.
+func TLSVersion(name string) (uint16, error) {
+	v, ok := tlsVersions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", name)
+	}
+	return v, nil
+}
+
+// A CertReloader holds the server certificate, and can re-read it from
+// disk without the server having to be restarted.
+type CertReloader struct {
+	lock     sync.RWMutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+}
+
+// Create a CertReloader, loading the certificate and key.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{certFile: certFile, keyFile: keyFile}
+	err := cr.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Re-read the certificate and key. If that fails, the certificate
+// already loaded is kept.
+func (cr *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"cert":  cr.certFile,
+			"key":   cr.keyFile,
+		}).Error("loading TLS certificate")
+		return err
+	}
+
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+	cr.cert = &cert
+
+	return nil
+}
+
+// Return the current certificate. This has the signature of
+// tls.Config.GetCertificate.
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	return cr.cert, nil
+}
+
+// Build a TLS config serving the reloadable certificate. If clientCAs
+// is not nil, clients must present a certificate signed by one of
+// them.
+func (cr *CertReloader) TLSConfig(minVersion uint16, clientCAs *x509.CertPool) *tls.Config {
+	rv := &tls.Config{
+		GetCertificate: cr.GetCertificate,
+		MinVersion:     minVersion,
+	}
+	if clientCAs != nil {
+		rv.ClientCAs = clientCAs
+		rv.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return rv
+}
+
+// Read a file of PEM-encoded CA certificates.
+func LoadCertPool(filename string) (*x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("no certificates found in %s", filename)
+	}
+	return pool, nil
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// A throwaway CA for issuing test certificates.
+type testCA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	serial  int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mspm test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		serial:  1,
+	}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// Issue a certificate valid for localhost, writing it and its key to
+// <dir>/<name>.crt and <dir>/<name>.key.
+func (ca *testCA) issue(t *testing.T, dir, name, cn string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ca.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestTLSVersion(t *testing.T) {
+	cases := []struct {
+		name string
+		want uint16
+		ok   bool
+	}{
+		{"1.2", tls.VersionTLS12, true},
+		{"1.3", tls.VersionTLS13, true},
+		{"1.4", 0, false},
+		{"", 0, false},
+	}
+
+	for _, c := range cases {
+		seen, err := TLSVersion(c.name)
+		if (err == nil) != c.ok {
+			t.Errorf("TLSVersion(%q), unexpected error state %v", c.name, err)
+		}
+		if seen != c.want {
+			t.Errorf("TLSVersion(%q), expected %d, saw %d", c.name, c.want, seen)
+		}
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-tls")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "server", "first")
+	cr, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first, _ := cr.GetCertificate(nil)
+
+	ca.issue(t, dir, "server", "second")
+	if err := cr.Reload(); err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	second, _ := cr.GetCertificate(nil)
+	if first == second {
+		t.Errorf("Expected a new certificate after reloading")
+	}
+
+	ioutil.WriteFile(certFile, []byte("garbage"), 0644)
+	if err := cr.Reload(); err == nil {
+		t.Errorf("Expected an error reloading a broken certificate")
+	}
+	if kept, _ := cr.GetCertificate(nil); kept != second {
+		t.Errorf("Expected the old certificate to be kept on a failed reload")
+	}
+
+	_, err = NewCertReloader(certFile, keyFile)
+	if err == nil {
+		t.Errorf("Expected an error loading a broken certificate")
+	}
+}
+
+// Start a TLS-serving gRPC server with s on a local port, returning
+// the address.
+func serveTLS(t *testing.T, s *Server, config *tls.Config, opts ...grpc.ServerOption) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	gs := grpc.NewServer(opts...)
+	pb.RegisterMspmServer(gs, s)
+	go gs.Serve(listener)
+
+	return listener.Addr().String(), gs.Stop
+}
+
+func TestServeTLS(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	cr, err := NewCertReloader(ca.issue(t, dir, "server", "server"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	addr, stop := serveTLS(t, s, cr.TLSConfig(tls.VersionTLS12, nil))
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	creds := credentials.NewTLS(&tls.Config{RootCAs: ca.pool()})
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = pb.NewMspmClient(conn).GetPackageInformation(ctx, &pb.PackageInformationRequest{PackageName: "foo"})
+	if err != nil {
+		t.Errorf("Unexpected error over TLS: %v", err)
+	}
+
+	// A client only speaking TLS 1.1 must be turned away.
+	old := &tls.Config{RootCAs: ca.pool(), MaxVersion: tls.VersionTLS11}
+	oldConn, err := tls.Dial("tcp", addr, old)
+	if err == nil {
+		oldConn.Close()
+		t.Errorf("Expected a TLS 1.1 handshake to fail")
+	}
+}
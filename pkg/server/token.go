@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Token scopes. Each RPC needs one of these, admin allows everything.
+const (
+	ScopeRead   = "read"
+	ScopeUpload = "upload"
+	ScopeLabel  = "label"
+	ScopeAdmin  = "admin"
+)
+
+// The scope each RPC needs. RPCs not listed need admin, as does
+// forcing a protected label to move (the "ForceLabel" pseudo-method).
+var methodScopes = map[string]string{
+	"GetPackageInformation": ScopeRead,
+	"GetPackage":            ScopeRead,
+	"DownloadPackage":       ScopeRead,
+	"GetUploadStatus":       ScopeUpload,
+	"UploadPackage":         ScopeUpload,
+	"UploadPackageStream":   ScopeUpload,
+	"StartUpload":           ScopeUpload,
+	"ResumeUpload":          ScopeUpload,
+	"FinishUpload":          ScopeUpload,
+	"AbortUpload":           ScopeUpload,
+	"SetLabels":             ScopeLabel,
+	"ChangeLabels":          ScopeLabel,
+	"RollbackLabel":         ScopeLabel,
+	"GetLabelHistory":       ScopeRead,
+	"ListPackages":          ScopeRead,
+	"GetRetentionReport":    ScopeRead,
+	"DownloadDelta":         ScopeRead,
+	"ListFiles":             ScopeRead,
+	"GetFile":               ScopeRead,
+	"SetPackageOptions":     ScopeAdmin,
+	"QueryAuditLog":         ScopeAdmin,
+	"DeleteVersion":         ScopeAdmin,
+	"DeletePackage":         ScopeAdmin,
+	"ForceLabel":            ScopeAdmin,
+}
+
+// The service our RPCs belong to, as it appears in full method names.
+const mspmService = "/mspm.Mspm/"
+
+// A token, as stored in the token file. Only the SHA-256 hash of the
+// token is stored, hex-encoded.
+type tokenEntry struct {
+	Identity string   `json:"identity"`
+	Hash     string   `json:"hash"`
+	Scopes   []string `json:"scopes"`
+}
+
+type tokenFile struct {
+	Tokens []tokenEntry `json:"tokens"`
+}
+
+// Return the hash of a token, as it is stored in the token file.
+func HashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// A TokenStore holds the known tokens, read from a token file, and
+// provides interceptors that authenticate callers using them.
+type TokenStore struct {
+	lock     sync.RWMutex
+	filename string
+	tokens   map[string]tokenEntry
+	auditLog *AuditLog
+}
+
+// Create a TokenStore, reading the tokens from a JSON file.
+func NewTokenStore(filename string) (*TokenStore, error) {
+	ts := &TokenStore{filename: filename}
+	err := ts.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Re-read the token file. If that fails, the tokens already loaded
+// are kept.
+func (ts *TokenStore) Reload() error {
+	buf, err := ioutil.ReadFile(ts.filename)
+	if err != nil {
+		return err
+	}
+	var tf tokenFile
+	err = json.Unmarshal(buf, &tf)
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]tokenEntry)
+	for _, te := range tf.Tokens {
+		te.Hash = strings.ToLower(te.Hash)
+		if te.Identity == "" || len(te.Hash) != 2*sha256.Size {
+			return fmt.Errorf("token file %s: entry for %q needs an identity and a SHA-256 hash", ts.filename, te.Identity)
+		}
+		tokens[te.Hash] = te
+	}
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.tokens = tokens
+
+	return nil
+}
+
+// Set the audit log calls the interceptors turn away are recorded
+// in. The audit log's own interceptors never see those calls.
+func (ts *TokenStore) SetAuditLog(a *AuditLog) {
+	ts.auditLog = a
+}
+
+// Check that the bearer token in the call metadata is known, and has
+// the scope needed for the method. The token is returned, even if it
+// lacks the scope.
+func (ts *TokenStore) authenticate(ctx context.Context, fullMethod string) (tokenEntry, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	for _, v := range md.Get("authorization") {
+		if strings.HasPrefix(v, "Bearer ") {
+			token = strings.TrimPrefix(v, "Bearer ")
+		}
+	}
+	if token == "" {
+		return tokenEntry{}, status.Error(codes.Unauthenticated, "no bearer token")
+	}
+
+	ts.lock.RLock()
+	te, ok := ts.tokens[HashToken(token)]
+	ts.lock.RUnlock()
+	if !ok {
+		log.WithFields(log.Fields{
+			"method": fullMethod,
+		}).Warning("unknown token")
+		return tokenEntry{}, status.Error(codes.Unauthenticated, "unknown token")
+	}
+
+	// Other services (such as reflection) only need a valid token.
+	if !strings.HasPrefix(fullMethod, mspmService) {
+		return te, nil
+	}
+	method := strings.TrimPrefix(fullMethod, mspmService)
+	if te.allows(method) {
+		return te, nil
+	}
+
+	log.WithFields(log.Fields{
+		"identity": te.Identity,
+		"method":   method,
+		"scope":    scopeFor(method),
+	}).Warning("token lacks scope")
+	return te, status.Errorf(codes.PermissionDenied, "%s lacks the %s scope needed for %s", te.Identity, scopeFor(method), method)
+}
+
+// Record a call that was turned away in the audit log, if there is
+// one and the method is audited. The request is nil for streaming
+// calls, as nothing has been read from the stream yet.
+func (ts *TokenStore) recordDenied(ctx context.Context, fullMethod string, te tokenEntry, req interface{}, err error) {
+	if ts.auditLog == nil || !audited(fullMethod) {
+		return
+	}
+
+	r := newAuditRecord(ctx, fullMethod)
+	if te.Identity != "" {
+		r.Identity = te.Identity
+	}
+	if req != nil {
+		r.describe(req)
+	}
+	ts.auditLog.finish(r, err)
+}
+
+// Return the scope a method needs.
+func scopeFor(method string) string {
+	needed, ok := methodScopes[method]
+	if !ok {
+		return ScopeAdmin
+	}
+	return needed
+}
+
+// Check if the token has the scope a method needs.
+func (te tokenEntry) allows(method string) bool {
+	needed := scopeFor(method)
+	for _, scope := range te.Scopes {
+		if scope == needed || scope == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenKey struct{}
+
+// Return the token a call was made with, if any.
+func callToken(ctx context.Context) (tokenEntry, bool) {
+	te, ok := ctx.Value(tokenKey{}).(tokenEntry)
+	return te, ok
+}
+
+// Return the identity of the token a call was made with, if any.
+func tokenIdentity(ctx context.Context) (string, bool) {
+	te, ok := callToken(ctx)
+	return te.Identity, ok
+}
+
+// Return a unary server interceptor that authenticates calls.
+func (ts *TokenStore) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		te, err := ts.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			ts.recordDenied(ctx, info.FullMethod, te, req, err)
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, tokenKey{}, te), req)
+	}
+}
+
+// A server stream with the token identity added to its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (as *authenticatedStream) Context() context.Context {
+	return as.ctx
+}
+
+// Return a stream server interceptor that authenticates calls.
+func (ts *TokenStore) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		te, err := ts.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			ts.recordDenied(ss.Context(), info.FullMethod, te, nil, err)
+			return err
+		}
+		ctx := context.WithValue(ss.Context(), tokenKey{}, te)
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
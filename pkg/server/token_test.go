@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+func writeTokenFile(t *testing.T, filename string, tokens map[string][]string) {
+	var tf tokenFile
+	for token, scopes := range tokens {
+		tf.Tokens = append(tf.Tokens, tokenEntry{Identity: token, Hash: HashToken(token), Scopes: scopes})
+	}
+	buf, err := json.Marshal(tf)
+	if err != nil {
+		t.Fatalf("Failed to marshal tokens: %v", err)
+	}
+	err = ioutil.WriteFile(filename, buf, 0600)
+	if err != nil {
+		t.Fatalf("Failed to write tokens: %v", err)
+	}
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestTokenStoreReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mspm-tokens")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tokens.json")
+	writeTokenFile(t, filename, map[string][]string{"reader": {ScopeRead}})
+	ts, err := NewTokenStore(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer reader"))
+	te, err := ts.authenticate(ctx, mspmService+"GetPackage")
+	if err != nil || te.Identity != "reader" {
+		t.Errorf("Expected reader to authenticate, saw %q, %v", te.Identity, err)
+	}
+
+	ioutil.WriteFile(filename, []byte(`{"tokens": [{"identity": "x", "hash": "short"}]}`), 0600)
+	if err := ts.Reload(); err == nil {
+		t.Errorf("Expected an error reloading a bad token file")
+	}
+	if _, err := ts.authenticate(ctx, mspmService+"GetPackage"); err != nil {
+		t.Errorf("Expected old tokens to be kept, saw %v", err)
+	}
+}
+
+func TestTokenInterceptors(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tokens.json")
+	writeTokenFile(t, filename, map[string][]string{
+		"reader":   {ScopeRead},
+		"uploader": {ScopeRead, ScopeUpload},
+		"admin":    {ScopeAdmin},
+	})
+	ts, err := NewTokenStore(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Identities from tokens are what the policy sees.
+	s.SetPolicy(&Policy{Rules: []PolicyRule{
+		{Identities: []string{"*"}, Methods: []string{"*"}, Packages: []string{"*"}, Labels: []string{"staging"}},
+		{Identities: []string{"admin"}, Methods: []string{"*"}, Packages: []string{"*"}, Labels: []string{"*"}},
+	}})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	gs := grpc.NewServer(grpc.UnaryInterceptor(ts.UnaryInterceptor()), grpc.StreamInterceptor(ts.StreamInterceptor()))
+	pb.RegisterMspmServer(gs, s)
+	go gs.Serve(listener)
+	defer gs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewMspmClient(conn)
+
+	infoReq := &pb.PackageInformationRequest{PackageName: "foo"}
+	_, err = c.GetPackageInformation(ctx, infoReq)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, saw %v", err)
+	}
+	_, err = c.GetPackageInformation(withToken(ctx, "bogus"), infoReq)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated with an unknown token, saw %v", err)
+	}
+	_, err = c.GetPackageInformation(withToken(ctx, "reader"), infoReq)
+	if err != nil {
+		t.Errorf("Unexpected error reading: %v", err)
+	}
+	_, err = c.UploadPackage(withToken(ctx, "reader"), testPackage("hello"))
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied uploading as reader, saw %v", err)
+	}
+
+	// Streaming calls go through the stream interceptor.
+	stream, err := c.UploadPackageStream(withToken(ctx, "uploader"))
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+	stream.Send(headerPart("foo", "staging"))
+	stream.Send(filePart("hello", 0644))
+	stream.Send(dataPart("hello"))
+	info, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Unexpected error uploading as uploader: %v", err)
+	}
+
+	dl, err := c.DownloadPackage(withToken(ctx, "reader"), &pb.GetPackageRequest{PackageName: "foo", Designator: "staging"})
+	if err != nil {
+		t.Fatalf("Failed to start download: %v", err)
+	}
+	for {
+		_, err = dl.Recv()
+		if err != nil {
+			break
+		}
+	}
+	if err != io.EOF {
+		t.Errorf("Unexpected error downloading as reader: %v", err)
+	}
+
+	req := &pb.SetLabelRequest{PackageName: "foo", Version: info.GetVersion(), Label: []string{"prod"}}
+	_, err = c.SetLabels(withToken(ctx, "uploader"), req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied labelling as uploader, saw %v", err)
+	}
+	_, err = c.SetLabels(withToken(ctx, "admin"), req)
+	if err != nil {
+		t.Errorf("Unexpected error labelling as admin: %v", err)
+	}
+}
+
+func TestTokenForceNeedsAdmin(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	info, err := s.UploadPackage(context.Background(), testPackage("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	// No policy, so only the token scopes stand in the way.
+	labeller := context.WithValue(context.Background(), tokenKey{}, tokenEntry{Identity: "labeller", Scopes: []string{ScopeLabel}})
+	admin := context.WithValue(context.Background(), tokenKey{}, tokenEntry{Identity: "admin", Scopes: []string{ScopeAdmin}})
+
+	req := &pb.SetLabelRequest{PackageName: "foo", Version: info.GetVersion(), Label: []string{"prod"}}
+	if _, err := s.SetLabels(labeller, req); err != nil {
+		t.Errorf("Unexpected error labelling: %v", err)
+	}
+	req.Force = true
+	if _, err := s.SetLabels(labeller, req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied forcing with the label scope, saw %v", err)
+	}
+	if _, err := s.SetLabels(admin, req); err != nil {
+		t.Errorf("Unexpected error forcing as admin: %v", err)
+	}
+
+	del := &pb.DeleteRequest{PackageName: "foo", Version: info.GetVersion(), Force: true}
+	if _, err := s.DeleteVersion(labeller, del); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied deleting with the label scope, saw %v", err)
+	}
+}
+
+func TestTokenDenialsAudited(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "tokens.json")
+	writeTokenFile(t, filename, map[string][]string{"reader": {ScopeRead}})
+	ts, err := NewTokenStore(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	a, err := NewAuditLog(filepath.Join(dir, "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer a.Close()
+	s.SetAuditLog(a)
+	ts.SetAuditLog(a)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(ts.UnaryInterceptor(), a.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(ts.StreamInterceptor(), a.StreamInterceptor()),
+	)
+	pb.RegisterMspmServer(gs, s)
+	go gs.Serve(listener)
+	defer gs.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	c := pb.NewMspmClient(conn)
+
+	c.UploadPackage(withToken(ctx, "reader"), testPackage("hello"))
+	c.SetLabels(withToken(ctx, "bogus"), &pb.SetLabelRequest{PackageName: "foo", Version: "v1", Label: []string{"prod"}})
+	c.GetPackageInformation(ctx, &pb.PackageInformationRequest{PackageName: "foo"})
+	stream, err := c.UploadPackageStream(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start upload: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated streaming without a token, saw %v", err)
+	}
+
+	records, err := a.Query("", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error querying: %v", err)
+	}
+	expected := []struct {
+		method   string
+		identity string
+		pkg      string
+		code     codes.Code
+	}{
+		{"UploadPackage", "reader", "foo", codes.PermissionDenied},
+		{"SetLabels", anonymous, "foo", codes.Unauthenticated},
+		{"UploadPackageStream", anonymous, "", codes.Unauthenticated},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d audited denials, saw %v", len(expected), records)
+	}
+	for ix, e := range expected {
+		r := records[ix]
+		if r.Method != e.method || r.Identity != e.identity {
+			t.Errorf("Record #%d, expected %s by %s, saw %s by %s", ix, e.method, e.identity, r.Method, r.Identity)
+		}
+		if (e.pkg == "") != (len(r.Packages) == 0) || (e.pkg != "" && r.Packages[0] != e.pkg) {
+			t.Errorf("Record #%d, expected package %q, saw %v", ix, e.pkg, r.Packages)
+		}
+		if !strings.HasPrefix(r.Result, e.code.String()+":") {
+			t.Errorf("Record #%d, expected a %s result, saw %s", ix, e.code, r.Result)
+		}
+	}
+}
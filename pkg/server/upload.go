@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vatine/mspm/pkg/data"
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Receive a new version of a package as a stream. This works like
+// UploadPackage, except the file contents arrive in chunks, so there
+// is no limit on the size of a package.
+func (s *Server) UploadPackageStream(stream pb.Mspm_UploadPackageStreamServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Error("UploadPackageStream - receiving header")
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil {
+		log.Error("UploadPackageStream - stream does not start with a header")
+		return fmt.Errorf("Upload stream must start with a header")
+	}
+
+	name := header.GetPackageName()
+	if name == "" {
+		return fmt.Errorf("No package name specified.")
+	}
+	if err := s.authorize(stream.Context(), "UploadPackageStream", name, header.GetLabel()...); err != nil {
+		return err
+	}
+
+	pv, err := s.dataStore.NewPackageVersion(name)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("UploadPackageStream")
+		return err
+	}
+
+	err = receiveFiles(pv, stream)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+			"name":  name,
+		}).Error("UploadPackageStream - receiving files")
+		s.dataStore.DiscardPackageVersion(pv)
+		return err
+	}
+
+	pv.UploadedBy = peerIdentity(stream.Context())
+	info, err := s.finishUpload(stream.Context(), pv, header.GetLabel())
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(info)
+}
+
+// Receive file headers and contents from an upload stream, until the
+// client closes it.
+func receiveFiles(pv data.PackageVersion, stream pb.Mspm_UploadPackageStreamServer) error {
+	var current io.WriteCloser
+	var currentName string
+	defer func() {
+		if current != nil {
+			current.Close()
+		}
+	}()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch part := req.GetPart().(type) {
+		case *pb.UploadRequest_File:
+			if current != nil {
+				err = current.Close()
+				current = nil
+				if err != nil {
+					return err
+				}
+			}
+			file := part.File
+			currentName = file.GetName()
+			if strings.HasSuffix(currentName, "/") {
+				err = pv.AddDir(file)
+			} else {
+				current, err = pv.CreateFile(file)
+			}
+			if err != nil {
+				return err
+			}
+		case *pb.UploadRequest_Data:
+			if current == nil {
+				return fmt.Errorf("Data received without a file to put it in")
+			}
+			_, err = current.Write(part.Data)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"error": err,
+					"name":  pv.Name,
+					"file":  currentName,
+				}).Error("writing uploaded data")
+				return err
+			}
+		default:
+			return fmt.Errorf("Unexpected message in upload stream")
+		}
+	}
+
+	if current != nil {
+		err := current.Close()
+		current = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/vatine/mspm/pkg/protos"
+)
+
+// Feeds a fixed sequence of messages to a streaming upload.
+type fakeUploadStream struct {
+	grpc.ServerStream
+	reqs []*pb.UploadRequest
+	resp *pb.PackageInformation
+}
+
+func (f *fakeUploadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeUploadStream) Recv() (*pb.UploadRequest, error) {
+	if len(f.reqs) == 0 {
+		return nil, io.EOF
+	}
+	rv := f.reqs[0]
+	f.reqs = f.reqs[1:]
+	return rv, nil
+}
+
+func (f *fakeUploadStream) SendAndClose(info *pb.PackageInformation) error {
+	f.resp = info
+	return nil
+}
+
+func headerPart(name string, labels ...string) *pb.UploadRequest {
+	return &pb.UploadRequest{Part: &pb.UploadRequest_Header{Header: &pb.UploadHeader{PackageName: name, Label: labels}}}
+}
+
+func filePart(name string, mode int32) *pb.UploadRequest {
+	return &pb.UploadRequest{Part: &pb.UploadRequest_File{File: &pb.File{Name: name, Owner: "root", Mode: mode}}}
+}
+
+func dataPart(data string) *pb.UploadRequest {
+	return &pb.UploadRequest{Part: &pb.UploadRequest_Data{Data: []byte(data)}}
+}
+
+func TestUploadPackageStream(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	// The same package as testPackage, in pieces.
+	stream := &fakeUploadStream{reqs: []*pb.UploadRequest{
+		headerPart("foo", "stable"),
+		filePart("bin/", 0755),
+		filePart("bin/start", 0755),
+		dataPart("#!/bin"), dataPart("/sh\n"),
+		filePart("README", 0644),
+		dataPart("read me"),
+	}}
+	if err := s.UploadPackageStream(stream); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	unary, err := s.UploadPackage(context.Background(), testPackage("#!/bin/sh\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	if stream.resp.GetVersion() != unary.GetVersion() {
+		t.Errorf("Streamed version %s differs from unary %s", stream.resp.GetVersion(), unary.GetVersion())
+	}
+	if _, err := s.dataStore.GetPackageVersion("foo", "stable"); err != nil {
+		t.Errorf("Upload label not set: %v", err)
+	}
+}
+
+func TestUploadPackageStreamErrors(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	testcases := [][]*pb.UploadRequest{
+		{},
+		{filePart("README", 0644)},
+		{headerPart("foo"), dataPart("orphan")},
+		{headerPart("foo"), filePart("README", 0644), headerPart("foo")},
+		{headerPart("foo"), filePart("../README", 0644)},
+	}
+
+	for ix, tc := range testcases {
+		err := s.UploadPackageStream(&fakeUploadStream{reqs: tc})
+		if err == nil {
+			t.Errorf("Case #%d, expected error, saw none", ix)
+		}
+	}
+	if left := filesUnder(dir); len(left) != 0 {
+		t.Errorf("Failed uploads left files behind: %v", left)
+	}
+}
+
+func TestUploadLabelIdentity(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+
+	stream := &fakeUploadStream{reqs: []*pb.UploadRequest{
+		headerPart("foo", "stable"),
+		filePart("README", 0644),
+		dataPart("read me"),
+	}}
+	if err := s.UploadPackageStream(stream); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+
+	history, err := s.dataStore.LabelHistory("foo", "stable")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected one history event, saw %v, %v", history, err)
+	}
+	if history[0].Identity != anonymous {
+		t.Errorf("Expected label set by %q, saw %q", anonymous, history[0].Identity)
+	}
+}
+
+func TestUploadProtectedLabel(t *testing.T) {
+	s, dir := newTestServer(t)
+	defer os.RemoveAll(dir)
+	s.SetProtectedLabels([]string{"prod"})
+
+	upload := func(contents string) error {
+		return s.UploadPackageStream(&fakeUploadStream{reqs: []*pb.UploadRequest{
+			headerPart("foo", "prod"),
+			filePart("README", 0644),
+			dataPart(contents),
+		}})
+	}
+	if err := upload("first"); err != nil {
+		t.Fatalf("Unexpected error uploading: %v", err)
+	}
+	if err := upload("second"); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, saw %v", err)
+	}
+
+	pvs, _ := s.dataStore.GetPackageVersions("foo")
+	if len(pvs) != 1 {
+		t.Errorf("Refused upload was stored, saw %d versions", len(pvs))
+	}
+}